@@ -75,6 +75,11 @@ const (
 	// are automatically re-tried by the controller.
 	CloningFailedReason = "CloningFailed"
 
+	// InsufficientCapacityReason (Severity=Warning) documents a VSphereMachine/VSphereVM controller detecting
+	// that the resource pool or the datastore of the virtual machine cannot accommodate it before cloning it;
+	// no clone task is created and the clone is re-tried by the controller with an exponential backoff.
+	InsufficientCapacityReason = "InsufficientCapacity"
+
 	// PoweringOnReason documents (Severity=Info) a VSphereMachine/VSphereVM currently executing the power on sequence.
 	PoweringOnReason = "PoweringOn"
 
@@ -111,6 +116,83 @@ const (
 	// NotFoundReason (Severity=Warning) documents the VSphereVM not having the PCI device attached during VM startup.
 	// This would indicate that the PCI devices were removed out of band by an external entity.
 	NotFoundReason = "NotFound"
+
+	// SecurityConfiguredCondition documents whether the firmware, Secure Boot and virtual TPM requested
+	// in the security section of the VSphereVM are applied to the underlying VM.
+	//
+	// NOTE: This condition does not apply to VSphereMachine.
+	SecurityConfiguredCondition clusterv1.ConditionType = "SecurityConfigured"
+
+	// KeyProviderNotFoundReason (Severity=Error) documents that no key provider is available in vCenter
+	// to encrypt a VM with a virtual TPM.
+	KeyProviderNotFoundReason = "KeyProviderNotFound"
+
+	// SecurityNotAppliedReason (Severity=Warning) documents a VM on which some of the requested security
+	// features are not in place, e.g. because they are not supported by its hardware version or were
+	// removed out of band.
+	SecurityNotAppliedReason = "SecurityNotApplied"
+
+	// VMResizedCondition documents whether changes to the CPUs and memory of a VSphereVM with a resize
+	// policy other than Disabled are applied to the underlying VM.
+	//
+	// NOTE: This condition does not apply to VSphereMachine.
+	VMResizedCondition clusterv1.ConditionType = "VMResized"
+
+	// ResizingReason (Severity=Info) documents a VSphereVM currently executing the reconfigure operation
+	// changing the CPUs and memory of the VM.
+	ResizingReason = "Resizing"
+
+	// ResizeFailedReason (Severity=Warning) documents a VSphereVM controller detecting an error while
+	// triggering the reconfigure operation changing the CPUs and memory of the VM.
+	ResizeFailedReason = "ResizeFailed"
+
+	// WaitingForPowerOffReason (Severity=Info) documents a VSphereVM with the HotAdd resize policy waiting
+	// for the VM to be powered off to apply changes that cannot be hot added.
+	WaitingForPowerOffReason = "WaitingForPowerOff"
+
+	// PowerCyclingReason (Severity=Info) documents a VSphereVM with the HotAddOrPowerCycle resize policy
+	// powering off the VM to apply changes that cannot be hot added.
+	PowerCyclingReason = "PowerCycling"
+
+	// VMConfigurationInSyncCondition documents whether the configuration of the underlying VM matches the
+	// spec of the VSphereVM. It is False while the VM differs from its spec, e.g. because of CPUs added
+	// directly in vCenter, and its message then lists the differing fields. The VM is only compared once
+	// the VSphereVM has been ready.
+	//
+	// The drift is reported with a positive condition rather than a VMConfigurationDrifted condition, as
+	// Cluster API expects conditions to be True when the object is in the desired state: the reasons and
+	// severities of False conditions are what the Ready condition and the status tooling of Cluster API
+	// summarize.
+	//
+	// NOTE: This condition does not apply to VSphereMachine.
+	VMConfigurationInSyncCondition clusterv1.ConditionType = "VMConfigurationInSync"
+
+	// DriftDetectedReason (Severity=Warning) documents a VM whose configuration differs from the spec of
+	// the VSphereVM and is not corrected, either because of the drift policy or because the differences
+	// cannot be corrected. The severity is Error if the machine is failed for remediation.
+	DriftDetectedReason = "DriftDetected"
+
+	// CorrectingDriftReason (Severity=Info) documents a VSphereVM with the Correct drift policy currently
+	// changing the VM back to its spec.
+	CorrectingDriftReason = "CorrectingDrift"
+
+	// DriftCorrectionFailedReason (Severity=Warning) documents a VSphereVM controller detecting an error
+	// while triggering the operation changing the VM back to its spec.
+	DriftCorrectionFailedReason = "DriftCorrectionFailed"
+
+	// GuestCustomizedCondition documents whether the guest OS customization requested for a VSphereVM
+	// completed on the underlying VM.
+	//
+	// NOTE: This condition does not apply to VSphereMachine.
+	GuestCustomizedCondition clusterv1.ConditionType = "GuestCustomized"
+
+	// GuestCustomizationPendingReason (Severity=Info) documents a VSphereVM whose guest OS customization
+	// is staged on the VM and runs in the guest once it boots, until VMware Tools reports its success.
+	GuestCustomizationPendingReason = "GuestCustomizationPending"
+
+	// GuestCustomizationFailedReason (Severity=Warning) documents a VSphereVM whose guest OS customization
+	// could not be staged on the VM or failed in the guest.
+	GuestCustomizationFailedReason = "GuestCustomizationFailed"
 )
 
 // Conditions and Reasons related to utilizing a VSphereIdentity to make connections to a VCenter.
@@ -143,6 +225,42 @@ const (
 	ClusterModuleSetupFailedReason = "ClusterModuleSetupFailed"
 )
 
+const (
+	// LoadBalancerAvailableCondition documents the availability of the load balancer VMs of the
+	// control plane endpoint of a VSphereCluster.
+	LoadBalancerAvailableCondition clusterv1.ConditionType = "LoadBalancerAvailable"
+
+	// LoadBalancerProvisioningReason (Severity=Info) documents that no load balancer VM of the
+	// VSphereCluster is ready yet.
+	LoadBalancerProvisioningReason = "LoadBalancerProvisioning"
+
+	// LoadBalancerProvisioningFailedReason (Severity=Error) documents a controller detecting
+	// issues while provisioning the load balancer VMs of the VSphereCluster.
+	LoadBalancerProvisioningFailedReason = "LoadBalancerProvisioningFailed"
+
+	// LoadBalancerDegradedReason (Severity=Warning) documents that some of the load balancer VMs
+	// of the VSphereCluster are not ready.
+	LoadBalancerDegradedReason = "LoadBalancerDegraded"
+
+	// LoadBalancerUnreachableReason (Severity=Warning) documents that the control plane endpoint
+	// served by the ready load balancer VMs of the VSphereCluster does not accept connections.
+	LoadBalancerUnreachableReason = "LoadBalancerUnreachable"
+)
+
+const (
+	// PrivilegesVerifiedCondition documents whether the vCenter user of a VSphereCluster or a
+	// VSphereDeploymentZone has the privileges required on the inventory objects it uses.
+	PrivilegesVerifiedCondition clusterv1.ConditionType = "PrivilegesVerified"
+
+	// MissingPrivilegesReason (Severity=Error) documents that the vCenter user lacks some of the
+	// required privileges; the condition message lists the missing privileges of each object.
+	MissingPrivilegesReason = "MissingPrivileges"
+
+	// PrivilegesVerificationFailedReason (Severity=Warning) documents a controller detecting
+	// issues while verifying the privileges of the vCenter user.
+	PrivilegesVerificationFailedReason = "PrivilegesVerificationFailed"
+)
+
 const (
 	// CredentialsAvailableCondidtion is used by VSphereClusterIdentity when a credential
 	// secret is available and unused by other VSphereClusterIdentities.
@@ -238,3 +356,16 @@ const (
 	// shutdown request fails.
 	GuestSoftPowerOffFailedReason = "GuestSoftPowerOffFailed"
 )
+
+const (
+	// MetadataTemplateRenderedCondition documents whether the custom metadata template of a VSphereVM
+	// is rendered. A template which cannot be rendered does not affect the provisioning of a VM which
+	// already runs, which is why it is reported on a condition of its own.
+	//
+	// NOTE: This condition does not apply to VSphereMachine.
+	MetadataTemplateRenderedCondition clusterv1.ConditionType = "MetadataTemplateRendered"
+
+	// MetadataTemplateInvalidReason (Severity=Warning) documents a VSphereVM whose custom metadata template
+	// cannot be retrieved, rendered or validated; a user intervention is required to fix the template.
+	MetadataTemplateInvalidReason = "MetadataTemplateInvalid"
+)
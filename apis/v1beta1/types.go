@@ -50,6 +50,14 @@ const (
 	// clone mode, but it also prevents expanding a VMs disk beyond the size of
 	// the source VM/template.
 	LinkedClone CloneMode = "linkedClone"
+
+	// InstantClone means resulting VMs are forked from the running, frozen
+	// source VM using the InstantClone API. The new VM shares the memory and
+	// disk state of the source VM at the point in time it was frozen. This is
+	// the fastest way to boot a VM, but it requires the source VM to be powered
+	// on and frozen, and the number of CPUs, memory and disks of the source VM
+	// cannot be changed.
+	InstantClone CloneMode = "instantClone"
 )
 
 // OS is the type of Operating System the virtual machine uses.
@@ -91,12 +99,64 @@ const (
 	VirtualMachinePowerOpModeTrySoft VirtualMachinePowerOpMode = "trySoft"
 )
 
+// VirtualMachineResizePolicy represents how changes to the CPUs and memory
+// of a VM are applied after it was created.
+// +kubebuilder:validation:Enum=Disabled;HotAdd;HotAddOrPowerCycle
+type VirtualMachineResizePolicy string
+
+const (
+	// VirtualMachineResizePolicyDisabled indicates that the CPUs and memory
+	// of a VM cannot be changed after it was created.
+	VirtualMachineResizePolicyDisabled VirtualMachineResizePolicy = "Disabled"
+
+	// VirtualMachineResizePolicyHotAdd indicates to add CPUs and memory to a
+	// powered on VM if CPU and memory hot add are enabled on it. Changes that
+	// cannot be hot added are applied the next time the VM is powered off.
+	VirtualMachineResizePolicyHotAdd VirtualMachineResizePolicy = "HotAdd"
+
+	// VirtualMachineResizePolicyHotAddOrPowerCycle indicates to add CPUs and
+	// memory to a powered on VM if CPU and memory hot add are enabled on it,
+	// and to power cycle the VM according to its power off mode to apply
+	// changes that cannot be hot added.
+	VirtualMachineResizePolicyHotAddOrPowerCycle VirtualMachineResizePolicy = "HotAddOrPowerCycle"
+)
+
+// VirtualMachineDriftPolicy represents how differences between the spec of a
+// VSphereVM and the configuration of its VM, e.g. caused by changes made
+// directly in vCenter, are handled.
+// +kubebuilder:validation:Enum=Report;Correct;Remediate
+type VirtualMachineDriftPolicy string
+
+const (
+	// VirtualMachineDriftPolicyReport indicates to only report the
+	// differences in the VMConfigurationInSync condition.
+	VirtualMachineDriftPolicyReport VirtualMachineDriftPolicy = "Report"
+
+	// VirtualMachineDriftPolicyCorrect indicates to change the VM back to its
+	// spec. Differences which cannot be corrected, e.g. a removed network
+	// device or CPUs which cannot be hot added to the powered on VM, are
+	// reported.
+	VirtualMachineDriftPolicyCorrect VirtualMachineDriftPolicy = "Correct"
+
+	// VirtualMachineDriftPolicyRemediate indicates to fail the VSphereVM, and
+	// in turn its VSphereMachine, so that the Machine is remediated by a
+	// MachineHealthCheck.
+	VirtualMachineDriftPolicyRemediate VirtualMachineDriftPolicy = "Remediate"
+)
+
 // VirtualMachineCloneSpec is information used to clone a virtual machine.
 type VirtualMachineCloneSpec struct {
 	// Template is the name or inventory path of the template used to clone
 	// the virtual machine.
+	// Required unless ContentLibrary is set.
 	// +kubebuilder:validation:MinLength=1
-	Template string `json:"template"`
+	// +optional
+	Template string `json:"template,omitempty"`
+
+	// ContentLibrary is the Content Library item from which the virtual
+	// machine is deployed. It is mutually exclusive with Template.
+	// +optional
+	ContentLibrary *ContentLibraryItem `json:"contentLibrary,omitempty"`
 
 	// CloneMode specifies the type of clone operation.
 	// The LinkedClone mode is only support for templates that have at least
@@ -106,6 +166,10 @@ type VirtualMachineCloneSpec struct {
 	// not possible to expand disks of linked clones.
 	// Defaults to LinkedClone, but fails gracefully to FullClone if the source
 	// of the clone operation has no snapshots.
+	// When InstantClone mode is enabled the Template must reference a powered
+	// on and frozen VM, and the NumCPUs, NumCoresPerSocket, MemoryMiB, DiskGiB
+	// and AdditionalDisksGiB fields are ignored as they are inherited from the
+	// source VM.
 	// +optional
 	CloneMode CloneMode `json:"cloneMode,omitempty"`
 
@@ -143,11 +207,26 @@ type VirtualMachineCloneSpec struct {
 	// +optional
 	Datastore string `json:"datastore,omitempty"`
 
+	// DatastoreCluster is the name or inventory path of the datastore cluster
+	// in which the virtual machine is created/located. The datastore of the
+	// cluster is chosen by the placement recommendation of Storage DRS.
+	// It cannot be set together with Datastore.
+	// +optional
+	DatastoreCluster string `json:"datastoreCluster,omitempty"`
+
 	// StoragePolicyName of the storage policy to use with this
 	// Virtual Machine
 	// +optional
 	StoragePolicyName string `json:"storagePolicyName,omitempty"`
 
+	// DatastoreSelectionPolicy is the strategy used to choose one of the
+	// datastores compatible with StoragePolicyName when neither Datastore nor
+	// DatastoreCluster is set.
+	// Defaults to the DatastoreSelectionPolicy of the VSphereCluster, or to
+	// MostFreeSpace if that is not set either.
+	// +optional
+	DatastoreSelectionPolicy DatastoreSelectionPolicy `json:"datastoreSelectionPolicy,omitempty"`
+
 	// ResourcePool is the name or inventory path of the resource pool in which
 	// the virtual machine is created/located.
 	// +optional
@@ -172,6 +251,13 @@ type VirtualMachineCloneSpec struct {
 	// virtual machine is cloned.
 	// +optional
 	MemoryMiB int64 `json:"memoryMiB,omitempty"`
+	// ResourceAllocation configures the reservations, limits and shares of
+	// the CPU and memory of the virtual machine. It is applied when the
+	// virtual machine is cloned and reconciled afterwards.
+	// Defaults to the eponymous property value in the template from which the
+	// virtual machine is cloned.
+	// +optional
+	ResourceAllocation *ResourceAllocation `json:"resourceAllocation,omitempty"`
 	// DiskGiB is the size of a virtual machine's disk, in GiB.
 	// Defaults to the eponymous property value in the template from which the
 	// virtual machine is cloned.
@@ -180,12 +266,49 @@ type VirtualMachineCloneSpec struct {
 	// AdditionalDisksGiB holds the sizes of additional disks of the virtual machine, in GiB
 	// Defaults to the eponymous property value in the template from which the
 	// virtual machine is cloned.
+	//
+	// Deprecated: Use Disks instead, which allows to configure all properties
+	// of the additional disks and to add new disks. AdditionalDisksGiB cannot
+	// be set together with Disks.
 	// +optional
 	AdditionalDisksGiB []int32 `json:"additionalDisksGiB,omitempty"`
+	// Disks is the list of additional disks of the virtual machine, in addition
+	// to its primary disk which is sized by DiskGiB.
+	// The first entries of the list configure the additional disks provided by
+	// the template, in the order in which they are attached to the template.
+	// Every remaining entry adds a new disk to the virtual machine.
+	// When LinkedClone mode is enabled only the datastore and storage policy
+	// of the disks provided by the template can be set, as these disks are
+	// backed by the snapshot of the template.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Disks []VirtualDiskSpec `json:"disks,omitempty"`
 	// CustomVMXKeys is a dictionary of advanced VMX options that can be set on VM
 	// Defaults to empty map
 	// +optional
 	CustomVMXKeys map[string]string `json:"customVMXKeys,omitempty"`
+	// BootstrapDataTransport is the transport used to pass the bootstrap data
+	// and the metadata to the guest. The NoCloud transport is not limited by
+	// the size of a guestinfo value, it requires cloud-init in the template
+	// to enable the NoCloud data source and bootstrap data in the cloud-config
+	// format.
+	// Defaults to GuestInfo.
+	// +optional
+	BootstrapDataTransport BootstrapDataTransport `json:"bootstrapDataTransport,omitempty"`
+	// BootstrapDataCleanupPolicy determines when the bootstrap data, which
+	// contains credentials like the join token, is removed from the guestinfo
+	// of the virtual machine, and the NoCloud ISO holding it is detached and
+	// deleted.
+	// Defaults to NodeRef.
+	// +optional
+	BootstrapDataCleanupPolicy BootstrapDataCleanupPolicy `json:"bootstrapDataCleanupPolicy,omitempty"`
+	// MetadataTemplate references a ConfigMap in the namespace of the
+	// machine holding a Go template which replaces the built-in cloud-init
+	// metadata template, e.g. to configure bonds or VLANs. It is rendered with
+	// the same data as the built-in template.
+	// +optional
+	MetadataTemplate *MetadataTemplateReference `json:"metadataTemplate,omitempty"`
 	// TagIDs is an optional set of tags to add to an instance. Specified tagIDs
 	// must use URN-notation instead of display names.
 	// +optional
@@ -197,12 +320,379 @@ type VirtualMachineCloneSpec struct {
 	// Defaults to Linux
 	// +optional
 	OS OS `json:"os,omitempty"`
+	// Customization configures the guest OS customization applied to a
+	// Windows virtual machine before it is powered on for the first time.
+	// It requires OS to be Windows.
+	// +optional
+	Customization *GuestCustomization `json:"customization,omitempty"`
 	// HardwareVersion is the hardware version of the virtual machine.
 	// Defaults to the eponymous property value in the template from which the
 	// virtual machine is cloned.
 	// Check the compatibility with the ESXi version before setting the value.
 	// +optional
 	HardwareVersion string `json:"hardwareVersion,omitempty"`
+	// Security configures the firmware, Secure Boot and virtual TPM of the
+	// virtual machine.
+	// Defaults to the eponymous property values in the template from which
+	// the virtual machine is cloned.
+	// +optional
+	Security *VirtualMachineSecuritySpec `json:"security,omitempty"`
+}
+
+// Firmware is the firmware of a virtual machine.
+// +kubebuilder:validation:Enum=bios;efi
+type Firmware string
+
+const (
+	// FirmwareBIOS boots the virtual machine with a legacy BIOS.
+	FirmwareBIOS Firmware = "bios"
+
+	// FirmwareEFI boots the virtual machine with UEFI.
+	FirmwareEFI Firmware = "efi"
+)
+
+// VirtualMachineSecuritySpec configures the boot security features of a
+// virtual machine.
+type VirtualMachineSecuritySpec struct {
+	// Firmware is the firmware of the virtual machine. The guest operating
+	// system of the template must be installed for the same firmware.
+	// Defaults to the eponymous property value in the template from which the
+	// virtual machine is cloned.
+	// +optional
+	Firmware Firmware `json:"firmware,omitempty"`
+
+	// SecureBoot enables UEFI Secure Boot. It requires the efi firmware.
+	// +optional
+	SecureBoot bool `json:"secureBoot,omitempty"`
+
+	// VTPM attaches a virtual Trusted Platform Module to the virtual machine.
+	// It requires the efi firmware, hardware version 14 or later and a key
+	// provider in vCenter, as the virtual machine files are encrypted.
+	// +optional
+	VTPM bool `json:"vtpm,omitempty"`
+
+	// KeyProvider is the name of the key provider used to encrypt the
+	// virtual machine files when VTPM is enabled.
+	// Defaults to the default key provider of vCenter.
+	// +optional
+	KeyProvider string `json:"keyProvider,omitempty"`
+}
+
+// MetadataTemplateReference references the key of a ConfigMap holding a
+// cloud-init metadata template.
+//
+// The template is rendered with the following data:
+//   - .Hostname is the name of the virtual machine.
+//   - .Devices are the network devices of the virtual machine, with the MAC
+//     address of the device and the addresses allocated from IPAM pools.
+//   - .Bonds and .VLANs are the bonds and VLANs of the network spec, with
+//     the addresses allocated from IPAM pools. The addresses of an interface
+//     are rendered by {{ template "interface" .NetworkInterfaceSpec }}.
+//   - .Routes are the routes of the network spec.
+//   - .WaitForIPv4 and .WaitForIPv6 are true if the devices are expected to
+//     get an IPv4 or IPv6 address.
+//
+// The rendered metadata must be a YAML mapping defining instance-id and
+// local-hostname.
+type MetadataTemplateReference struct {
+	// Name is the name of the ConfigMap.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Key is the key of the template in the ConfigMap.
+	// Defaults to metadata.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// DefaultMetadataTemplateKey is the key of the template in the ConfigMap
+// referenced by a MetadataTemplateReference which does not set a key.
+const DefaultMetadataTemplateKey = "metadata"
+
+// GuestCustomization configures the sysprep customization of a Windows
+// virtual machine. Exactly one of SpecName and Sysprep must be set.
+//
+// The customization sets the computer name to the name of the virtual
+// machine and configures its network devices, including the addresses
+// allocated from IPAM pools. The bootstrap data is still delivered through
+// the guestinfo user data, from which cloudbase-init consumes it once the
+// customization completed. Bootstrap data which is a PowerShell script, i.e.
+// starts with #ps1, is run by a command at the first logon instead. The
+// command is added to the GuiRunOnce commands of the specification stored in
+// vCenter, which must enable automatic logon.
+type GuestCustomization struct {
+	// SpecName is the name of a Windows customization specification stored
+	// in vCenter. Its computer name and network adapter settings are replaced
+	// with the ones of the virtual machine.
+	// +optional
+	SpecName string `json:"specName,omitempty"`
+
+	// Sysprep is an inline sysprep answer file. It is rendered as a Go
+	// template in which {{ .Hostname }} expands to the name of the virtual
+	// machine and {{ .BootstrapCommand }} to the command running the
+	// bootstrap data, e.g. in a FirstLogonCommands entry.
+	// +optional
+	Sysprep string `json:"sysprep,omitempty"`
+}
+
+// ContentLibraryItem identifies an item in a vSphere Content Library that is
+// used as the source of a virtual machine.
+type ContentLibraryItem struct {
+	// Library is the name of the Content Library which contains the item.
+	// Both local and subscribed libraries are supported.
+	// +kubebuilder:validation:MinLength=1
+	Library string `json:"library"`
+
+	// Item is the name or ID of the library item. The item must be either an
+	// OVF template or a VM template.
+	// +kubebuilder:validation:MinLength=1
+	Item string `json:"item"`
+}
+
+// DatastoreSelectionPolicy is the strategy used to choose a datastore among
+// the ones compatible with a storage policy.
+// +kubebuilder:validation:Enum=MostFreeSpace;LeastProvisioned;RoundRobin
+type DatastoreSelectionPolicy string
+
+const (
+	// DatastoreSelectionPolicyMostFreeSpace chooses the datastore with the
+	// most free space.
+	DatastoreSelectionPolicyMostFreeSpace DatastoreSelectionPolicy = "MostFreeSpace"
+
+	// DatastoreSelectionPolicyLeastProvisioned chooses the datastore with the
+	// lowest ratio of provisioned space, including the space not yet
+	// committed by thin provisioned disks, to capacity.
+	DatastoreSelectionPolicyLeastProvisioned DatastoreSelectionPolicy = "LeastProvisioned"
+
+	// DatastoreSelectionPolicyRoundRobin chooses the datastore hosting the
+	// fewest virtual machines of the cluster, spreading the machines of the
+	// cluster evenly across the compatible datastores.
+	DatastoreSelectionPolicyRoundRobin DatastoreSelectionPolicy = "RoundRobin"
+)
+
+// BootstrapDataTransport is the transport used to pass the bootstrap data
+// of a virtual machine to the guest.
+// +kubebuilder:validation:Enum=GuestInfo;NoCloud
+type BootstrapDataTransport string
+
+const (
+	// BootstrapDataTransportGuestInfo sets the bootstrap data and the metadata
+	// as guestinfo values in the extra config of the virtual machine.
+	BootstrapDataTransportGuestInfo BootstrapDataTransport = "GuestInfo"
+
+	// BootstrapDataTransportNoCloud writes the bootstrap data and the metadata
+	// to an ISO image with the cidata volume label, which is uploaded next to
+	// the virtual machine and attached as a CD-ROM before the first boot.
+	BootstrapDataTransportNoCloud BootstrapDataTransport = "NoCloud"
+)
+
+// BootstrapDataCleanupPolicy determines when the bootstrap data of a virtual
+// machine is removed.
+// +kubebuilder:validation:Enum=NodeRef;BootstrapSentinel
+type BootstrapDataCleanupPolicy string
+
+const (
+	// BootstrapDataCleanupPolicyNodeRef removes the bootstrap data once the
+	// node of the Machine joined the cluster.
+	BootstrapDataCleanupPolicyNodeRef BootstrapDataCleanupPolicy = "NodeRef"
+
+	// BootstrapDataCleanupPolicyBootstrapSentinel also removes the bootstrap
+	// data once the guest reports the success of the bootstrap by setting
+	// the guestinfo.cluster-api.bootstrap-status key to "success", e.g. with
+	// vmware-rpctool once the bootstrap sentinel file
+	// /run/cluster-api/bootstrap-success.complete exists.
+	BootstrapDataCleanupPolicyBootstrapSentinel BootstrapDataCleanupPolicy = "BootstrapSentinel"
+)
+
+// DiskProvisioningType is the type of provisioning used for a virtual disk.
+// +kubebuilder:validation:Enum=Thin;Thick;EagerlyZeroedThick
+type DiskProvisioningType string
+
+const (
+	// DiskProvisioningTypeThin allocates the storage of a disk on demand.
+	DiskProvisioningTypeThin DiskProvisioningType = "Thin"
+
+	// DiskProvisioningTypeThick allocates the storage of a disk upfront.
+	DiskProvisioningTypeThick DiskProvisioningType = "Thick"
+
+	// DiskProvisioningTypeEagerlyZeroedThick allocates the storage of a disk
+	// upfront and zeroes it out when the disk is created.
+	DiskProvisioningTypeEagerlyZeroedThick DiskProvisioningType = "EagerlyZeroedThick"
+)
+
+// DiskControllerType is the type of controller a virtual disk is attached to.
+// +kubebuilder:validation:Enum=SCSI;NVME;SATA
+type DiskControllerType string
+
+const (
+	// DiskControllerTypeSCSI attaches a disk to a SCSI controller.
+	DiskControllerTypeSCSI DiskControllerType = "SCSI"
+
+	// DiskControllerTypeNVME attaches a disk to a NVMe controller.
+	DiskControllerTypeNVME DiskControllerType = "NVME"
+
+	// DiskControllerTypeSATA attaches a disk to a SATA controller.
+	DiskControllerTypeSATA DiskControllerType = "SATA"
+)
+
+// DiskMode is the mode of a virtual disk, which determines how the disk is
+// affected by snapshots.
+// +kubebuilder:validation:Enum=Persistent;IndependentPersistent;IndependentNonPersistent
+type DiskMode string
+
+const (
+	// DiskModePersistent includes the disk in snapshots.
+	DiskModePersistent DiskMode = "Persistent"
+
+	// DiskModeIndependentPersistent excludes the disk from snapshots and
+	// persists all changes.
+	DiskModeIndependentPersistent DiskMode = "IndependentPersistent"
+
+	// DiskModeIndependentNonPersistent excludes the disk from snapshots and
+	// discards all changes when the virtual machine is powered off.
+	DiskModeIndependentNonPersistent DiskMode = "IndependentNonPersistent"
+)
+
+// VirtualDiskSpec defines an additional disk of a virtual machine.
+type VirtualDiskSpec struct {
+	// Name is the unique name of the disk within the list of disks.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// SizeGiB is the size of the disk, in GiB.
+	// Defaults to the size of the disk in the template for disks provided by
+	// the template.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	SizeGiB int32 `json:"sizeGiB,omitempty"`
+
+	// ProvisioningType is the type of provisioning of a new disk.
+	// It cannot be set for disks provided by the template.
+	// Defaults to Thin.
+	// +optional
+	ProvisioningType DiskProvisioningType `json:"provisioningType,omitempty"`
+
+	// Controller is the type of controller a new disk is attached to. The
+	// first controller of this type with a free slot is used.
+	// It cannot be set for disks provided by the template.
+	// Defaults to SCSI.
+	// +optional
+	Controller DiskControllerType `json:"controller,omitempty"`
+
+	// Mode is the mode of the disk.
+	// Defaults to Persistent for new disks, and to the mode of the disk in
+	// the template for disks provided by the template.
+	// +optional
+	Mode DiskMode `json:"mode,omitempty"`
+
+	// Datastore is the name or inventory path of the datastore on which the
+	// disk is placed.
+	// Defaults to the datastore of the virtual machine. Cannot be set when
+	// the virtual machine is deployed from a Content Library item.
+	// +optional
+	Datastore string `json:"datastore,omitempty"`
+
+	// StoragePolicyName is the name of the storage policy applied to the disk.
+	// Cannot be set when the virtual machine is deployed from a Content
+	// Library item, whose disks are placed with the StoragePolicyName of the
+	// virtual machine.
+	// +optional
+	StoragePolicyName string `json:"storagePolicyName,omitempty"`
+}
+
+// ResourceAllocation configures the CPU and memory resource allocation of a
+// virtual machine.
+type ResourceAllocation struct {
+	// CPU configures the CPU resource allocation of the virtual machine.
+	// The CPU resource allocation of the template is kept if it is not set.
+	// +optional
+	CPU *CPUAllocation `json:"cpu,omitempty"`
+
+	// Memory configures the memory resource allocation of the virtual machine.
+	// The memory resource allocation of the template is kept if it is not set.
+	// +optional
+	Memory *MemoryAllocation `json:"memory,omitempty"`
+}
+
+// CPUAllocation configures the CPU resource allocation of a virtual machine.
+type CPUAllocation struct {
+	// ReservationMHz is the amount of CPU guaranteed to the virtual machine,
+	// in MHz.
+	// Defaults to 0.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ReservationMHz *int64 `json:"reservationMHz,omitempty"`
+
+	// LimitMHz is the upper bound of CPU the virtual machine can consume,
+	// in MHz.
+	// Defaults to unlimited.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	LimitMHz *int64 `json:"limitMHz,omitempty"`
+
+	// Shares is the relative priority of the virtual machine when competing
+	// for CPU.
+	// Defaults to the normal shares level.
+	// +optional
+	Shares *Shares `json:"shares,omitempty"`
+}
+
+// MemoryAllocation configures the memory resource allocation of a virtual
+// machine.
+type MemoryAllocation struct {
+	// ReservationMiB is the amount of memory guaranteed to the virtual machine,
+	// in MiB.
+	// Defaults to 0.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ReservationMiB *int64 `json:"reservationMiB,omitempty"`
+
+	// LimitMiB is the upper bound of memory the virtual machine can consume,
+	// in MiB.
+	// Defaults to unlimited.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	LimitMiB *int64 `json:"limitMiB,omitempty"`
+
+	// Shares is the relative priority of the virtual machine when competing
+	// for memory.
+	// Defaults to the normal shares level.
+	// +optional
+	Shares *Shares `json:"shares,omitempty"`
+}
+
+// SharesLevel is a predefined level of shares of a resource.
+// +kubebuilder:validation:Enum=low;normal;high;custom
+type SharesLevel string
+
+const (
+	// SharesLevelLow allocates a quarter of the shares of the high level.
+	SharesLevelLow SharesLevel = "low"
+
+	// SharesLevelNormal allocates half of the shares of the high level.
+	SharesLevelNormal SharesLevel = "normal"
+
+	// SharesLevelHigh allocates the most shares of the predefined levels.
+	SharesLevelHigh SharesLevel = "high"
+
+	// SharesLevelCustom allocates the number of shares set in Shares.Value.
+	SharesLevelCustom SharesLevel = "custom"
+)
+
+// Shares is the relative priority of a virtual machine when competing for a
+// resource.
+type Shares struct {
+	// Level is the level of shares. The number of shares of the predefined
+	// levels is proportional to the number of CPUs or the amount of memory of
+	// the virtual machine.
+	// +kubebuilder:validation:Required
+	Level SharesLevel `json:"level"`
+
+	// Value is the number of shares. It must be set if, and only if, Level is
+	// custom.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Value *int32 `json:"value,omitempty"`
 }
 
 // VSphereMachineTemplateResource describes the data needed to create a VSphereMachine from a template.
@@ -276,8 +766,34 @@ type NetworkSpec struct {
 	// server endpoint on this machine
 	// +optional
 	PreferredAPIServerCIDR string `json:"preferredAPIServerCidr,omitempty"`
+
+	// Bonds is a list of bond interfaces aggregating network devices.
+	// +optional
+	Bonds []NetworkBondSpec `json:"bonds,omitempty"`
+
+	// VLANs is a list of VLAN interfaces on top of network devices or bonds.
+	// +optional
+	VLANs []NetworkVLANSpec `json:"vlans,omitempty"`
 }
 
+// NetworkAdapterType is the type of a virtual network adapter.
+// +kubebuilder:validation:Enum=vmxnet3;e1000e;sriov
+type NetworkAdapterType string
+
+const (
+	// NetworkAdapterTypeVmxnet3 is a paravirtualized network adapter.
+	NetworkAdapterTypeVmxnet3 NetworkAdapterType = "vmxnet3"
+
+	// NetworkAdapterTypeE1000e is an emulated Intel 82574 network adapter.
+	NetworkAdapterTypeE1000e NetworkAdapterType = "e1000e"
+
+	// NetworkAdapterTypeSRIOV is a virtual function of a SR-IOV capable
+	// physical network adapter, which is passed through to the virtual machine.
+	// The memory of virtual machines with SR-IOV network adapters is fully
+	// reserved.
+	NetworkAdapterTypeSRIOV NetworkAdapterType = "sriov"
+)
+
 // NetworkDeviceSpec defines the network configuration for a virtual machine's
 // network device.
 type NetworkDeviceSpec struct {
@@ -290,6 +806,19 @@ type NetworkDeviceSpec struct {
 	// +optional
 	DeviceName string `json:"deviceName,omitempty"`
 
+	// AdapterType is the type of the virtual network adapter.
+	// Defaults to vmxnet3.
+	// +optional
+	AdapterType NetworkAdapterType `json:"adapterType,omitempty"`
+
+	// PhysicalFunction is the PCI ID of the physical function of the SR-IOV
+	// capable physical network adapter which backs this device, for example
+	// 0000:3b:00.0. The ID must be the same on every host the virtual machine
+	// can be placed on.
+	// Required when AdapterType is sriov and may not be set otherwise.
+	// +optional
+	PhysicalFunction string `json:"physicalFunction,omitempty"`
+
 	// DHCP4 is a flag that indicates whether or not to use DHCP for IPv4
 	// on this device.
 	// If true then IPAddrs should not contain any IPv4 addresses.
@@ -427,6 +956,121 @@ type NetworkRouteSpec struct {
 	Metric int32 `json:"metric"`
 }
 
+// NetworkBondMode is the bonding mode of a bond interface.
+// +kubebuilder:validation:Enum=balance-rr;active-backup;balance-xor;broadcast;802.3ad;balance-tlb;balance-alb
+type NetworkBondMode string
+
+const (
+	// NetworkBondModeActiveBackup uses a single member of the bond at a time,
+	// another member takes over if it fails.
+	NetworkBondModeActiveBackup NetworkBondMode = "active-backup"
+
+	// NetworkBondMode8023AD aggregates the members of the bond with the
+	// IEEE 802.3ad link aggregation control protocol, which must be supported
+	// by the physical switches.
+	NetworkBondMode8023AD NetworkBondMode = "802.3ad"
+)
+
+// NetworkBondSpec defines a bond interface aggregating network devices of a
+// virtual machine.
+type NetworkBondSpec struct {
+	// Name is the name of the bond interface in the guest operating system.
+	// It is part of the names of the IPAddressClaims of the interface, which
+	// is why it must consist of lower case alphanumeric characters, '-' or
+	// '.', and start with a letter and end with an alphanumeric character.
+	// +kubebuilder:validation:MaxLength=15
+	// +kubebuilder:validation:Pattern=`^[a-z]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	Name string `json:"name"`
+
+	// Interfaces are the names of the network devices aggregated by the
+	// bond, i.e. their DeviceName or ethN for the Nth device without
+	// DeviceName.
+	// +kubebuilder:validation:MinItems=1
+	Interfaces []string `json:"interfaces"`
+
+	// Mode is the bonding mode.
+	// Defaults to active-backup.
+	// +optional
+	Mode NetworkBondMode `json:"mode,omitempty"`
+
+	NetworkInterfaceSpec `json:",inline"`
+}
+
+// NetworkVLANSpec defines a VLAN interface on top of a network device or a
+// bond of a virtual machine.
+type NetworkVLANSpec struct {
+	// Name is the name of the VLAN interface in the guest operating system.
+	// It is part of the names of the IPAddressClaims of the interface, which
+	// is why it must consist of lower case alphanumeric characters, '-' or
+	// '.', and start with a letter and end with an alphanumeric character.
+	// +kubebuilder:validation:MaxLength=15
+	// +kubebuilder:validation:Pattern=`^[a-z]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	Name string `json:"name"`
+
+	// ID is the VLAN ID.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=4094
+	ID int32 `json:"id"`
+
+	// Link is the name of the network device or bond on which the VLAN
+	// interface is created. Network devices are named like in the Interfaces
+	// of a bond.
+	Link string `json:"link"`
+
+	NetworkInterfaceSpec `json:",inline"`
+}
+
+// NetworkInterfaceSpec defines the address configuration of a bond or VLAN
+// interface. The fields have the same meaning as for a NetworkDeviceSpec.
+type NetworkInterfaceSpec struct {
+	// DHCP4 is a flag that indicates whether or not to use DHCP for IPv4
+	// on this interface.
+	// +optional
+	DHCP4 bool `json:"dhcp4,omitempty"`
+
+	// DHCP6 is a flag that indicates whether or not to use DHCP for IPv6
+	// on this interface.
+	// +optional
+	DHCP6 bool `json:"dhcp6,omitempty"`
+
+	// Gateway4 is the IPv4 gateway used by this interface.
+	// +optional
+	Gateway4 string `json:"gateway4,omitempty"`
+
+	// Gateway6 is the IPv6 gateway used by this interface.
+	// +optional
+	Gateway6 string `json:"gateway6,omitempty"`
+
+	// IPAddrs is a list of one or more IPv4 and/or IPv6 addresses to assign
+	// to this interface in CIDR notation.
+	// +optional
+	IPAddrs []string `json:"ipAddrs,omitempty"`
+
+	// MTU is the interface’s Maximum Transmission Unit size in bytes.
+	// +optional
+	MTU *int64 `json:"mtu,omitempty"`
+
+	// Nameservers is a list of IPv4 and/or IPv6 addresses used as DNS
+	// nameservers.
+	// +optional
+	Nameservers []string `json:"nameservers,omitempty"`
+
+	// Routes is a list of optional, static routes applied to the interface.
+	// +optional
+	Routes []NetworkRouteSpec `json:"routes,omitempty"`
+
+	// SearchDomains is a list of search domains used when resolving IP
+	// addresses with DNS.
+	// +optional
+	SearchDomains []string `json:"searchDomains,omitempty"`
+
+	// AddressesFromPools is a list of IPAddressPools that should be assigned
+	// to IPAddressClaims. The machine's cloud-init metadata will be populated
+	// with IPAddresses fulfilled by an IPAM provider.
+	// +optional
+	AddressesFromPools []corev1.TypedLocalObjectReference `json:"addressesFromPools,omitempty"`
+}
+
 // NetworkStatus provides information about one of a VM's networks.
 type NetworkStatus struct {
 	// Connected is a flag that indicates whether this network is currently
@@ -443,6 +1087,12 @@ type NetworkStatus struct {
 	// NetworkName is the name of the network.
 	// +optional
 	NetworkName string `json:"networkName,omitempty"`
+
+	// Interface is the name of the bond or VLAN interface in the guest
+	// operating system. It is only set for the status of bonds and VLANs,
+	// whose MACAddr is the one of the network device they are created on.
+	// +optional
+	Interface string `json:"interface,omitempty"`
 }
 
 // VirtualMachineState describes the state of a VM.
@@ -17,6 +17,7 @@ limitations under the License.
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
@@ -26,6 +27,10 @@ const (
 	// resources associated with VSphereCluster before removing it from the
 	// API server.
 	ClusterFinalizer = "vspherecluster.infrastructure.cluster.x-k8s.io"
+
+	// LoadBalancerLabel is the label set to the name of the VSphereCluster
+	// on the VSphereVMs of the load balancer of its control plane endpoint.
+	LoadBalancerLabel = "vspherecluster.infrastructure.cluster.x-k8s.io/load-balancer"
 )
 
 // VCenterVersion conveys the API version of the vCenter instance.
@@ -49,6 +54,13 @@ type VSphereClusterSpec struct {
 	// +optional
 	ControlPlaneEndpoint APIEndpoint `json:"controlPlaneEndpoint"`
 
+	// ControlPlaneEndpointAddressFromPool is a reference to an IP pool the
+	// host of the ControlPlaneEndpoint is claimed from when it is not set.
+	// The port of the ControlPlaneEndpoint defaults to 6443. The claimed
+	// address is released when the VSphereCluster is deleted.
+	// +optional
+	ControlPlaneEndpointAddressFromPool *corev1.TypedLocalObjectReference `json:"controlPlaneEndpointAddressFromPool,omitempty"`
+
 	// IdentityRef is a reference to either a Secret or VSphereClusterIdentity that contains
 	// the identity to use when reconciling the cluster.
 	// +optional
@@ -66,6 +78,62 @@ type VSphereClusterSpec struct {
 	// A valid selector will select all failure domains which match the selector.
 	// +optional
 	FailureDomainSelector *metav1.LabelSelector `json:"failureDomainSelector,omitempty"`
+
+	// DatastoreSelectionPolicy is the default strategy used to choose one of
+	// the datastores compatible with the storage policy of a machine of the
+	// cluster that sets neither a datastore nor a datastore cluster. It is
+	// overridden by the DatastoreSelectionPolicy of the machine.
+	// Defaults to MostFreeSpace.
+	// +optional
+	DatastoreSelectionPolicy DatastoreSelectionPolicy `json:"datastoreSelectionPolicy,omitempty"`
+
+	// MetadataTemplate references a ConfigMap in the namespace of the cluster
+	// holding the default cloud-init metadata template of the machines of the
+	// cluster. It is overridden by the MetadataTemplate of the machine.
+	// +optional
+	MetadataTemplate *MetadataTemplateReference `json:"metadataTemplate,omitempty"`
+
+	// LoadBalancer is the configuration of the load balancer VMs of the
+	// control plane endpoint provisioned for the cluster. If not set, the
+	// control plane endpoint must be served by kube-vip or an external load
+	// balancer.
+	// +optional
+	LoadBalancer *VSphereLoadBalancerSpec `json:"loadBalancer,omitempty"`
+}
+
+// VSphereLoadBalancerSpec defines the HAProxy VMs load balancing the control
+// plane endpoint of a VSphereCluster to its control plane machines. keepalived
+// assigns the host of the control plane endpoint, which must be an IP address,
+// to one of the VMs.
+type VSphereLoadBalancerSpec struct {
+	// VirtualMachineCloneSpec is the configuration of the load balancer VMs.
+	// The template must provide cloud-init, open-vm-tools, HAProxy and
+	// keepalived. The server, thumbprint, datastore selection policy and
+	// metadata template default to the ones of the VSphereCluster.
+	// Only the number of replicas of the load balancer can be changed once
+	// it is set.
+	VirtualMachineCloneSpec `json:",inline"`
+
+	// Replicas is the number of load balancer VMs.
+	// Defaults to 2.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=2
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// VirtualRouterID is the VRRP virtual router ID of keepalived, which must
+	// be unique among the load balancers sharing a network.
+	// Defaults to an ID derived from the UID of the VSphereCluster.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=255
+	// +optional
+	VirtualRouterID *int32 `json:"virtualRouterID,omitempty"`
+
+	// Interface is the name of the network interface of the load balancer VMs
+	// the host of the control plane endpoint is assigned to.
+	// Defaults to eth0.
+	// +optional
+	Interface string `json:"interface,omitempty"`
 }
 
 // ClusterModule holds the anti affinity construct `ClusterModule` identifier
@@ -68,6 +68,41 @@ type VSphereMachineSpec struct {
 	//
 	// +optional
 	GuestSoftPowerOffTimeout *metav1.Duration `json:"guestSoftPowerOffTimeout,omitempty"`
+
+	// ResizePolicy describes how changes to NumCPUs, NumCoresPerSocket and
+	// MemoryMiB are applied to the existing VM.
+	//
+	// There are three supported resize policies: Disabled, HotAdd and
+	// HotAddOrPowerCycle. With Disabled, these fields cannot be changed and
+	// a new machine has to be rolled out instead. HotAdd adds
+	// CPUs and memory to the running VM if CPU and memory hot add are enabled
+	// on it and applies any other change the next time the VM is powered off.
+	// HotAddOrPowerCycle power cycles the VM according to the PowerOffMode to
+	// apply changes that cannot be hot added. Instant clones are not resized,
+	// as their CPUs and memory are inherited from the source VM.
+	//
+	// If omitted, the policy defaults to Disabled.
+	//
+	// +optional
+	ResizePolicy VirtualMachineResizePolicy `json:"resizePolicy,omitempty"`
+
+	// DriftPolicy describes how differences between the spec and the
+	// configuration of the VM are handled. The CPUs, memory, network devices
+	// and their networks, folder and resource pool of the VM are compared to
+	// the spec once the VSphereVM has been ready. The CPUs and memory of
+	// instant clones are not compared, as they are inherited from the source
+	// VM.
+	//
+	// There are three supported drift policies: Report, Correct and
+	// Remediate. Report sets the VMConfigurationInSync condition of the
+	// VSphereVM to false. Correct reconfigures, moves or relocates the VM
+	// back to its spec where possible. Remediate fails the machine so that it
+	// is replaced by a MachineHealthCheck.
+	//
+	// If omitted, the policy defaults to Report.
+	//
+	// +optional
+	DriftPolicy VirtualMachineDriftPolicy `json:"driftPolicy,omitempty"`
 }
 
 // VSphereMachineStatus defines the observed state of VSphereMachine.
@@ -84,6 +84,50 @@ type VSphereVMSpec struct {
 	//
 	// +optional
 	GuestSoftPowerOffTimeout *metav1.Duration `json:"guestSoftPowerOffTimeout,omitempty"`
+
+	// ResizePolicy describes how changes to NumCPUs, NumCoresPerSocket and
+	// MemoryMiB are applied to the existing VM.
+	//
+	// There are three supported resize policies: Disabled, HotAdd and
+	// HotAddOrPowerCycle. With Disabled, these fields cannot be changed and
+	// a new machine has to be rolled out instead. HotAdd adds
+	// CPUs and memory to the running VM if CPU and memory hot add are enabled
+	// on it and applies any other change the next time the VM is powered off.
+	// HotAddOrPowerCycle power cycles the VM according to the PowerOffMode to
+	// apply changes that cannot be hot added. Instant clones are not resized,
+	// as their CPUs and memory are inherited from the source VM.
+	//
+	// If omitted, the policy defaults to Disabled.
+	//
+	// +optional
+	ResizePolicy VirtualMachineResizePolicy `json:"resizePolicy,omitempty"`
+
+	// DriftPolicy describes how differences between the spec and the
+	// configuration of the VM are handled. The CPUs, memory, network devices
+	// and their networks, folder and resource pool of the VM are compared to
+	// the spec once the VSphereVM has been ready. The CPUs and memory of
+	// instant clones are not compared, as they are inherited from the source
+	// VM.
+	//
+	// There are three supported drift policies: Report, Correct and
+	// Remediate. Report sets the VMConfigurationInSync condition of the
+	// VSphereVM to false. Correct reconfigures, moves or relocates the VM
+	// back to its spec where possible. Remediate fails the machine so that it
+	// is replaced by a MachineHealthCheck.
+	//
+	// If omitted, the policy defaults to Report.
+	//
+	// +optional
+	DriftPolicy VirtualMachineDriftPolicy `json:"driftPolicy,omitempty"`
+
+	// GuestInfo is a dictionary of guestinfo variables, without their
+	// "guestinfo." prefix, that are kept in sync with the extra config of the
+	// VM. It passes configuration that changes over the lifetime of the VM to
+	// the guest. Variables removed from the dictionary are kept on the VM.
+	// The userdata, metadata, vendordata, ignition and cluster-api variables
+	// are reserved.
+	// +optional
+	GuestInfo map[string]string `json:"guestInfo,omitempty"`
 }
 
 // VSphereVMStatus defines the observed state of VSphereVM.
@@ -117,10 +161,28 @@ type VSphereVMStatus struct {
 	// +optional
 	Snapshot string `json:"snapshot,omitempty"`
 
+	// Datastore is the name of the datastore on which the VM was placed when
+	// it was created. It is the datastore recommended by Storage DRS if
+	// DatastoreCluster is set.
+	// +optional
+	Datastore string `json:"datastore,omitempty"`
+
+	// BootstrapDataISO is the datastore path of the ISO image holding the
+	// bootstrap data of the VM when it uses the NoCloud bootstrap data
+	// transport. The image is deleted with the VM.
+	// +optional
+	BootstrapDataISO string `json:"bootstrapDataISO,omitempty"`
+
 	// RetryAfter tracks the time we can retry queueing a task
 	// +optional
 	RetryAfter metav1.Time `json:"retryAfter,omitempty"`
 
+	// TaskRetries is the number of consecutive tasks which failed with a
+	// transient fault. The delay before retrying a failed task, tracked by
+	// RetryAfter, doubles with each failure.
+	// +optional
+	TaskRetries int32 `json:"taskRetries,omitempty"`
+
 	// TaskRef is a managed object reference to a Task related to the machine.
 	// This value is set automatically at runtime and should not be set or
 	// modified by users.
@@ -132,6 +194,11 @@ type VSphereVMStatus struct {
 	// +optional
 	Network []NetworkStatus `json:"network,omitempty"`
 
+	// NetworkInterfaces returns the network status for each of the bonds and
+	// VLANs of the machine's network spec.
+	// +optional
+	NetworkInterfaces []NetworkStatus `json:"networkInterfaces,omitempty"`
+
 	// FailureReason will be set in the event that there is a terminal problem
 	// reconciling the vspherevm and will contain a succinct value suitable
 	// for vm interpretation.
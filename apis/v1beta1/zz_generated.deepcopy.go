@@ -59,6 +59,36 @@ func (in *AllowedNamespaces) DeepCopy() *AllowedNamespaces {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CPUAllocation) DeepCopyInto(out *CPUAllocation) {
+	*out = *in
+	if in.ReservationMHz != nil {
+		in, out := &in.ReservationMHz, &out.ReservationMHz
+		*out = new(int64)
+		**out = **in
+	}
+	if in.LimitMHz != nil {
+		in, out := &in.LimitMHz, &out.LimitMHz
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Shares != nil {
+		in, out := &in.Shares, &out.Shares
+		*out = new(Shares)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CPUAllocation.
+func (in *CPUAllocation) DeepCopy() *CPUAllocation {
+	if in == nil {
+		return nil
+	}
+	out := new(CPUAllocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterModule) DeepCopyInto(out *ClusterModule) {
 	*out = *in
@@ -74,6 +104,21 @@ func (in *ClusterModule) DeepCopy() *ClusterModule {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContentLibraryItem) DeepCopyInto(out *ContentLibraryItem) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContentLibraryItem.
+func (in *ContentLibraryItem) DeepCopy() *ContentLibraryItem {
+	if in == nil {
+		return nil
+	}
+	out := new(ContentLibraryItem)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DHCPOverrides) DeepCopyInto(out *DHCPOverrides) {
 	*out = *in
@@ -169,6 +214,66 @@ func (in *FailureDomainHosts) DeepCopy() *FailureDomainHosts {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuestCustomization) DeepCopyInto(out *GuestCustomization) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuestCustomization.
+func (in *GuestCustomization) DeepCopy() *GuestCustomization {
+	if in == nil {
+		return nil
+	}
+	out := new(GuestCustomization)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemoryAllocation) DeepCopyInto(out *MemoryAllocation) {
+	*out = *in
+	if in.ReservationMiB != nil {
+		in, out := &in.ReservationMiB, &out.ReservationMiB
+		*out = new(int64)
+		**out = **in
+	}
+	if in.LimitMiB != nil {
+		in, out := &in.LimitMiB, &out.LimitMiB
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Shares != nil {
+		in, out := &in.Shares, &out.Shares
+		*out = new(Shares)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemoryAllocation.
+func (in *MemoryAllocation) DeepCopy() *MemoryAllocation {
+	if in == nil {
+		return nil
+	}
+	out := new(MemoryAllocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetadataTemplateReference) DeepCopyInto(out *MetadataTemplateReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetadataTemplateReference.
+func (in *MetadataTemplateReference) DeepCopy() *MetadataTemplateReference {
+	if in == nil {
+		return nil
+	}
+	out := new(MetadataTemplateReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Network) DeepCopyInto(out *Network) {
 	*out = *in
@@ -194,6 +299,27 @@ func (in *Network) DeepCopy() *Network {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkBondSpec) DeepCopyInto(out *NetworkBondSpec) {
+	*out = *in
+	if in.Interfaces != nil {
+		in, out := &in.Interfaces, &out.Interfaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.NetworkInterfaceSpec.DeepCopyInto(&out.NetworkInterfaceSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkBondSpec.
+func (in *NetworkBondSpec) DeepCopy() *NetworkBondSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkBondSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NetworkDeviceSpec) DeepCopyInto(out *NetworkDeviceSpec) {
 	*out = *in
@@ -251,6 +377,53 @@ func (in *NetworkDeviceSpec) DeepCopy() *NetworkDeviceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkInterfaceSpec) DeepCopyInto(out *NetworkInterfaceSpec) {
+	*out = *in
+	if in.IPAddrs != nil {
+		in, out := &in.IPAddrs, &out.IPAddrs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MTU != nil {
+		in, out := &in.MTU, &out.MTU
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Nameservers != nil {
+		in, out := &in.Nameservers, &out.Nameservers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]NetworkRouteSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.SearchDomains != nil {
+		in, out := &in.SearchDomains, &out.SearchDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AddressesFromPools != nil {
+		in, out := &in.AddressesFromPools, &out.AddressesFromPools
+		*out = make([]v1.TypedLocalObjectReference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkInterfaceSpec.
+func (in *NetworkInterfaceSpec) DeepCopy() *NetworkInterfaceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkInterfaceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NetworkRouteSpec) DeepCopyInto(out *NetworkRouteSpec) {
 	*out = *in
@@ -281,6 +454,20 @@ func (in *NetworkSpec) DeepCopyInto(out *NetworkSpec) {
 		*out = make([]NetworkRouteSpec, len(*in))
 		copy(*out, *in)
 	}
+	if in.Bonds != nil {
+		in, out := &in.Bonds, &out.Bonds
+		*out = make([]NetworkBondSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VLANs != nil {
+		in, out := &in.VLANs, &out.VLANs
+		*out = make([]NetworkVLANSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkSpec.
@@ -313,6 +500,22 @@ func (in *NetworkStatus) DeepCopy() *NetworkStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkVLANSpec) DeepCopyInto(out *NetworkVLANSpec) {
+	*out = *in
+	in.NetworkInterfaceSpec.DeepCopyInto(&out.NetworkInterfaceSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkVLANSpec.
+func (in *NetworkVLANSpec) DeepCopy() *NetworkVLANSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkVLANSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PCIDeviceSpec) DeepCopyInto(out *PCIDeviceSpec) {
 	*out = *in
@@ -353,6 +556,31 @@ func (in *PlacementConstraint) DeepCopy() *PlacementConstraint {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceAllocation) DeepCopyInto(out *ResourceAllocation) {
+	*out = *in
+	if in.CPU != nil {
+		in, out := &in.CPU, &out.CPU
+		*out = new(CPUAllocation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		*out = new(MemoryAllocation)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceAllocation.
+func (in *ResourceAllocation) DeepCopy() *ResourceAllocation {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceAllocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SSHUser) DeepCopyInto(out *SSHUser) {
 	*out = *in
@@ -373,6 +601,26 @@ func (in *SSHUser) DeepCopy() *SSHUser {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Shares) DeepCopyInto(out *Shares) {
+	*out = *in
+	if in.Value != nil {
+		in, out := &in.Value, &out.Value
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Shares.
+func (in *Shares) DeepCopy() *Shares {
+	if in == nil {
+		return nil
+	}
+	out := new(Shares)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Topology) DeepCopyInto(out *Topology) {
 	*out = *in
@@ -567,6 +815,11 @@ func (in *VSphereClusterList) DeepCopyObject() runtime.Object {
 func (in *VSphereClusterSpec) DeepCopyInto(out *VSphereClusterSpec) {
 	*out = *in
 	out.ControlPlaneEndpoint = in.ControlPlaneEndpoint
+	if in.ControlPlaneEndpointAddressFromPool != nil {
+		in, out := &in.ControlPlaneEndpointAddressFromPool, &out.ControlPlaneEndpointAddressFromPool
+		*out = new(v1.TypedLocalObjectReference)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.IdentityRef != nil {
 		in, out := &in.IdentityRef, &out.IdentityRef
 		*out = new(VSphereIdentityReference)
@@ -582,6 +835,16 @@ func (in *VSphereClusterSpec) DeepCopyInto(out *VSphereClusterSpec) {
 		*out = new(metav1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.MetadataTemplate != nil {
+		in, out := &in.MetadataTemplate, &out.MetadataTemplate
+		*out = new(MetadataTemplateReference)
+		**out = **in
+	}
+	if in.LoadBalancer != nil {
+		in, out := &in.LoadBalancer, &out.LoadBalancer
+		*out = new(VSphereLoadBalancerSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereClusterSpec.
@@ -911,6 +1174,32 @@ func (in *VSphereIdentityReference) DeepCopy() *VSphereIdentityReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VSphereLoadBalancerSpec) DeepCopyInto(out *VSphereLoadBalancerSpec) {
+	*out = *in
+	in.VirtualMachineCloneSpec.DeepCopyInto(&out.VirtualMachineCloneSpec)
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.VirtualRouterID != nil {
+		in, out := &in.VirtualRouterID, &out.VirtualRouterID
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereLoadBalancerSpec.
+func (in *VSphereLoadBalancerSpec) DeepCopy() *VSphereLoadBalancerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VSphereLoadBalancerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VSphereMachine) DeepCopyInto(out *VSphereMachine) {
 	*out = *in
@@ -1209,6 +1498,13 @@ func (in *VSphereVMSpec) DeepCopyInto(out *VSphereVMSpec) {
 		*out = new(metav1.Duration)
 		**out = **in
 	}
+	if in.GuestInfo != nil {
+		in, out := &in.GuestInfo, &out.GuestInfo
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VSphereVMSpec.
@@ -1237,6 +1533,13 @@ func (in *VSphereVMStatus) DeepCopyInto(out *VSphereVMStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.NetworkInterfaces != nil {
+		in, out := &in.NetworkInterfaces, &out.NetworkInterfaces
+		*out = make([]NetworkStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.FailureReason != nil {
 		in, out := &in.FailureReason, &out.FailureReason
 		*out = new(errors.MachineStatusError)
@@ -1271,6 +1574,21 @@ func (in *VSphereVMStatus) DeepCopy() *VSphereVMStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualDiskSpec) DeepCopyInto(out *VirtualDiskSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualDiskSpec.
+func (in *VirtualDiskSpec) DeepCopy() *VirtualDiskSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualDiskSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtualMachine) DeepCopyInto(out *VirtualMachine) {
 	*out = *in
@@ -1296,12 +1614,27 @@ func (in *VirtualMachine) DeepCopy() *VirtualMachine {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtualMachineCloneSpec) DeepCopyInto(out *VirtualMachineCloneSpec) {
 	*out = *in
+	if in.ContentLibrary != nil {
+		in, out := &in.ContentLibrary, &out.ContentLibrary
+		*out = new(ContentLibraryItem)
+		**out = **in
+	}
 	in.Network.DeepCopyInto(&out.Network)
+	if in.ResourceAllocation != nil {
+		in, out := &in.ResourceAllocation, &out.ResourceAllocation
+		*out = new(ResourceAllocation)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.AdditionalDisksGiB != nil {
 		in, out := &in.AdditionalDisksGiB, &out.AdditionalDisksGiB
 		*out = make([]int32, len(*in))
 		copy(*out, *in)
 	}
+	if in.Disks != nil {
+		in, out := &in.Disks, &out.Disks
+		*out = make([]VirtualDiskSpec, len(*in))
+		copy(*out, *in)
+	}
 	if in.CustomVMXKeys != nil {
 		in, out := &in.CustomVMXKeys, &out.CustomVMXKeys
 		*out = make(map[string]string, len(*in))
@@ -1309,6 +1642,11 @@ func (in *VirtualMachineCloneSpec) DeepCopyInto(out *VirtualMachineCloneSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.MetadataTemplate != nil {
+		in, out := &in.MetadataTemplate, &out.MetadataTemplate
+		*out = new(MetadataTemplateReference)
+		**out = **in
+	}
 	if in.TagIDs != nil {
 		in, out := &in.TagIDs, &out.TagIDs
 		*out = make([]string, len(*in))
@@ -1321,6 +1659,16 @@ func (in *VirtualMachineCloneSpec) DeepCopyInto(out *VirtualMachineCloneSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Customization != nil {
+		in, out := &in.Customization, &out.Customization
+		*out = new(GuestCustomization)
+		**out = **in
+	}
+	if in.Security != nil {
+		in, out := &in.Security, &out.Security
+		*out = new(VirtualMachineSecuritySpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineCloneSpec.
@@ -1332,3 +1680,18 @@ func (in *VirtualMachineCloneSpec) DeepCopy() *VirtualMachineCloneSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineSecuritySpec) DeepCopyInto(out *VirtualMachineSecuritySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineSecuritySpec.
+func (in *VirtualMachineSecuritySpec) DeepCopy() *VirtualMachineSecuritySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineSecuritySpec)
+	in.DeepCopyInto(out)
+	return out
+}
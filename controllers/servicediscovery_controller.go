@@ -53,6 +53,7 @@ import (
 	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
 	vmwarecontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/vmware"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 )
 
 const (
@@ -121,7 +122,7 @@ func AddServiceDiscoveryControllerToManager(ctx context.Context, controllerManag
 				handler.OnlyControllerOwner(),
 			)).
 		WithEventFilter(predicates.ResourceNotPausedAndHasFilterLabel(ctrl.LoggerFrom(ctx), controllerManagerCtx.WatchFilterValue)).
-		Complete(r)
+		Complete(tracing.Reconciler(controllerNameShort, r))
 }
 
 type serviceDiscoveryReconciler struct {
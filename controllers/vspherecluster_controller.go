@@ -24,6 +24,7 @@ import (
 
 	"github.com/pkg/errors"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ipamv1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1alpha1"
 	clusterutilv1 "sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/predicates"
@@ -43,6 +44,7 @@ import (
 	inframanager "sigs.k8s.io/cluster-api-provider-vsphere/pkg/manager"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/vmoperator"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
 )
 
@@ -66,10 +68,12 @@ func AddClusterControllerToManager(ctx context.Context, controllerManagerCtx *ca
 	var (
 		clusterControlledTypeName = reflect.TypeOf(clusterControlledType).Elem().Name()
 		clusterControlledTypeGVK  = infrav1.GroupVersion.WithKind(clusterControlledTypeName)
+		controllerNameShort       = fmt.Sprintf("%s-controller", strings.ToLower(clusterControlledTypeName))
 		controllerNameLong        = fmt.Sprintf("%s/%s/%s", controllerManagerCtx.Namespace, controllerManagerCtx.Name, strings.ToLower(clusterControlledTypeName))
 	)
 	if supervisorBased {
 		clusterControlledTypeGVK = vmwarev1.GroupVersion.WithKind(clusterControlledTypeName)
+		controllerNameShort = fmt.Sprintf("%s-supervisor-controller", strings.ToLower(clusterControlledTypeName))
 		controllerNameLong = fmt.Sprintf("%s/%s/%s", controllerManagerCtx.Namespace, controllerManagerCtx.Name, strings.ToLower(clusterControlledTypeName))
 	}
 
@@ -97,7 +101,7 @@ func AddClusterControllerToManager(ctx context.Context, controllerManagerCtx *ca
 				handler.EnqueueRequestsFromMapFunc(reconciler.VSphereMachineToCluster),
 			).
 			WithEventFilter(predicates.ResourceNotPausedAndHasFilterLabel(ctrl.LoggerFrom(ctx), controllerManagerCtx.WatchFilterValue)).
-			Complete(reconciler)
+			Complete(tracing.Reconciler(controllerNameShort, reconciler))
 	}
 
 	reconciler := &clusterReconciler{
@@ -142,12 +146,26 @@ func AddClusterControllerToManager(ctx context.Context, controllerManagerCtx *ca
 			&infrav1.VSphereMachine{},
 			handler.EnqueueRequestsFromMapFunc(reconciler.controlPlaneMachineToCluster),
 		).
+		// Watch the control plane machines to keep the backends of the load
+		// balancer of the infrastructure cluster in sync.
+		Watches(
+			&infrav1.VSphereMachine{},
+			handler.EnqueueRequestsFromMapFunc(reconciler.loadBalancerBackendToCluster),
+		).
+		// Watch the load balancer VMs of the infrastructure cluster.
+		Owns(&infrav1.VSphereVM{}).
 		// Watch the Vsphere deployment zone with the Server field matching the
 		// server field of the VSphereCluster.
 		Watches(
 			&infrav1.VSphereDeploymentZone{},
 			handler.EnqueueRequestsFromMapFunc(reconciler.deploymentZoneToCluster),
 		).
+		// Watch the IPAddressClaim of the control plane endpoint to reconcile
+		// the infrastructure cluster once its address is allocated.
+		Watches(
+			&ipamv1.IPAddressClaim{},
+			handler.EnqueueRequestsFromMapFunc(reconciler.ipAddressClaimToVSphereCluster),
+		).
 		// Watch a GenericEvent channel for the controlled resource.
 		//
 		// This is useful when there are events outside of Kubernetes that
@@ -159,7 +177,7 @@ func AddClusterControllerToManager(ctx context.Context, controllerManagerCtx *ca
 		).
 		WithEventFilter(predicates.ResourceNotPausedAndHasFilterLabel(ctrl.LoggerFrom(ctx), controllerManagerCtx.WatchFilterValue)).
 		WithEventFilter(predicates.ResourceIsNotExternallyManaged(ctrl.LoggerFrom(ctx))).
-		Build(reconciler)
+		Build(tracing.Reconciler(controllerNameShort, reconciler))
 	if err != nil {
 		return err
 	}
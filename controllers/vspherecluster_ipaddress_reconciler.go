@@ -0,0 +1,236 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ipamv1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1alpha1"
+	clusterutilv1 "sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/constants"
+	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+// reconcileControlPlaneEndpointAddress ensures that a VSphereCluster configured
+// with .spec.controlPlaneEndpointAddressFromPool has an IPAddressClaim for its
+// control plane endpoint, and sets the host of the control plane endpoint to
+// the claimed address. It returns false while the address is not allocated.
+func (r *clusterReconciler) reconcileControlPlaneEndpointAddress(ctx context.Context, clusterCtx *capvcontext.ClusterContext) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	vsphereCluster := clusterCtx.VSphereCluster
+	poolRef := vsphereCluster.Spec.ControlPlaneEndpointAddressFromPool
+	if poolRef == nil {
+		return true, nil
+	}
+
+	// A control plane endpoint which is set before an address is claimed,
+	// e.g. by the user or another provider, is kept without claiming one.
+	if vsphereCluster.Spec.ControlPlaneEndpoint.Host != "" {
+		claim := &ipamv1.IPAddressClaim{}
+		claimKey := client.ObjectKey{
+			Namespace: vsphereCluster.Namespace,
+			Name:      util.ControlPlaneEndpointIPAddressClaimName(vsphereCluster.Name),
+		}
+		if err := r.Client.Get(ctx, claimKey, claim); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return false, errors.Wrapf(err, "failed to get IPAddressClaim %s", claimKey)
+			}
+			log.V(4).Info("Skipping IPAddressClaim, the control plane endpoint is already set", "host", vsphereCluster.Spec.ControlPlaneEndpoint.Host)
+			return true, nil
+		}
+	}
+
+	claim, created, err := r.createOrPatchControlPlaneEndpointIPAddressClaim(ctx, clusterCtx, *poolRef)
+	if err != nil {
+		conditions.MarkFalse(vsphereCluster,
+			infrav1.IPAddressClaimedCondition,
+			infrav1.IPAddressClaimNotFoundReason,
+			clusterv1.ConditionSeverityError,
+			err.Error())
+		return false, err
+	}
+	if claim.Status.AddressRef.Name == "" {
+		if created {
+			conditions.MarkFalse(vsphereCluster, infrav1.IPAddressClaimedCondition,
+				infrav1.IPAddressClaimsBeingCreatedReason, clusterv1.ConditionSeverityInfo,
+				"claim %s being created", claim.Name)
+		} else {
+			conditions.MarkFalse(vsphereCluster, infrav1.IPAddressClaimedCondition,
+				infrav1.WaitingForIPAddressReason, clusterv1.ConditionSeverityInfo,
+				"claim %s being processed", claim.Name)
+		}
+		log.Info("Waiting for the control plane endpoint address to be allocated", "IPAddressClaim", klog.KObj(claim))
+		return false, nil
+	}
+
+	ipAddr := &ipamv1.IPAddress{}
+	ipAddrKey := client.ObjectKey{
+		Namespace: claim.Namespace,
+		Name:      claim.Status.AddressRef.Name,
+	}
+	if err := r.Client.Get(ctx, ipAddrKey, ipAddr); err != nil {
+		if apierrors.IsNotFound(err) {
+			conditions.MarkFalse(vsphereCluster, infrav1.IPAddressClaimedCondition,
+				infrav1.WaitingForIPAddressReason, clusterv1.ConditionSeverityInfo,
+				"IPAddress %s not found", ipAddrKey.Name)
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "failed to get IPAddress %s", ipAddrKey)
+	}
+	addr, err := netip.ParseAddr(ipAddr.Spec.Address)
+	if err != nil {
+		conditions.MarkFalse(vsphereCluster, infrav1.IPAddressClaimedCondition,
+			infrav1.IPAddressInvalidReason, clusterv1.ConditionSeverityError,
+			"IPAddress %s has invalid ip address: %q", ipAddrKey.Name, ipAddr.Spec.Address)
+		return false, errors.Errorf("IPAddress %s has invalid ip address: %q", ipAddrKey, ipAddr.Spec.Address)
+	}
+
+	endpoint := &vsphereCluster.Spec.ControlPlaneEndpoint
+	switch endpoint.Host {
+	case "":
+		log.Info("Setting the control plane endpoint to the claimed address", "address", addr.String())
+		endpoint.Host = addr.String()
+	case addr.String():
+	default:
+		// The control plane endpoint cannot be changed once the cluster is
+		// created, so a mismatch is only reported.
+		conditions.MarkFalse(vsphereCluster, infrav1.IPAddressClaimedCondition,
+			infrav1.IPAddressInvalidReason, clusterv1.ConditionSeverityWarning,
+			"control plane endpoint host %s does not match the address %s claimed from the pool", endpoint.Host, addr)
+		return true, nil
+	}
+	if endpoint.Port == 0 {
+		endpoint.Port = constants.DefaultBindPort
+	}
+	conditions.MarkTrue(vsphereCluster, infrav1.IPAddressClaimedCondition)
+	return true, nil
+}
+
+// createOrPatchControlPlaneEndpointIPAddressClaim creates/patches the IPAddressClaim object of the control
+// plane endpoint of a VSphereCluster. Ensures that the claim has a reference to the cluster to support
+// pausing reconciliation.
+// The responsibility of the IP address resolution is handled by an external IPAM provider.
+func (r *clusterReconciler) createOrPatchControlPlaneEndpointIPAddressClaim(ctx context.Context, clusterCtx *capvcontext.ClusterContext, poolRef corev1.TypedLocalObjectReference) (*ipamv1.IPAddressClaim, bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	vsphereCluster := clusterCtx.VSphereCluster
+	claim := &ipamv1.IPAddressClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.ControlPlaneEndpointIPAddressClaimName(vsphereCluster.Name),
+			Namespace: vsphereCluster.Namespace,
+		},
+	}
+	mutateFn := func() (err error) {
+		claim.SetOwnerReferences(clusterutilv1.EnsureOwnerRef(
+			claim.OwnerReferences,
+			metav1.OwnerReference{
+				APIVersion: infrav1.GroupVersion.String(),
+				Kind:       "VSphereCluster",
+				Name:       vsphereCluster.Name,
+				UID:        vsphereCluster.UID,
+			}))
+
+		ctrlutil.AddFinalizer(claim, infrav1.IPAddressClaimFinalizer)
+
+		if claim.Labels == nil {
+			claim.Labels = make(map[string]string)
+		}
+		claim.Labels[clusterv1.ClusterNameLabel] = clusterCtx.Cluster.Name
+
+		claim.Spec.PoolRef.APIGroup = poolRef.APIGroup
+		claim.Spec.PoolRef.Kind = poolRef.Kind
+		claim.Spec.PoolRef.Name = poolRef.Name
+		return nil
+	}
+
+	result, err := ctrlutil.CreateOrPatch(ctx, r.Client, claim, mutateFn)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "failed to CreateOrPatch IPAddressClaim %s/%s", claim.Namespace, claim.Name)
+	}
+	switch result {
+	case ctrlutil.OperationResultCreated:
+		log.Info("created claim", "claim", klog.KObj(claim))
+		return claim, true, nil
+	case ctrlutil.OperationResultUpdated:
+		log.Info("updated claim", "claim", klog.KObj(claim))
+	case ctrlutil.OperationResultNone, ctrlutil.OperationResultUpdatedStatus, ctrlutil.OperationResultUpdatedStatusOnly:
+		log.V(5).Info("no change required for claim", "claim", klog.KObj(claim), "operation", result)
+	}
+	return claim, false, nil
+}
+
+// deleteControlPlaneEndpointIPAddressClaim removes the finalizer from the
+// IPAddressClaim of the control plane endpoint thus freeing it up for garbage
+// collection.
+func (r *clusterReconciler) deleteControlPlaneEndpointIPAddressClaim(ctx context.Context, clusterCtx *capvcontext.ClusterContext) error {
+	ipAddrClaim := &ipamv1.IPAddressClaim{}
+	ipAddrClaimKey := client.ObjectKey{
+		Namespace: clusterCtx.VSphereCluster.Namespace,
+		Name:      util.ControlPlaneEndpointIPAddressClaimName(clusterCtx.VSphereCluster.Name),
+	}
+	if err := r.Client.Get(ctx, ipAddrClaimKey, ipAddrClaim); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to find IPAddressClaim %s to remove the finalizer", ipAddrClaimKey)
+	}
+	if ctrlutil.RemoveFinalizer(ipAddrClaim, infrav1.IPAddressClaimFinalizer) {
+		ctrl.LoggerFrom(ctx).Info("removing finalizer", "IPAddressClaim", klog.KObj(ipAddrClaim))
+		if err := r.Client.Update(ctx, ipAddrClaim); err != nil {
+			return errors.Wrapf(err, "failed to update IPAddressClaim %s", ipAddrClaimKey)
+		}
+	}
+	return nil
+}
+
+// ipAddressClaimToVSphereCluster is a handler.ToRequestsFunc that enqueues
+// the VSphereCluster owning an IPAddressClaim of a control plane endpoint.
+func (r *clusterReconciler) ipAddressClaimToVSphereCluster(_ context.Context, o client.Object) []reconcile.Request {
+	ipAddressClaim, ok := o.(*ipamv1.IPAddressClaim)
+	if !ok {
+		return nil
+	}
+
+	for _, ref := range ipAddressClaim.OwnerReferences {
+		if ref.Kind == "VSphereCluster" {
+			return []reconcile.Request{{
+				NamespacedName: apitypes.NamespacedName{
+					Name:      ref.Name,
+					Namespace: ipAddressClaim.Namespace,
+				},
+			}}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ipamv1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1alpha1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+func Test_clusterReconciler_reconcileControlPlaneEndpointAddress(t *testing.T) {
+	ctx := context.Background()
+	setup := func(initObjects ...client.Object) (*clusterReconciler, *capvcontext.ClusterContext) {
+		controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext(initObjects...))
+		clusterCtx := fake.NewClusterContext(ctx, controllerCtx)
+		pool := poolRef("my-pool")
+		clusterCtx.VSphereCluster.Spec.ControlPlaneEndpointAddressFromPool = &pool
+		return &clusterReconciler{
+			ControllerManagerContext: controllerCtx.ControllerManagerContext,
+			Client:                   controllerCtx.Client,
+		}, clusterCtx
+	}
+	claimName := util.ControlPlaneEndpointIPAddressClaimName(fake.Clusterv1a2Name)
+	boundClaim := func(address string) []client.Object {
+		return []client.Object{
+			&ipamv1.IPAddressClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      claimName,
+					Namespace: fake.Namespace,
+				},
+				Spec: ipamv1.IPAddressClaimSpec{PoolRef: poolRef("my-pool")},
+				Status: ipamv1.IPAddressClaimStatus{
+					AddressRef: corev1.LocalObjectReference{Name: "my-address"},
+				},
+			},
+			&ipamv1.IPAddress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "my-address",
+					Namespace: fake.Namespace,
+				},
+				Spec: ipamv1.IPAddressSpec{
+					Address: address,
+					Prefix:  24,
+					PoolRef: poolRef("my-pool"),
+				},
+			},
+		}
+	}
+
+	t.Run("when no pool is referenced", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		r, clusterCtx := setup()
+		clusterCtx.VSphereCluster.Spec.ControlPlaneEndpointAddressFromPool = nil
+		ok, err := r.reconcileControlPlaneEndpointAddress(ctx, clusterCtx)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(ok).To(gomega.BeTrue())
+
+		ipAddrClaimList := &ipamv1.IPAddressClaimList{}
+		g.Expect(r.Client.List(ctx, ipAddrClaimList)).To(gomega.Succeed())
+		g.Expect(ipAddrClaimList.Items).To(gomega.BeEmpty())
+		g.Expect(conditions.Has(clusterCtx.VSphereCluster, infrav1.IPAddressClaimedCondition)).To(gomega.BeFalse())
+	})
+
+	t.Run("when the claim does not exist", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		r, clusterCtx := setup()
+		ok, err := r.reconcileControlPlaneEndpointAddress(ctx, clusterCtx)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(ok).To(gomega.BeFalse())
+
+		claim := &ipamv1.IPAddressClaim{}
+		g.Expect(r.Client.Get(ctx, client.ObjectKey{Namespace: fake.Namespace, Name: claimName}, claim)).To(gomega.Succeed())
+		g.Expect(ctrlutil.ContainsFinalizer(claim, infrav1.IPAddressClaimFinalizer)).To(gomega.BeTrue())
+		g.Expect(claim.OwnerReferences).To(gomega.HaveLen(1))
+		g.Expect(claim.OwnerReferences[0].Kind).To(gomega.Equal("VSphereCluster"))
+		g.Expect(claim.Labels).To(gomega.HaveKeyWithValue(clusterv1.ClusterNameLabel, fake.Clusterv1a2Name))
+		g.Expect(claim.Spec.PoolRef.Name).To(gomega.Equal("my-pool"))
+
+		g.Expect(clusterCtx.VSphereCluster.Spec.ControlPlaneEndpoint.IsZero()).To(gomega.BeTrue())
+		claimedCondition := conditions.Get(clusterCtx.VSphereCluster, infrav1.IPAddressClaimedCondition)
+		g.Expect(claimedCondition).NotTo(gomega.BeNil())
+		g.Expect(claimedCondition.Status).To(gomega.Equal(corev1.ConditionFalse))
+		g.Expect(claimedCondition.Reason).To(gomega.Equal(infrav1.IPAddressClaimsBeingCreatedReason))
+	})
+
+	t.Run("when the address is allocated", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		r, clusterCtx := setup(boundClaim("10.0.0.50")...)
+		ok, err := r.reconcileControlPlaneEndpointAddress(ctx, clusterCtx)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(ok).To(gomega.BeTrue())
+
+		g.Expect(clusterCtx.VSphereCluster.Spec.ControlPlaneEndpoint).To(gomega.Equal(infrav1.APIEndpoint{Host: "10.0.0.50", Port: 6443}))
+		g.Expect(conditions.IsTrue(clusterCtx.VSphereCluster, infrav1.IPAddressClaimedCondition)).To(gomega.BeTrue())
+	})
+
+	t.Run("when the control plane endpoint is already set", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		r, clusterCtx := setup(boundClaim("10.0.0.50")...)
+		clusterCtx.VSphereCluster.Spec.ControlPlaneEndpoint = infrav1.APIEndpoint{Host: "10.0.0.60", Port: 443}
+		ok, err := r.reconcileControlPlaneEndpointAddress(ctx, clusterCtx)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(ok).To(gomega.BeTrue())
+
+		g.Expect(clusterCtx.VSphereCluster.Spec.ControlPlaneEndpoint).To(gomega.Equal(infrav1.APIEndpoint{Host: "10.0.0.60", Port: 443}))
+		claimedCondition := conditions.Get(clusterCtx.VSphereCluster, infrav1.IPAddressClaimedCondition)
+		g.Expect(claimedCondition).NotTo(gomega.BeNil())
+		g.Expect(claimedCondition.Reason).To(gomega.Equal(infrav1.IPAddressInvalidReason))
+	})
+
+	t.Run("when the control plane endpoint is set before claiming an address", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		r, clusterCtx := setup()
+		clusterCtx.VSphereCluster.Spec.ControlPlaneEndpoint = infrav1.APIEndpoint{Host: "10.0.0.60", Port: 443}
+		ok, err := r.reconcileControlPlaneEndpointAddress(ctx, clusterCtx)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(ok).To(gomega.BeTrue())
+
+		ipAddrClaimList := &ipamv1.IPAddressClaimList{}
+		g.Expect(r.Client.List(ctx, ipAddrClaimList)).To(gomega.Succeed())
+		g.Expect(ipAddrClaimList.Items).To(gomega.BeEmpty())
+		g.Expect(clusterCtx.VSphereCluster.Spec.ControlPlaneEndpoint).To(gomega.Equal(infrav1.APIEndpoint{Host: "10.0.0.60", Port: 443}))
+		g.Expect(conditions.Has(clusterCtx.VSphereCluster, infrav1.IPAddressClaimedCondition)).To(gomega.BeFalse())
+	})
+
+	t.Run("when the address is invalid", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		r, clusterCtx := setup(boundClaim("10.0.0")...)
+		ok, err := r.reconcileControlPlaneEndpointAddress(ctx, clusterCtx)
+		g.Expect(err).To(gomega.HaveOccurred())
+		g.Expect(ok).To(gomega.BeFalse())
+		g.Expect(clusterCtx.VSphereCluster.Spec.ControlPlaneEndpoint.IsZero()).To(gomega.BeTrue())
+	})
+}
+
+func Test_clusterReconciler_deleteControlPlaneEndpointIPAddressClaim(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctx := context.Background()
+
+	claim := &ipamv1.IPAddressClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       util.ControlPlaneEndpointIPAddressClaimName(fake.Clusterv1a2Name),
+			Namespace:  fake.Namespace,
+			Finalizers: []string{infrav1.IPAddressClaimFinalizer, "keep.this/finalizer"},
+		},
+		Spec: ipamv1.IPAddressClaimSpec{PoolRef: poolRef("my-pool")},
+	}
+	controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext(claim))
+	clusterCtx := fake.NewClusterContext(ctx, controllerCtx)
+	r := &clusterReconciler{
+		ControllerManagerContext: controllerCtx.ControllerManagerContext,
+		Client:                   controllerCtx.Client,
+	}
+	g.Expect(r.deleteControlPlaneEndpointIPAddressClaim(ctx, clusterCtx)).To(gomega.Succeed())
+
+	g.Expect(r.Client.Get(ctx, client.ObjectKeyFromObject(claim), claim)).To(gomega.Succeed())
+	g.Expect(claim.Finalizers).To(gomega.ConsistOf("keep.this/finalizer"))
+}
@@ -0,0 +1,410 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+	clusterutilv1 "sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/constants"
+	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/loadbalancer"
+	infrautilv1 "sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+const (
+	// loadBalancerProbeTimeout is the timeout of the probe of the control
+	// plane endpoint served by the load balancer VMs.
+	loadBalancerProbeTimeout = 2 * time.Second
+
+	// loadBalancerRequeueAfter is the interval the VSphereCluster is requeued
+	// at while its load balancer is not available.
+	loadBalancerRequeueAfter = 15 * time.Second
+)
+
+// probeLoadBalancer checks that the control plane endpoint served by the load
+// balancer VMs accepts connections. It is a variable so that it can be
+// replaced in tests.
+var probeLoadBalancer = func(ctx context.Context, address string) error {
+	ctx, cancel := context.WithTimeout(ctx, loadBalancerProbeTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// reconcileLoadBalancer ensures that a VSphereCluster configured with
+// .spec.loadBalancer has load balancer VSphereVMs forwarding the control
+// plane endpoint to its control plane machines, and reports their health
+// with the LoadBalancerAvailable condition. The VSphereVMs are provisioned
+// by the VSphereVM controller. It returns true once all the load balancer VMs
+// are ready and the control plane endpoint accepts connections, or if no load
+// balancer is configured.
+func (r *clusterReconciler) reconcileLoadBalancer(ctx context.Context, clusterCtx *capvcontext.ClusterContext) (bool, error) {
+	vsphereCluster := clusterCtx.VSphereCluster
+	spec := vsphereCluster.Spec.LoadBalancer
+	if spec == nil {
+		conditions.Delete(vsphereCluster, infrav1.LoadBalancerAvailableCondition)
+		if _, err := r.deleteLoadBalancerVMs(ctx, clusterCtx, 0); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	endpoint := vsphereCluster.Spec.ControlPlaneEndpoint
+	if endpoint.Host == "" {
+		conditions.MarkFalse(vsphereCluster, infrav1.LoadBalancerAvailableCondition,
+			infrav1.LoadBalancerProvisioningReason, clusterv1.ConditionSeverityInfo,
+			"waiting for the control plane endpoint host")
+		return false, nil
+	}
+	address, err := netip.ParseAddr(endpoint.Host)
+	if err != nil {
+		conditions.MarkFalse(vsphereCluster, infrav1.LoadBalancerAvailableCondition,
+			infrav1.LoadBalancerProvisioningFailedReason, clusterv1.ConditionSeverityError,
+			"control plane endpoint host %q is not an IP address", endpoint.Host)
+		return false, nil
+	}
+	port := endpoint.Port
+	if port == 0 {
+		port = constants.DefaultBindPort
+	}
+
+	backends, err := r.getLoadBalancerBackends(ctx, clusterCtx)
+	if err != nil {
+		return false, err
+	}
+	haproxyConfig, err := loadbalancer.HAProxyConfig(address, port, backends)
+	if err != nil {
+		return false, err
+	}
+	if err := r.reconcileLoadBalancerBootstrapSecret(ctx, clusterCtx, address); err != nil {
+		return false, err
+	}
+
+	replicas := loadbalancer.Replicas(spec)
+	guestInfo := map[string]string{
+		loadbalancer.HAProxyConfigGuestInfoKey: base64.StdEncoding.EncodeToString(haproxyConfig),
+	}
+	vms := make([]*infrav1.VSphereVM, 0, replicas)
+	for i := 0; i < replicas; i++ {
+		vm, err := r.createOrPatchLoadBalancerVM(ctx, clusterCtx, i, guestInfo)
+		if err != nil {
+			return false, err
+		}
+		vms = append(vms, vm)
+	}
+	if _, err := r.deleteLoadBalancerVMs(ctx, clusterCtx, replicas); err != nil {
+		return false, err
+	}
+
+	ready := 0
+	for _, vm := range vms {
+		if vm.Status.FailureMessage != nil {
+			conditions.MarkFalse(vsphereCluster, infrav1.LoadBalancerAvailableCondition,
+				infrav1.LoadBalancerProvisioningFailedReason, clusterv1.ConditionSeverityError,
+				"load balancer VM %s failed: %s", vm.Name, *vm.Status.FailureMessage)
+			return false, nil
+		}
+		if vm.Status.Ready {
+			ready++
+		}
+	}
+	switch {
+	case ready == 0:
+		conditions.MarkFalse(vsphereCluster, infrav1.LoadBalancerAvailableCondition,
+			infrav1.LoadBalancerProvisioningReason, clusterv1.ConditionSeverityInfo,
+			"0 of %d load balancer VMs ready", replicas)
+		return false, nil
+	case ready < replicas:
+		conditions.MarkFalse(vsphereCluster, infrav1.LoadBalancerAvailableCondition,
+			infrav1.LoadBalancerDegradedReason, clusterv1.ConditionSeverityWarning,
+			"%d of %d load balancer VMs ready", ready, replicas)
+		return false, nil
+	}
+
+	if err := probeLoadBalancer(ctx, net.JoinHostPort(address.String(), strconv.Itoa(int(port)))); err != nil {
+		conditions.MarkFalse(vsphereCluster, infrav1.LoadBalancerAvailableCondition,
+			infrav1.LoadBalancerUnreachableReason, clusterv1.ConditionSeverityWarning,
+			err.Error())
+		return false, nil
+	}
+	conditions.MarkTrue(vsphereCluster, infrav1.LoadBalancerAvailableCondition)
+	return true, nil
+}
+
+// getLoadBalancerBackends returns the control plane machines of the cluster
+// which have an IP address and are not being deleted.
+func (r *clusterReconciler) getLoadBalancerBackends(ctx context.Context, clusterCtx *capvcontext.ClusterContext) ([]loadbalancer.Backend, error) {
+	vsphereMachines, err := infrautilv1.GetVSphereMachinesInCluster(ctx, r.Client, clusterCtx.Cluster.Namespace, clusterCtx.Cluster.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err,
+			"unable to list VSphereMachines part of VSphereCluster %s/%s", clusterCtx.VSphereCluster.Namespace, clusterCtx.VSphereCluster.Name)
+	}
+
+	var backends []loadbalancer.Backend
+	for _, vsphereMachine := range vsphereMachines {
+		if !infrautilv1.IsControlPlaneMachine(vsphereMachine) || !vsphereMachine.DeletionTimestamp.IsZero() {
+			continue
+		}
+		address, err := infrautilv1.GetMachinePreferredIPAddress(vsphereMachine)
+		if err != nil {
+			continue
+		}
+		backends = append(backends, loadbalancer.Backend{
+			Name:    vsphereMachine.Name,
+			Address: address,
+			Port:    constants.DefaultBindPort,
+		})
+	}
+	return backends, nil
+}
+
+// reconcileLoadBalancerBootstrapSecret ensures the secret holding the
+// bootstrap data of the load balancer VMs and their VRRP password exists.
+func (r *clusterReconciler) reconcileLoadBalancerBootstrapSecret(ctx context.Context, clusterCtx *capvcontext.ClusterContext, address netip.Addr) error {
+	vsphereCluster := clusterCtx.VSphereCluster
+	spec := vsphereCluster.Spec.LoadBalancer
+	iface := spec.Interface
+	if iface == "" {
+		iface = loadbalancer.DefaultInterface
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: vsphereCluster.Namespace,
+			Name:      loadbalancer.BootstrapSecretName(vsphereCluster.Name),
+		},
+	}
+	_, err := ctrlutil.CreateOrPatch(ctx, r.Client, secret, func() error {
+		secret.SetOwnerReferences(clusterutilv1.EnsureOwnerRef(
+			secret.OwnerReferences,
+			metav1.OwnerReference{
+				APIVersion: infrav1.GroupVersion.String(),
+				Kind:       "VSphereCluster",
+				Name:       vsphereCluster.Name,
+				UID:        vsphereCluster.UID,
+			}))
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		secret.Labels[clusterv1.ClusterNameLabel] = clusterCtx.Cluster.Name
+
+		// The VRRP password is generated once and reused, since the load
+		// balancer VMs are bootstrapped with it.
+		password := string(secret.Data[loadbalancer.VRRPPasswordSecretKey])
+		if password == "" {
+			var err error
+			if password, err = loadbalancer.GenerateVRRPPassword(); err != nil {
+				return err
+			}
+		}
+		keepalivedConfig, err := loadbalancer.KeepalivedConfig(address, iface, loadbalancer.VirtualRouterID(spec, string(vsphereCluster.UID)), password)
+		if err != nil {
+			return err
+		}
+		bootstrapData, err := loadbalancer.BootstrapData(keepalivedConfig)
+		if err != nil {
+			return err
+		}
+		secret.Data = map[string][]byte{
+			"value":                            bootstrapData,
+			"format":                           []byte(bootstrapv1.CloudConfig),
+			loadbalancer.VRRPPasswordSecretKey: []byte(password),
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to CreateOrPatch load balancer bootstrap data secret %s/%s", secret.Namespace, secret.Name)
+	}
+	return nil
+}
+
+// createOrPatchLoadBalancerVM creates/patches the load balancer VSphereVM with
+// the given index. The clone spec is only set when the VSphereVM is created
+// since it cannot be changed afterwards.
+func (r *clusterReconciler) createOrPatchLoadBalancerVM(ctx context.Context, clusterCtx *capvcontext.ClusterContext, index int, guestInfo map[string]string) (*infrav1.VSphereVM, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	vsphereCluster := clusterCtx.VSphereCluster
+	vm := &infrav1.VSphereVM{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: vsphereCluster.Namespace,
+			Name:      loadbalancer.VMName(vsphereCluster.Name, index),
+		},
+	}
+	mutateFn := func() error {
+		vm.SetOwnerReferences(clusterutilv1.EnsureOwnerRef(
+			vm.OwnerReferences,
+			metav1.OwnerReference{
+				APIVersion: infrav1.GroupVersion.String(),
+				Kind:       "VSphereCluster",
+				Name:       vsphereCluster.Name,
+				UID:        vsphereCluster.UID,
+				Controller: pointer.Bool(true),
+			}))
+
+		if vm.Labels == nil {
+			vm.Labels = map[string]string{}
+		}
+		vm.Labels[clusterv1.ClusterNameLabel] = clusterCtx.Cluster.Name
+		vm.Labels[infrav1.LoadBalancerLabel] = vsphereCluster.Name
+
+		if vm.CreationTimestamp.IsZero() {
+			vsphereCluster.Spec.LoadBalancer.VirtualMachineCloneSpec.DeepCopyInto(&vm.Spec.VirtualMachineCloneSpec)
+			if vm.Spec.Server == "" {
+				vm.Spec.Server = vsphereCluster.Spec.Server
+			}
+			if vm.Spec.Thumbprint == "" {
+				vm.Spec.Thumbprint = vsphereCluster.Spec.Thumbprint
+			}
+			if vm.Spec.DatastoreSelectionPolicy == "" {
+				vm.Spec.DatastoreSelectionPolicy = vsphereCluster.Spec.DatastoreSelectionPolicy
+			}
+			if vm.Spec.MetadataTemplate == nil && vsphereCluster.Spec.MetadataTemplate != nil {
+				vm.Spec.MetadataTemplate = vsphereCluster.Spec.MetadataTemplate.DeepCopy()
+			}
+		}
+		vm.Spec.BootstrapRef = &corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Secret",
+			Name:       loadbalancer.BootstrapSecretName(vsphereCluster.Name),
+			Namespace:  vsphereCluster.Namespace,
+		}
+		vm.Spec.GuestInfo = guestInfo
+		return nil
+	}
+
+	result, err := ctrlutil.CreateOrPatch(ctx, r.Client, vm, mutateFn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to CreateOrPatch load balancer VSphereVM %s/%s", vm.Namespace, vm.Name)
+	}
+	switch result {
+	case ctrlutil.OperationResultCreated:
+		log.Info("Created load balancer VSphereVM", "VSphereVM", klog.KObj(vm))
+	case ctrlutil.OperationResultUpdated:
+		log.Info("Updated load balancer VSphereVM", "VSphereVM", klog.KObj(vm))
+	case ctrlutil.OperationResultNone, ctrlutil.OperationResultUpdatedStatus, ctrlutil.OperationResultUpdatedStatusOnly:
+	}
+	return vm, nil
+}
+
+// deleteLoadBalancerVMs deletes the load balancer VSphereVMs of the
+// VSphereCluster with an index of at least the number of replicas, and
+// returns the number of those VSphereVMs which still exist.
+func (r *clusterReconciler) deleteLoadBalancerVMs(ctx context.Context, clusterCtx *capvcontext.ClusterContext, replicas int) (int, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	vsphereCluster := clusterCtx.VSphereCluster
+	vmList := &infrav1.VSphereVMList{}
+	if err := r.Client.List(ctx, vmList,
+		client.InNamespace(vsphereCluster.Namespace),
+		client.MatchingLabels{infrav1.LoadBalancerLabel: vsphereCluster.Name}); err != nil {
+		return 0, errors.Wrapf(err, "unable to list load balancer VSphereVMs of VSphereCluster %s/%s", vsphereCluster.Namespace, vsphereCluster.Name)
+	}
+
+	desired := map[string]bool{}
+	for i := 0; i < replicas; i++ {
+		desired[loadbalancer.VMName(vsphereCluster.Name, i)] = true
+	}
+	remaining := 0
+	for i := range vmList.Items {
+		vm := &vmList.Items[i]
+		if desired[vm.Name] {
+			continue
+		}
+		remaining++
+		if !vm.DeletionTimestamp.IsZero() {
+			continue
+		}
+		log.Info("Deleting load balancer VSphereVM", "VSphereVM", klog.KObj(vm))
+		if err := r.Client.Delete(ctx, vm); err != nil && !apierrors.IsNotFound(err) {
+			return remaining, errors.Wrapf(err, "failed to delete load balancer VSphereVM %s/%s", vm.Namespace, vm.Name)
+		}
+	}
+	return remaining, nil
+}
+
+// loadBalancerBackendToCluster is a handler.ToRequestsFunc that enqueues
+// the VSphereCluster of a control plane VSphereMachine when the VSphereCluster
+// has a load balancer, to keep the backends of the load balancer in sync.
+func (r *clusterReconciler) loadBalancerBackendToCluster(ctx context.Context, o client.Object) []ctrl.Request {
+	log := ctrl.LoggerFrom(ctx)
+
+	vsphereMachine, ok := o.(*infrav1.VSphereMachine)
+	if !ok {
+		log.Error(nil, fmt.Sprintf("expected a VSphereMachine but got a %T", o))
+		return nil
+	}
+	if !infrautilv1.IsControlPlaneMachine(vsphereMachine) {
+		return nil
+	}
+
+	vsphereCluster, err := infrautilv1.GetVSphereClusterFromVSphereMachine(ctx, r.Client, vsphereMachine)
+	if err != nil {
+		log.V(4).Info("Failed to get VSphereCluster of VSphereMachine", "VSphereMachine", klog.KObj(vsphereMachine), "err", err.Error())
+		return nil
+	}
+	if vsphereCluster.Spec.LoadBalancer == nil {
+		return nil
+	}
+	return []ctrl.Request{{
+		NamespacedName: apitypes.NamespacedName{
+			Namespace: vsphereCluster.Namespace,
+			Name:      vsphereCluster.Name,
+		},
+	}}
+}
+
+// reconcileLoadBalancerDelete deletes the load balancer VSphereVMs of the
+// VSphereCluster, and requeues until they are gone.
+func (r *clusterReconciler) reconcileLoadBalancerDelete(ctx context.Context, clusterCtx *capvcontext.ClusterContext) (reconcile.Result, error) {
+	remaining, err := r.deleteLoadBalancerVMs(ctx, clusterCtx, 0)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if remaining > 0 {
+		ctrl.LoggerFrom(ctx).Info("Waiting for load balancer VSphereVMs to be deleted", "count", remaining)
+		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+	return reconcile.Result{}, nil
+}
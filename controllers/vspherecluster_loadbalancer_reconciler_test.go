@@ -0,0 +1,245 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/loadbalancer"
+)
+
+func Test_clusterReconciler_reconcileLoadBalancer(t *testing.T) {
+	ctx := context.Background()
+	setup := func(initObjects ...client.Object) (*clusterReconciler, *capvcontext.ClusterContext) {
+		controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext(initObjects...))
+		clusterCtx := fake.NewClusterContext(ctx, controllerCtx)
+		clusterCtx.VSphereCluster.Spec.ControlPlaneEndpoint = infrav1.APIEndpoint{Host: "10.0.0.50", Port: 6443}
+		clusterCtx.VSphereCluster.Spec.LoadBalancer = &infrav1.VSphereLoadBalancerSpec{
+			VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+				Template: "haproxy",
+			},
+		}
+		return &clusterReconciler{
+			ControllerManagerContext: controllerCtx.ControllerManagerContext,
+			Client:                   controllerCtx.Client,
+		}, clusterCtx
+	}
+	loadBalancerVM := func(index int, ready bool) *infrav1.VSphereVM {
+		return &infrav1.VSphereVM{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      loadbalancer.VMName(fake.Clusterv1a2Name, index),
+				Namespace: fake.Namespace,
+				Labels:    map[string]string{infrav1.LoadBalancerLabel: fake.Clusterv1a2Name},
+			},
+			Status: infrav1.VSphereVMStatus{Ready: ready},
+		}
+	}
+	probe := func(err error) {
+		probeLoadBalancer = func(context.Context, string) error { return err }
+	}
+	defer func(fn func(context.Context, string) error) { probeLoadBalancer = fn }(probeLoadBalancer)
+
+	t.Run("when no load balancer is configured", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		r, clusterCtx := setup(loadBalancerVM(0, true))
+		clusterCtx.VSphereCluster.Spec.LoadBalancer = nil
+		ok, err := r.reconcileLoadBalancer(ctx, clusterCtx)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(ok).To(gomega.BeTrue())
+
+		vmList := &infrav1.VSphereVMList{}
+		g.Expect(r.Client.List(ctx, vmList)).To(gomega.Succeed())
+		g.Expect(vmList.Items).To(gomega.BeEmpty())
+		g.Expect(conditions.Has(clusterCtx.VSphereCluster, infrav1.LoadBalancerAvailableCondition)).To(gomega.BeFalse())
+	})
+
+	t.Run("when the load balancer VMs do not exist", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		controlPlaneMachine := &infrav1.VSphereMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "control-plane-0",
+				Namespace: fake.Namespace,
+				Labels: map[string]string{
+					clusterv1.ClusterNameLabel:         fake.Clusterv1a2Name,
+					clusterv1.MachineControlPlaneLabel: "",
+				},
+			},
+			Status: infrav1.VSphereMachineStatus{
+				Addresses: []clusterv1.MachineAddress{{Type: clusterv1.MachineExternalIP, Address: "10.0.0.10"}},
+			},
+		}
+		r, clusterCtx := setup(controlPlaneMachine)
+		ok, err := r.reconcileLoadBalancer(ctx, clusterCtx)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(ok).To(gomega.BeFalse())
+
+		secret := &corev1.Secret{}
+		g.Expect(r.Client.Get(ctx, client.ObjectKey{Namespace: fake.Namespace, Name: loadbalancer.BootstrapSecretName(fake.Clusterv1a2Name)}, secret)).To(gomega.Succeed())
+		g.Expect(string(secret.Data["format"])).To(gomega.Equal("cloud-config"))
+		g.Expect(string(secret.Data["value"])).To(gomega.ContainSubstring("10.0.0.50/32"))
+		password := string(secret.Data[loadbalancer.VRRPPasswordSecretKey])
+		g.Expect(password).To(gomega.HaveLen(8))
+		g.Expect(string(secret.Data["value"])).To(gomega.ContainSubstring("auth_pass " + password))
+
+		for i := 0; i < loadbalancer.DefaultReplicas; i++ {
+			vm := &infrav1.VSphereVM{}
+			g.Expect(r.Client.Get(ctx, client.ObjectKey{Namespace: fake.Namespace, Name: loadbalancer.VMName(fake.Clusterv1a2Name, i)}, vm)).To(gomega.Succeed())
+			g.Expect(vm.Labels).To(gomega.HaveKeyWithValue(infrav1.LoadBalancerLabel, fake.Clusterv1a2Name))
+			g.Expect(vm.OwnerReferences).To(gomega.HaveLen(1))
+			g.Expect(vm.OwnerReferences[0].Kind).To(gomega.Equal("VSphereCluster"))
+			g.Expect(vm.Spec.Template).To(gomega.Equal("haproxy"))
+			g.Expect(vm.Spec.Server).To(gomega.Equal(fake.VCenterURL))
+			g.Expect(vm.Spec.BootstrapRef.Name).To(gomega.Equal(secret.Name))
+
+			haproxyConfig, err := base64.StdEncoding.DecodeString(vm.Spec.GuestInfo[loadbalancer.HAProxyConfigGuestInfoKey])
+			g.Expect(err).ToNot(gomega.HaveOccurred())
+			g.Expect(string(haproxyConfig)).To(gomega.ContainSubstring("server control-plane-0 10.0.0.10:6443"))
+		}
+
+		availableCondition := conditions.Get(clusterCtx.VSphereCluster, infrav1.LoadBalancerAvailableCondition)
+		g.Expect(availableCondition).NotTo(gomega.BeNil())
+		g.Expect(availableCondition.Reason).To(gomega.Equal(infrav1.LoadBalancerProvisioningReason))
+
+		// The VRRP password is kept on later reconciles.
+		_, err = r.reconcileLoadBalancer(ctx, clusterCtx)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(r.Client.Get(ctx, client.ObjectKeyFromObject(secret), secret)).To(gomega.Succeed())
+		g.Expect(string(secret.Data[loadbalancer.VRRPPasswordSecretKey])).To(gomega.Equal(password))
+	})
+
+	t.Run("when a load balancer VM is not ready", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		r, clusterCtx := setup(loadBalancerVM(0, true), loadBalancerVM(1, false))
+		ok, err := r.reconcileLoadBalancer(ctx, clusterCtx)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(ok).To(gomega.BeFalse())
+
+		availableCondition := conditions.Get(clusterCtx.VSphereCluster, infrav1.LoadBalancerAvailableCondition)
+		g.Expect(availableCondition).NotTo(gomega.BeNil())
+		g.Expect(availableCondition.Reason).To(gomega.Equal(infrav1.LoadBalancerDegradedReason))
+	})
+
+	t.Run("when the load balancer VMs are ready", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		probe(nil)
+		r, clusterCtx := setup(loadBalancerVM(0, true), loadBalancerVM(1, true))
+		ok, err := r.reconcileLoadBalancer(ctx, clusterCtx)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(ok).To(gomega.BeTrue())
+		g.Expect(conditions.IsTrue(clusterCtx.VSphereCluster, infrav1.LoadBalancerAvailableCondition)).To(gomega.BeTrue())
+	})
+
+	t.Run("when the control plane endpoint is unreachable", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		probe(errors.New("connection refused"))
+		r, clusterCtx := setup(loadBalancerVM(0, true), loadBalancerVM(1, true))
+		ok, err := r.reconcileLoadBalancer(ctx, clusterCtx)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(ok).To(gomega.BeFalse())
+
+		availableCondition := conditions.Get(clusterCtx.VSphereCluster, infrav1.LoadBalancerAvailableCondition)
+		g.Expect(availableCondition).NotTo(gomega.BeNil())
+		g.Expect(availableCondition.Reason).To(gomega.Equal(infrav1.LoadBalancerUnreachableReason))
+		g.Expect(availableCondition.Message).To(gomega.Equal("connection refused"))
+	})
+
+	t.Run("when the number of replicas is reduced", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		probe(nil)
+		r, clusterCtx := setup(loadBalancerVM(0, true), loadBalancerVM(1, true))
+		clusterCtx.VSphereCluster.Spec.LoadBalancer.Replicas = pointer.Int32(1)
+		ok, err := r.reconcileLoadBalancer(ctx, clusterCtx)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(ok).To(gomega.BeTrue())
+
+		vmList := &infrav1.VSphereVMList{}
+		g.Expect(r.Client.List(ctx, vmList)).To(gomega.Succeed())
+		g.Expect(vmList.Items).To(gomega.HaveLen(1))
+		g.Expect(vmList.Items[0].Name).To(gomega.Equal(loadbalancer.VMName(fake.Clusterv1a2Name, 0)))
+		g.Expect(conditions.IsTrue(clusterCtx.VSphereCluster, infrav1.LoadBalancerAvailableCondition)).To(gomega.BeTrue())
+	})
+
+	t.Run("when the control plane endpoint host is not an IP address", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		r, clusterCtx := setup()
+		clusterCtx.VSphereCluster.Spec.ControlPlaneEndpoint.Host = "cluster.example.com"
+		ok, err := r.reconcileLoadBalancer(ctx, clusterCtx)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(ok).To(gomega.BeFalse())
+
+		availableCondition := conditions.Get(clusterCtx.VSphereCluster, infrav1.LoadBalancerAvailableCondition)
+		g.Expect(availableCondition).NotTo(gomega.BeNil())
+		g.Expect(availableCondition.Reason).To(gomega.Equal(infrav1.LoadBalancerProvisioningFailedReason))
+
+		vmList := &infrav1.VSphereVMList{}
+		g.Expect(r.Client.List(ctx, vmList)).To(gomega.Succeed())
+		g.Expect(vmList.Items).To(gomega.BeEmpty())
+	})
+}
+
+func Test_clusterReconciler_reconcileLoadBalancerDelete(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctx := context.Background()
+
+	vm := &infrav1.VSphereVM{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       loadbalancer.VMName(fake.Clusterv1a2Name, 0),
+			Namespace:  fake.Namespace,
+			Labels:     map[string]string{infrav1.LoadBalancerLabel: fake.Clusterv1a2Name},
+			Finalizers: []string{infrav1.VMFinalizer},
+		},
+	}
+	controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext(vm))
+	clusterCtx := fake.NewClusterContext(ctx, controllerCtx)
+	r := &clusterReconciler{
+		ControllerManagerContext: controllerCtx.ControllerManagerContext,
+		Client:                   controllerCtx.Client,
+	}
+
+	result, err := r.reconcileLoadBalancerDelete(ctx, clusterCtx)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(result.RequeueAfter).ToNot(gomega.BeZero())
+	g.Expect(r.Client.Get(ctx, client.ObjectKeyFromObject(vm), vm)).To(gomega.Succeed())
+	g.Expect(vm.DeletionTimestamp.IsZero()).To(gomega.BeFalse())
+
+	vm.Finalizers = nil
+	g.Expect(r.Client.Update(ctx, vm)).To(gomega.Succeed())
+	result, err = r.reconcileLoadBalancerDelete(ctx, clusterCtx)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(result.IsZero()).To(gomega.BeTrue())
+}
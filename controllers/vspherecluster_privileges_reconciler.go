@@ -0,0 +1,124 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/privileges"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	infrautilv1 "sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
+)
+
+// reconcilePrivileges verifies that the user of the vCenter session has the
+// privileges required on the inventory objects of the cluster's
+// VSphereMachines and load balancer, and reports the missing ones in the
+// PrivilegesVerified condition. Machines placed in failure domains are
+// verified by the VSphereDeploymentZones.
+func (r *clusterReconciler) reconcilePrivileges(ctx context.Context, clusterCtx *capvcontext.ClusterContext, vcenterSession *session.Session) {
+	log := ctrl.LoggerFrom(ctx)
+
+	placements, err := r.getPlacements(ctx, clusterCtx)
+	if err == nil {
+		var missing []privileges.Missing
+		if missing, err = verifyPrivileges(ctx, vcenterSession, placements); err == nil {
+			if len(missing) > 0 {
+				conditions.MarkFalse(clusterCtx.VSphereCluster, infrav1.PrivilegesVerifiedCondition, infrav1.MissingPrivilegesReason, clusterv1.ConditionSeverityError, privileges.Message(missing))
+				return
+			}
+			conditions.MarkTrue(clusterCtx.VSphereCluster, infrav1.PrivilegesVerifiedCondition)
+			return
+		}
+	}
+	log.Error(err, "unable to verify vCenter privileges")
+	conditions.MarkFalse(clusterCtx.VSphereCluster, infrav1.PrivilegesVerifiedCondition, infrav1.PrivilegesVerificationFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+}
+
+// getPlacements returns the distinct placements of the cluster's
+// VSphereMachines outside failure domains and of its load balancer.
+func (r *clusterReconciler) getPlacements(ctx context.Context, clusterCtx *capvcontext.ClusterContext) ([]privileges.Placement, error) {
+	vsphereMachines, err := infrautilv1.GetVSphereMachinesInCluster(ctx, r.Client, clusterCtx.Cluster.Namespace, clusterCtx.Cluster.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list VSphereMachines part of VSphereCluster %s/%s", clusterCtx.VSphereCluster.Namespace, clusterCtx.VSphereCluster.Name)
+	}
+
+	var specs []infrav1.VirtualMachineCloneSpec
+	for _, vsphereMachine := range vsphereMachines {
+		if vsphereMachine.Spec.FailureDomain != nil {
+			continue
+		}
+		specs = append(specs, vsphereMachine.Spec.VirtualMachineCloneSpec)
+	}
+	if loadBalancer := clusterCtx.VSphereCluster.Spec.LoadBalancer; loadBalancer != nil {
+		specs = append(specs, loadBalancer.VirtualMachineCloneSpec)
+	}
+
+	seen := map[string]bool{}
+	placements := make([]privileges.Placement, 0, len(specs))
+	for _, spec := range specs {
+		placement := privileges.Placement{
+			Datacenter:     spec.Datacenter,
+			Folder:         spec.Folder,
+			ResourcePool:   spec.ResourcePool,
+			Datastore:      spec.Datastore,
+			ContentLibrary: spec.ContentLibrary != nil,
+			StoragePolicy:  spec.StoragePolicyName != "",
+			Encryption:     spec.Security != nil && spec.Security.VTPM,
+		}
+		if spec.ContentLibrary == nil {
+			placement.Template = spec.Template
+		}
+		for _, device := range spec.Network.Devices {
+			placement.Networks = append(placement.Networks, device.NetworkName)
+		}
+		if key := fmt.Sprintf("%+v", placement); !seen[key] {
+			seen[key] = true
+			placements = append(placements, placement)
+		}
+	}
+	return placements, nil
+}
+
+// verifyPrivileges returns the privileges the user of the session is missing
+// on the inventory objects of the placements.
+func verifyPrivileges(ctx context.Context, s *session.Session, placements []privileges.Placement) ([]privileges.Missing, error) {
+	var (
+		objects []privileges.Object
+		errs    []error
+	)
+	for _, placement := range placements {
+		placementObjects, err := privileges.Objects(ctx, s.Client.Client, placement)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		objects = append(objects, placementObjects...)
+	}
+	if len(errs) > 0 {
+		return nil, kerrors.NewAggregate(errs)
+	}
+	return privileges.Verify(ctx, s.Client, objects)
+}
@@ -179,6 +179,12 @@ func (r *clusterReconciler) reconcileDelete(ctx context.Context, clusterCtx *cap
 		return reconcile.Result{RequeueAfter: 10 * time.Second}, nil
 	}
 
+	// The load balancer VMs need to be deleted before the secret deletion since
+	// they use the same credentials to access the vCenter instance.
+	if result, err := r.reconcileLoadBalancerDelete(ctx, clusterCtx); err != nil || !result.IsZero() {
+		return result, err
+	}
+
 	// The cluster module info needs to be reconciled before the secret deletion
 	// since it needs access to the vCenter instance to be able to perform LCM operations
 	// on the cluster modules.
@@ -187,6 +193,10 @@ func (r *clusterReconciler) reconcileDelete(ctx context.Context, clusterCtx *cap
 		return affinityReconcileResult, err
 	}
 
+	if err := r.deleteControlPlaneEndpointIPAddressClaim(ctx, clusterCtx); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	// Remove finalizer on Identity Secret
 	if identity.IsSecretIdentity(clusterCtx.VSphereCluster) {
 		secret := &corev1.Secret{}
@@ -244,6 +254,8 @@ func (r *clusterReconciler) reconcileNormal(ctx context.Context, clusterCtx *cap
 	}
 	conditions.MarkTrue(clusterCtx.VSphereCluster, infrav1.VCenterAvailableCondition)
 
+	r.reconcilePrivileges(ctx, clusterCtx, vcenterSession)
+
 	err = r.reconcileVCenterVersion(clusterCtx, vcenterSession)
 	if err != nil || clusterCtx.VSphereCluster.Status.VCenterVersion == "" {
 		conditions.MarkFalse(clusterCtx.VSphereCluster, infrav1.ClusterModulesAvailableCondition, infrav1.MissingVCenterVersionReason, clusterv1.ConditionSeverityWarning, "vCenter API version not set")
@@ -256,6 +268,31 @@ func (r *clusterReconciler) reconcileNormal(ctx context.Context, clusterCtx *cap
 		return affinityReconcileResult, err
 	}
 
+	ok, err = r.reconcileControlPlaneEndpointAddress(ctx, clusterCtx)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if !ok {
+		log.Info("Waiting for the control plane endpoint address to be claimed")
+		return reconcile.Result{}, nil
+	}
+
+	var result reconcile.Result
+	ok, err = r.reconcileLoadBalancer(ctx, clusterCtx)
+	if err != nil {
+		conditions.MarkFalse(clusterCtx.VSphereCluster, infrav1.LoadBalancerAvailableCondition, infrav1.LoadBalancerProvisioningFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		return reconcile.Result{}, err
+	}
+	if !ok {
+		// The load balancer is probed until it is available, which is not
+		// reported by an event.
+		result.RequeueAfter = loadBalancerRequeueAfter
+		if !clusterCtx.VSphereCluster.Status.Ready {
+			log.Info("Waiting for the load balancer to be available")
+			return result, nil
+		}
+	}
+
 	clusterCtx.VSphereCluster.Status.Ready = true
 
 	// Ensure the VSphereCluster is reconciled when the API server first comes online.
@@ -264,20 +301,20 @@ func (r *clusterReconciler) reconcileNormal(ctx context.Context, clusterCtx *cap
 	r.reconcileVSphereClusterWhenAPIServerIsOnline(ctx, clusterCtx)
 	if clusterCtx.VSphereCluster.Spec.ControlPlaneEndpoint.IsZero() {
 		log.Info("control plane endpoint is not reconciled")
-		return reconcile.Result{}, nil
+		return result, nil
 	}
 
 	// If the cluster is deleted, that's mean that the workload cluster is being deleted and so the CCM/CSI instances
 	if !clusterCtx.Cluster.DeletionTimestamp.IsZero() {
-		return reconcile.Result{}, nil
+		return result, nil
 	}
 
 	// Wait until the API server is online and accessible.
 	if !r.isAPIServerOnline(ctx, clusterCtx) {
-		return reconcile.Result{}, nil
+		return result, nil
 	}
 
-	return reconcile.Result{}, nil
+	return result, nil
 }
 
 func (r *clusterReconciler) reconcileIdentitySecret(ctx context.Context, clusterCtx *capvcontext.ClusterContext) error {
@@ -43,6 +43,7 @@ import (
 	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
 	pkgidentity "sigs.k8s.io/cluster-api-provider-vsphere/pkg/identity"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 )
 
 const (
@@ -77,7 +78,7 @@ func AddVsphereClusterIdentityControllerToManager(ctx context.Context, controlle
 		For(identityControlledType).
 		WithOptions(options).
 		WithEventFilter(predicates.ResourceNotPausedAndHasFilterLabel(ctrl.LoggerFrom(ctx), controllerManagerCtx.WatchFilterValue)).
-		Complete(reconciler)
+		Complete(tracing.Reconciler(controllerNameShort, reconciler))
 }
 
 type clusterIdentityReconciler struct {
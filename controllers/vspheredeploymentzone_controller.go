@@ -49,6 +49,7 @@ import (
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/identity"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
 )
 
@@ -92,7 +93,7 @@ func AddVSphereDeploymentZoneControllerToManager(ctx context.Context, controller
 			&handler.EnqueueRequestForObject{},
 		).
 		WithEventFilter(predicates.ResourceNotPausedAndHasFilterLabel(ctrl.LoggerFrom(ctx), controllerManagerCtx.WatchFilterValue)).
-		Complete(reconciler)
+		Complete(tracing.Reconciler(controllerNameShort, reconciler))
 }
 
 type vsphereDeploymentZoneReconciler struct {
@@ -178,6 +179,8 @@ func (r vsphereDeploymentZoneReconciler) reconcileNormal(ctx context.Context, de
 		return errors.Wrapf(err, "failed to reconcile failure domain")
 	}
 
+	r.reconcilePrivileges(ctx, deploymentZoneCtx, failureDomain)
+
 	// Mark the deployment zone as ready.
 	deploymentZoneCtx.VSphereDeploymentZone.Status.Ready = pointer.Bool(true)
 	return nil
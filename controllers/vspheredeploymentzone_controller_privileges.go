@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/privileges"
+)
+
+// reconcilePrivileges verifies that the user of the vCenter session has the
+// privileges required on the inventory objects of the failure domain and the
+// placement constraint, and reports the missing ones in the
+// PrivilegesVerified condition.
+func (r vsphereDeploymentZoneReconciler) reconcilePrivileges(ctx context.Context, deploymentZoneCtx *capvcontext.VSphereDeploymentZoneContext, vsphereFailureDomain *infrav1.VSphereFailureDomain) {
+	topology := vsphereFailureDomain.Spec.Topology
+	placementConstraint := deploymentZoneCtx.VSphereDeploymentZone.Spec.PlacementConstraint
+
+	placement := privileges.Placement{
+		Datacenter:   topology.Datacenter,
+		Folder:       placementConstraint.Folder,
+		ResourcePool: placementConstraint.ResourcePool,
+		Datastore:    topology.Datastore,
+		Networks:     topology.Networks,
+	}
+	if topology.ComputeCluster != nil {
+		placement.ComputeCluster = *topology.ComputeCluster
+	}
+
+	missing, err := verifyPrivileges(ctx, deploymentZoneCtx.AuthSession, []privileges.Placement{placement})
+	if err != nil {
+		deploymentZoneCtx.Logger.Error(err, "unable to verify vCenter privileges")
+		conditions.MarkFalse(deploymentZoneCtx.VSphereDeploymentZone, infrav1.PrivilegesVerifiedCondition, infrav1.PrivilegesVerificationFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		return
+	}
+	if len(missing) > 0 {
+		conditions.MarkFalse(deploymentZoneCtx.VSphereDeploymentZone, infrav1.PrivilegesVerifiedCondition, infrav1.MissingPrivilegesReason, clusterv1.ConditionSeverityError, privileges.Message(missing))
+		return
+	}
+	conditions.MarkTrue(deploymentZoneCtx.VSphereDeploymentZone, infrav1.PrivilegesVerifiedCondition)
+}
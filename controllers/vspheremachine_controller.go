@@ -59,6 +59,7 @@ import (
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/vmoperator"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
 )
 
@@ -164,7 +165,7 @@ func AddMachineControllerToManager(ctx context.Context, controllerManagerContext
 		)
 	}
 
-	return builder.Complete(r)
+	return builder.Complete(tracing.Reconciler(controllerNameShort, r))
 }
 
 type machineReconciler struct {
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -60,6 +61,7 @@ import (
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
 )
 
@@ -144,7 +146,7 @@ func AddVMControllerToManager(ctx context.Context, controllerManagerCtx *capvcon
 			&ipamv1.IPAddressClaim{},
 			handler.EnqueueRequestsFromMapFunc(r.ipAddressClaimToVSphereVM),
 		).
-		Complete(r)
+		Complete(tracing.Reconciler(controllerNameShort, r))
 }
 
 type vmReconciler struct {
@@ -185,43 +187,57 @@ func (r vmReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.R
 	}
 	conditions.MarkTrue(vsphereVM, infrav1.VCenterAvailableCondition)
 
-	// Fetch the owner VSphereMachine.
-	vsphereMachine, err := util.GetOwnerVSphereMachine(ctx, r.Client, vsphereVM.ObjectMeta)
-	// vsphereMachine can be nil in cases where custom mover other than clusterctl
-	// moves the resources without ownerreferences set
-	// in that case nil vsphereMachine can cause panic and CrashLoopBackOff the pod
-	// preventing vspheremachine_controller from setting the ownerref
-	if err != nil || vsphereMachine == nil {
-		r.Logger.Info("Owner VSphereMachine not found, won't reconcile", "key", req.NamespacedName)
-		return reconcile.Result{}, nil
-	}
-
-	vsphereCluster, err := util.GetVSphereClusterFromVSphereMachine(ctx, r.Client, vsphereMachine)
-	if err != nil || vsphereCluster == nil {
-		r.Logger.Info("VSphereCluster not found, won't reconcile", "key", ctrlclient.ObjectKeyFromObject(vsphereMachine))
-		return reconcile.Result{}, nil
-	}
+	var (
+		vsphereCluster       *infrav1.VSphereCluster
+		machine              *clusterv1.Machine
+		vsphereFailureDomain *infrav1.VSphereFailureDomain
+	)
+	if clusterName, ok := vsphereVM.Labels[infrav1.LoadBalancerLabel]; ok {
+		// Load balancer VMs are owned by their VSphereCluster rather than by a
+		// VSphereMachine and a Machine.
+		vsphereCluster = &infrav1.VSphereCluster{}
+		if err := r.Client.Get(ctx, apitypes.NamespacedName{Namespace: vsphereVM.Namespace, Name: clusterName}, vsphereCluster); err != nil {
+			r.Logger.Info("VSphereCluster not found, won't reconcile", "key", req.NamespacedName, "cluster", clusterName)
+			return reconcile.Result{}, nil
+		}
+	} else {
+		// Fetch the owner VSphereMachine.
+		vsphereMachine, err := util.GetOwnerVSphereMachine(ctx, r.Client, vsphereVM.ObjectMeta)
+		// vsphereMachine can be nil in cases where custom mover other than clusterctl
+		// moves the resources without ownerreferences set
+		// in that case nil vsphereMachine can cause panic and CrashLoopBackOff the pod
+		// preventing vspheremachine_controller from setting the ownerref
+		if err != nil || vsphereMachine == nil {
+			r.Logger.Info("Owner VSphereMachine not found, won't reconcile", "key", req.NamespacedName)
+			return reconcile.Result{}, nil
+		}
 
-	// Fetch the CAPI Machine.
-	machine, err := clusterutilv1.GetOwnerMachine(ctx, r.Client, vsphereMachine.ObjectMeta)
-	if err != nil {
-		return reconcile.Result{}, err
-	}
-	if machine == nil {
-		r.Logger.Info("Waiting for OwnerRef to be set on VSphereMachine", "key", vsphereMachine.Name)
-		return reconcile.Result{}, nil
-	}
+		vsphereCluster, err = util.GetVSphereClusterFromVSphereMachine(ctx, r.Client, vsphereMachine)
+		if err != nil || vsphereCluster == nil {
+			r.Logger.Info("VSphereCluster not found, won't reconcile", "key", ctrlclient.ObjectKeyFromObject(vsphereMachine))
+			return reconcile.Result{}, nil
+		}
 
-	var vsphereFailureDomain *infrav1.VSphereFailureDomain
-	if failureDomain := machine.Spec.FailureDomain; failureDomain != nil {
-		vsphereDeploymentZone := &infrav1.VSphereDeploymentZone{}
-		if err := r.Client.Get(ctx, apitypes.NamespacedName{Name: *failureDomain}, vsphereDeploymentZone); err != nil {
-			return reconcile.Result{}, errors.Wrapf(err, "failed to find vsphere deployment zone %s", *failureDomain)
+		// Fetch the CAPI Machine.
+		machine, err = clusterutilv1.GetOwnerMachine(ctx, r.Client, vsphereMachine.ObjectMeta)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if machine == nil {
+			r.Logger.Info("Waiting for OwnerRef to be set on VSphereMachine", "key", vsphereMachine.Name)
+			return reconcile.Result{}, nil
 		}
 
-		vsphereFailureDomain = &infrav1.VSphereFailureDomain{}
-		if err := r.Client.Get(ctx, apitypes.NamespacedName{Name: vsphereDeploymentZone.Spec.FailureDomain}, vsphereFailureDomain); err != nil {
-			return reconcile.Result{}, errors.Wrapf(err, "failed to find vsphere failure domain %s", vsphereDeploymentZone.Spec.FailureDomain)
+		if failureDomain := machine.Spec.FailureDomain; failureDomain != nil {
+			vsphereDeploymentZone := &infrav1.VSphereDeploymentZone{}
+			if err := r.Client.Get(ctx, apitypes.NamespacedName{Name: *failureDomain}, vsphereDeploymentZone); err != nil {
+				return reconcile.Result{}, errors.Wrapf(err, "failed to find vsphere deployment zone %s", *failureDomain)
+			}
+
+			vsphereFailureDomain = &infrav1.VSphereFailureDomain{}
+			if err := r.Client.Get(ctx, apitypes.NamespacedName{Name: vsphereDeploymentZone.Spec.FailureDomain}, vsphereFailureDomain); err != nil {
+				return reconcile.Result{}, errors.Wrapf(err, "failed to find vsphere failure domain %s", vsphereDeploymentZone.Spec.FailureDomain)
+			}
 		}
 	}
 
@@ -230,6 +246,7 @@ func (r vmReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.R
 		ControllerContext:    r.ControllerContext,
 		VSphereVM:            vsphereVM,
 		VSphereFailureDomain: vsphereFailureDomain,
+		Machine:              machine,
 		Session:              authSession,
 		Logger:               r.Logger.WithName(req.Namespace).WithName(req.Name),
 		PatchHelper:          patchHelper,
@@ -293,7 +310,7 @@ func (r vmReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.R
 // This logic was moved to a smaller function outside of the main Reconcile() loop
 // for the ease of testing.
 func (r vmReconciler) reconcile(ctx context.Context, vmCtx *capvcontext.VMContext, input fetchClusterModuleInput) (reconcile.Result, error) {
-	if feature.Gates.Enabled(feature.NodeAntiAffinity) {
+	if feature.Gates.Enabled(feature.NodeAntiAffinity) && input.Machine != nil {
 		clusterModuleInfo, err := r.fetchClusterModuleInfo(ctx, input)
 		// If cluster module information cannot be fetched for a VM being deleted,
 		// we should not block VM deletion since the cluster module is updated
@@ -334,14 +351,17 @@ func (r vmReconciler) reconcileDelete(ctx context.Context, vmCtx *capvcontext.VM
 		return reconcile.Result{}, nil
 	}
 
-	// Attempt to delete the node corresponding to the vsphere VM
-	result, err = r.deleteNode(ctx, vmCtx, vm.Name)
-	if err != nil {
-		r.Logger.V(6).Info("unable to delete node", "err", err)
-	}
-	if !result.IsZero() {
-		// a non-zero value means we need to requeue the request before proceed.
-		return result, nil
+	// Attempt to delete the node corresponding to the vsphere VM. Load
+	// balancer VMs do not join the cluster.
+	if vmCtx.Machine != nil {
+		result, err = r.deleteNode(ctx, vmCtx, vm.Name)
+		if err != nil {
+			r.Logger.V(6).Info("unable to delete node", "err", err)
+		}
+		if !result.IsZero() {
+			// a non-zero value means we need to requeue the request before proceed.
+			return result, nil
+		}
 	}
 
 	if err := r.deleteIPAddressClaims(ctx, vmCtx); err != nil {
@@ -399,7 +419,7 @@ func (r vmReconciler) reconcileNormal(ctx context.Context, vmCtx *capvcontext.VM
 	}
 
 	// Get or create the VM.
-	vm, err := r.VMService.ReconcileVM(ctx, vmCtx)
+	result, vm, err := r.VMService.ReconcileVM(ctx, vmCtx)
 	if err != nil {
 		vmCtx.Logger.Error(err, "error reconciling VM")
 		return reconcile.Result{}, errors.Wrapf(err, "failed to reconcile VM")
@@ -411,7 +431,7 @@ func (r vmReconciler) reconcileNormal(ctx context.Context, vmCtx *capvcontext.VM
 			"VM state is not reconciled",
 			"expected-vm-state", infrav1.VirtualMachineStateReady,
 			"actual-vm-state", vm.State)
-		return reconcile.Result{}, nil
+		return result, nil
 	}
 
 	// Update the VSphereVM's BIOS UUID.
@@ -449,14 +469,27 @@ func (r vmReconciler) reconcileNormal(ctx context.Context, vmCtx *capvcontext.VM
 // to be allocated.
 // It checks the state of both DHCP4 and DHCP6 for all the network devices and if
 // any static IP addresses or IPAM Pools are specified.
+// Devices and bonds on which a bond or VLAN is created do not need addresses
+// themselves.
 func (r vmReconciler) isWaitingForStaticIPAllocation(vmCtx *capvcontext.VMContext) bool {
-	devices := vmCtx.VSphereVM.Spec.Network.Devices
-	for _, dev := range devices {
+	network := &vmCtx.VSphereVM.Spec.Network
+	for i, dev := range network.Devices {
+		if util.IsNetworkInterfaceLink(network, util.NetworkDeviceName(dev, i)) {
+			continue
+		}
 		if !dev.DHCP4 && !dev.DHCP6 && len(dev.IPAddrs) == 0 && len(dev.AddressesFromPools) == 0 {
 			// Static IP is not available yet
 			return true
 		}
 	}
+	for _, iface := range util.NetworkInterfaces(network) {
+		if util.IsNetworkInterfaceLink(network, iface.Name) {
+			continue
+		}
+		if !iface.DHCP4 && !iface.DHCP6 && len(iface.IPAddrs) == 0 && len(iface.AddressesFromPools) == 0 {
+			return true
+		}
+	}
 
 	return false
 }
@@ -467,6 +500,13 @@ func (r vmReconciler) reconcileNetwork(vmCtx *capvcontext.VMContext, vm infrav1.
 	for _, netStatus := range vmCtx.VSphereVM.Status.Network {
 		ipAddrs = append(ipAddrs, netStatus.IPAddrs...)
 	}
+	for _, netStatus := range vmCtx.VSphereVM.Status.NetworkInterfaces {
+		for _, ipAddr := range netStatus.IPAddrs {
+			if !slices.Contains(ipAddrs, ipAddr) {
+				ipAddrs = append(ipAddrs, ipAddr)
+			}
+		}
+	}
 	vmCtx.VSphereVM.Status.Addresses = ipAddrs
 }
 
@@ -223,7 +223,7 @@ func TestReconcileNormal_WaitingForIPAddrAllocation(t *testing.T) {
 			},
 		})()
 		fakeVMSvc := new(fake_svc.VMService)
-		fakeVMSvc.On("ReconcileVM", mock.Anything).Return(infrav1.VirtualMachine{
+		fakeVMSvc.On("ReconcileVM", mock.Anything).Return(reconcile.Result{}, infrav1.VirtualMachine{
 			Name:     vsphereVM.Name,
 			BiosUUID: "265104de-1472-547c-b873-6dc7883fb6cb",
 			State:    infrav1.VirtualMachineStatePending,
@@ -251,7 +251,7 @@ func TestReconcileNormal_WaitingForIPAddrAllocation(t *testing.T) {
 			},
 		})()
 		fakeVMSvc := new(fake_svc.VMService)
-		fakeVMSvc.On("ReconcileVM", mock.Anything).Return(infrav1.VirtualMachine{
+		fakeVMSvc.On("ReconcileVM", mock.Anything).Return(reconcile.Result{}, infrav1.VirtualMachine{
 			Name:     vsphereVM.Name,
 			BiosUUID: "265104de-1472-547c-b873-6dc7883fb6cb",
 			State:    infrav1.VirtualMachineStateReady,
@@ -329,6 +329,7 @@ func TestVmReconciler_WaitingForStaticIPAllocation(t *testing.T) {
 	tests := []struct {
 		name       string
 		devices    []infrav1.NetworkDeviceSpec
+		vlans      []infrav1.NetworkVLANSpec
 		shouldWait bool
 	}{
 		{
@@ -367,6 +368,18 @@ func TestVmReconciler_WaitingForStaticIPAllocation(t *testing.T) {
 			},
 			shouldWait: true,
 		},
+		{
+			name:       "for n/w device with a VLAN with DHCP4 set to true",
+			devices:    []infrav1.NetworkDeviceSpec{{NetworkName: "nw-1"}},
+			vlans:      []infrav1.NetworkVLANSpec{{Name: "vlan100", ID: 100, Link: "eth0", NetworkInterfaceSpec: infrav1.NetworkInterfaceSpec{DHCP4: true}}},
+			shouldWait: false,
+		},
+		{
+			name:       "for n/w device with a VLAN with DHCP4, DHCP6 & IP address unset",
+			devices:    []infrav1.NetworkDeviceSpec{{NetworkName: "nw-1"}},
+			vlans:      []infrav1.NetworkVLANSpec{{Name: "vlan100", ID: 100, Link: "eth0"}},
+			shouldWait: true,
+		},
 	}
 
 	controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext())
@@ -377,7 +390,7 @@ func TestVmReconciler_WaitingForStaticIPAllocation(t *testing.T) {
 		// Need to explicitly reinitialize test variable, looks odd, but needed
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			vmContext.VSphereVM.Spec.Network = infrav1.NetworkSpec{Devices: tt.devices}
+			vmContext.VSphereVM.Spec.Network = infrav1.NetworkSpec{Devices: tt.devices, VLANs: tt.vlans}
 			isWaiting := r.isWaitingForStaticIPAllocation(vmContext)
 			g := NewWithT(t)
 			g.Expect(isWaiting).To(Equal(tt.shouldWait))
@@ -584,7 +597,7 @@ func Test_reconcile(t *testing.T) {
 		t.Run("when info cannot be fetched", func(t *testing.T) {
 			t.Run("when anti affinity feature gate is turned off", func(t *testing.T) {
 				fakeVMSvc := new(fake_svc.VMService)
-				fakeVMSvc.On("ReconcileVM", mock.Anything).Return(infrav1.VirtualMachine{
+				fakeVMSvc.On("ReconcileVM", mock.Anything).Return(reconcile.Result{}, infrav1.VirtualMachine{
 					Name:     vsphereVM.Name,
 					BiosUUID: "265104de-1472-547c-b873-6dc7883fb6cb",
 					State:    infrav1.VirtualMachineStateReady,
@@ -624,7 +637,7 @@ func Test_reconcile(t *testing.T) {
 			objsWithHierarchy := initObjs
 			objsWithHierarchy = append(objsWithHierarchy, createMachineOwnerHierarchy(machine)...)
 			fakeVMSvc := new(fake_svc.VMService)
-			fakeVMSvc.On("ReconcileVM", mock.Anything).Return(infrav1.VirtualMachine{
+			fakeVMSvc.On("ReconcileVM", mock.Anything).Return(reconcile.Result{}, infrav1.VirtualMachine{
 				Name:     vsphereVM.Name,
 				BiosUUID: "265104de-1472-547c-b873-6dc7883fb6cb",
 				State:    infrav1.VirtualMachineStateReady,
@@ -19,6 +19,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -35,6 +36,7 @@ import (
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/metrics"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
 )
 
@@ -50,40 +52,51 @@ func (r vmReconciler) reconcileIPAddressClaims(ctx context.Context, vmCtx *capvc
 	var (
 		claims  []conditions.Getter
 		errList []error
+
+		firstClaimCreated time.Time
 	)
 
-	for devIdx, device := range vmCtx.VSphereVM.Spec.Network.Devices {
-		for poolRefIdx, poolRef := range device.AddressesFromPools {
-			totalClaims++
-			ipAddrClaimName := util.IPAddressClaimName(vmCtx.VSphereVM.Name, devIdx, poolRefIdx)
-			ipAddrClaim := &ipamv1.IPAddressClaim{}
-			ipAddrClaimKey := client.ObjectKey{
-				Namespace: vmCtx.VSphereVM.Namespace,
-				Name:      ipAddrClaimName,
-			}
-			err := vmCtx.Client.Get(ctx, ipAddrClaimKey, ipAddrClaim)
-			if err != nil && !apierrors.IsNotFound(err) {
-				vmCtx.Logger.Error(err, "fetching IPAddressClaim failed", "name", ipAddrClaimName)
-				return err
-			}
-			ipAddrClaim, created, err := createOrPatchIPAddressClaim(ctx, vmCtx, ipAddrClaimName, poolRef)
-			if err != nil {
-				vmCtx.Logger.Error(err, "createOrPatchIPAddressClaim failed", "name", ipAddrClaimName)
-				errList = append(errList, err)
-				continue
-			}
-			if created {
-				claimsCreated++
-			}
-			if ipAddrClaim.Status.AddressRef.Name != "" {
-				claimsFulfilled++
-			}
+	// The time waited for the claims is observed once, when all of them are
+	// fulfilled.
+	defer func(claimed bool) {
+		if !claimed && totalClaims > 0 && conditions.IsTrue(vmCtx.VSphereVM, infrav1.IPAddressClaimedCondition) {
+			metrics.IPAddressClaimWaitSeconds.Observe(time.Since(firstClaimCreated).Seconds())
+		}
+	}(conditions.IsTrue(vmCtx.VSphereVM, infrav1.IPAddressClaimedCondition))
 
-			// Since this is eventually used to calculate the status of the
-			// IPAddressClaimed condition for the VSphereVM object.
-			if conditions.Has(ipAddrClaim, clusterv1.ReadyCondition) {
-				claims = append(claims, ipAddrClaim)
-			}
+	for _, claimPoolRef := range ipAddressClaimPoolRefs(vmCtx.VSphereVM) {
+		totalClaims++
+		ipAddrClaimName, poolRef := claimPoolRef.name, claimPoolRef.poolRef
+		ipAddrClaim := &ipamv1.IPAddressClaim{}
+		ipAddrClaimKey := client.ObjectKey{
+			Namespace: vmCtx.VSphereVM.Namespace,
+			Name:      ipAddrClaimName,
+		}
+		err := vmCtx.Client.Get(ctx, ipAddrClaimKey, ipAddrClaim)
+		if err != nil && !apierrors.IsNotFound(err) {
+			vmCtx.Logger.Error(err, "fetching IPAddressClaim failed", "name", ipAddrClaimName)
+			return err
+		}
+		ipAddrClaim, created, err := createOrPatchIPAddressClaim(ctx, vmCtx, ipAddrClaimName, poolRef)
+		if err != nil {
+			vmCtx.Logger.Error(err, "createOrPatchIPAddressClaim failed", "name", ipAddrClaimName)
+			errList = append(errList, err)
+			continue
+		}
+		if created {
+			claimsCreated++
+		}
+		if createdAt := ipAddrClaim.CreationTimestamp.Time; firstClaimCreated.IsZero() || createdAt.Before(firstClaimCreated) {
+			firstClaimCreated = createdAt
+		}
+		if ipAddrClaim.Status.AddressRef.Name != "" {
+			claimsFulfilled++
+		}
+
+		// Since this is eventually used to calculate the status of the
+		// IPAddressClaimed condition for the VSphereVM object.
+		if conditions.Has(ipAddrClaim, clusterv1.ReadyCondition) {
+			claims = append(claims, ipAddrClaim)
 		}
 	}
 
@@ -203,28 +216,56 @@ func createOrPatchIPAddressClaim(ctx context.Context, vmCtx *capvcontext.VMConte
 // deleteIPAddressClaims removes the finalizers from the IPAddressClaim objects
 // thus freeing them up for garbage collection.
 func (r vmReconciler) deleteIPAddressClaims(ctx context.Context, vmCtx *capvcontext.VMContext) error {
-	for devIdx, device := range vmCtx.VSphereVM.Spec.Network.Devices {
-		for poolRefIdx := range device.AddressesFromPools {
-			// check if claim exists
-			ipAddrClaim := &ipamv1.IPAddressClaim{}
-			ipAddrClaimName := util.IPAddressClaimName(vmCtx.VSphereVM.Name, devIdx, poolRefIdx)
-			vmCtx.Logger.Info("removing finalizer", "IPAddressClaim", ipAddrClaimName)
-			ipAddrClaimKey := client.ObjectKey{
-				Namespace: vmCtx.VSphereVM.Namespace,
-				Name:      ipAddrClaimName,
-			}
-			if err := vmCtx.Client.Get(ctx, ipAddrClaimKey, ipAddrClaim); err != nil {
-				if apierrors.IsNotFound(err) {
-					continue
-				}
-				return errors.Wrapf(err, fmt.Sprintf("failed to find IPAddressClaim %q to remove the finalizer", ipAddrClaimName))
+	for _, claimPoolRef := range ipAddressClaimPoolRefs(vmCtx.VSphereVM) {
+		// check if claim exists
+		ipAddrClaim := &ipamv1.IPAddressClaim{}
+		ipAddrClaimName := claimPoolRef.name
+		vmCtx.Logger.Info("removing finalizer", "IPAddressClaim", ipAddrClaimName)
+		ipAddrClaimKey := client.ObjectKey{
+			Namespace: vmCtx.VSphereVM.Namespace,
+			Name:      ipAddrClaimName,
+		}
+		if err := vmCtx.Client.Get(ctx, ipAddrClaimKey, ipAddrClaim); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
 			}
-			if ctrlutil.RemoveFinalizer(ipAddrClaim, infrav1.IPAddressClaimFinalizer) {
-				if err := vmCtx.Client.Update(ctx, ipAddrClaim); err != nil {
-					return errors.Wrapf(err, fmt.Sprintf("failed to update IPAddressClaim %q", ipAddrClaimName))
-				}
+			return errors.Wrapf(err, fmt.Sprintf("failed to find IPAddressClaim %q to remove the finalizer", ipAddrClaimName))
+		}
+		if ctrlutil.RemoveFinalizer(ipAddrClaim, infrav1.IPAddressClaimFinalizer) {
+			if err := vmCtx.Client.Update(ctx, ipAddrClaim); err != nil {
+				return errors.Wrapf(err, fmt.Sprintf("failed to update IPAddressClaim %q", ipAddrClaimName))
 			}
 		}
 	}
 	return nil
 }
+
+// ipAddressClaimPoolRef is the name of an IPAddressClaim of a VSphereVM and
+// the reference to the pool it claims an address from.
+type ipAddressClaimPoolRef struct {
+	name    string
+	poolRef corev1.TypedLocalObjectReference
+}
+
+// ipAddressClaimPoolRefs returns the IPAddressClaims required by the network
+// devices, bonds and VLANs of the VSphereVM.
+func ipAddressClaimPoolRefs(vsphereVM *infrav1.VSphereVM) []ipAddressClaimPoolRef {
+	var refs []ipAddressClaimPoolRef
+	for devIdx, device := range vsphereVM.Spec.Network.Devices {
+		for poolRefIdx, poolRef := range device.AddressesFromPools {
+			refs = append(refs, ipAddressClaimPoolRef{
+				name:    util.IPAddressClaimName(vsphereVM.Name, devIdx, poolRefIdx),
+				poolRef: poolRef,
+			})
+		}
+	}
+	for _, iface := range util.NetworkInterfaces(&vsphereVM.Spec.Network) {
+		for poolRefIdx, poolRef := range iface.AddressesFromPools {
+			refs = append(refs, ipAddressClaimPoolRef{
+				name:    util.InterfaceIPAddressClaimName(vsphereVM.Name, iface.Name, poolRefIdx),
+				poolRef: poolRef,
+			})
+		}
+	}
+	return refs
+}
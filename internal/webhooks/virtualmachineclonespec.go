@@ -0,0 +1,283 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"fmt"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// resizableSpecKeys are the keys of the VirtualMachineCloneSpec which can be
+// changed on existing machines with a resize policy other than Disabled.
+var resizableSpecKeys = []string{"numCPUs", "numCoresPerSocket", "memoryMiB"}
+
+// isResizable returns true if the CPUs and memory of existing machines with
+// the given resize policy can be changed.
+func isResizable(policy infrav1.VirtualMachineResizePolicy) bool {
+	return policy != "" && policy != infrav1.VirtualMachineResizePolicyDisabled
+}
+
+// validateVirtualMachineCloneSpec validates the fields of a VirtualMachineCloneSpec
+// which are shared by VSphereVM, VSphereMachine and VSphereMachineTemplate.
+func validateVirtualMachineCloneSpec(spec *infrav1.VirtualMachineCloneSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if spec.ContentLibrary == nil && spec.Template == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("template"), "either template or contentLibrary must be set"))
+	}
+
+	if spec.ContentLibrary != nil {
+		if spec.Template != "" {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("contentLibrary"), "cannot be set together with template"))
+		}
+		if spec.Snapshot != "" {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("snapshot"), "cannot be set when deploying from a content library"))
+		}
+		if spec.CloneMode == infrav1.LinkedClone {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("cloneMode"), spec.CloneMode, "linked clones cannot be created from a content library"))
+		}
+		// The deployed virtual machine is only reconfigured, so its disks
+		// cannot be placed individually.
+		for i, disk := range spec.Disks {
+			diskPath := fldPath.Child("disks").Index(i)
+			if disk.Datastore != "" {
+				allErrs = append(allErrs, field.Forbidden(diskPath.Child("datastore"), "cannot be set when deploying from a content library"))
+			}
+			if disk.StoragePolicyName != "" {
+				allErrs = append(allErrs, field.Forbidden(diskPath.Child("storagePolicyName"), "cannot be set when deploying from a content library"))
+			}
+		}
+	}
+
+	if spec.DatastoreCluster != "" {
+		if spec.Datastore != "" {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("datastoreCluster"), "cannot be set together with datastore"))
+		}
+		if spec.ContentLibrary != nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("datastoreCluster"), "cannot be set when deploying from a content library"))
+		}
+	}
+
+	if len(spec.Disks) > 0 && len(spec.AdditionalDisksGiB) > 0 {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("additionalDisksGiB"), "cannot be set together with disks"))
+	}
+	if spec.CloneMode == infrav1.LinkedClone && len(spec.AdditionalDisksGiB) > 0 {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("additionalDisksGiB"), "cannot be set for linked clones, the disks provided by the template cannot be resized"))
+	}
+
+	for i, device := range spec.Network.Devices {
+		devicePath := fldPath.Child("network", "devices").Index(i)
+		switch {
+		case device.AdapterType == infrav1.NetworkAdapterTypeSRIOV && device.PhysicalFunction == "":
+			allErrs = append(allErrs, field.Required(devicePath.Child("physicalFunction"), "must be set for sriov network devices"))
+		case device.AdapterType != infrav1.NetworkAdapterTypeSRIOV && device.PhysicalFunction != "":
+			allErrs = append(allErrs, field.Forbidden(devicePath.Child("physicalFunction"), "can only be set for sriov network devices"))
+		}
+		if spec.CloneMode == infrav1.InstantClone && device.AdapterType != "" {
+			allErrs = append(allErrs, field.Forbidden(devicePath.Child("adapterType"), "cannot be set for instant clones, the network devices are inherited from the source VM"))
+		}
+	}
+
+	allErrs = append(allErrs, validateNetworkInterfaces(&spec.Network, fldPath.Child("network"))...)
+
+	allErrs = append(allErrs, validateResourceAllocation(spec, fldPath)...)
+
+	if security := spec.Security; security != nil {
+		securityPath := fldPath.Child("security")
+		if security.Firmware == infrav1.FirmwareBIOS {
+			if security.SecureBoot {
+				allErrs = append(allErrs, field.Forbidden(securityPath.Child("secureBoot"), "requires the efi firmware"))
+			}
+			if security.VTPM {
+				allErrs = append(allErrs, field.Forbidden(securityPath.Child("vtpm"), "requires the efi firmware"))
+			}
+		}
+		if security.KeyProvider != "" && !security.VTPM {
+			allErrs = append(allErrs, field.Forbidden(securityPath.Child("keyProvider"), "can only be set if vtpm is enabled"))
+		}
+	}
+
+	if customization := spec.Customization; customization != nil {
+		customizationPath := fldPath.Child("customization")
+		if spec.OS != infrav1.Windows {
+			allErrs = append(allErrs, field.Forbidden(customizationPath, "can only be set if os is Windows"))
+		}
+		switch {
+		case customization.SpecName == "" && customization.Sysprep == "":
+			allErrs = append(allErrs, field.Required(customizationPath, "one of specName or sysprep must be set"))
+		case customization.SpecName != "" && customization.Sysprep != "":
+			allErrs = append(allErrs, field.Forbidden(customizationPath.Child("sysprep"), "cannot be set together with specName"))
+		case customization.Sysprep != "":
+			if _, err := template.New("sysprep").Parse(customization.Sysprep); err != nil {
+				allErrs = append(allErrs, field.Invalid(customizationPath.Child("sysprep"), "", fmt.Sprintf("is not a valid template: %v", err)))
+			}
+		}
+	}
+
+	if spec.CloneMode == infrav1.InstantClone {
+		if spec.ContentLibrary != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("cloneMode"), spec.CloneMode, "instant clones cannot be created from a content library"))
+		}
+		if spec.HardwareVersion != "" {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("hardwareVersion"), "cannot be set for instant clones, the hardware version is inherited from the source VM"))
+		}
+		if len(spec.Disks) > 0 {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("disks"), "cannot be set for instant clones, the disks are inherited from the source VM"))
+		}
+		if len(spec.PciDevices) > 0 {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("pciDevices"), "cannot be set for instant clones"))
+		}
+		if spec.Security != nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("security"), "cannot be set for instant clones, the security features are inherited from the source VM"))
+		}
+		if spec.Customization != nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("customization"), "cannot be set for instant clones, they are powered on when created"))
+		}
+		if spec.BootstrapDataTransport == infrav1.BootstrapDataTransportNoCloud {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("bootstrapDataTransport"), "cannot be NoCloud for instant clones, they are powered on when created"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateNetworkInterfaces validates the bonds and VLANs of a NetworkSpec.
+// Bonds aggregate network devices, which are referenced by their name in the
+// guest operating system, and VLANs are created on network devices or bonds.
+func validateNetworkInterfaces(network *infrav1.NetworkSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	devices := map[string]bool{}
+	for i, device := range network.Devices {
+		if device.DeviceName != "" {
+			devices[device.DeviceName] = true
+		} else {
+			devices[fmt.Sprintf("eth%d", i)] = true
+		}
+	}
+
+	bonds, bondMembers := map[string]bool{}, map[string]bool{}
+	for i, bond := range network.Bonds {
+		bondPath := fldPath.Child("bonds").Index(i)
+		allErrs = append(allErrs, validateNetworkInterfaceName(bond.Name, bondPath.Child("name"))...)
+		if devices[bond.Name] || bonds[bond.Name] {
+			allErrs = append(allErrs, field.Duplicate(bondPath.Child("name"), bond.Name))
+		}
+		bonds[bond.Name] = true
+		for j, member := range bond.Interfaces {
+			memberPath := bondPath.Child("interfaces").Index(j)
+			switch {
+			case !devices[member]:
+				allErrs = append(allErrs, field.NotFound(memberPath, member))
+			case bondMembers[member]:
+				allErrs = append(allErrs, field.Invalid(memberPath, member, "is already a member of a bond"))
+			}
+			bondMembers[member] = true
+		}
+	}
+
+	vlans := map[string]bool{}
+	for i, vlan := range network.VLANs {
+		vlanPath := fldPath.Child("vlans").Index(i)
+		allErrs = append(allErrs, validateNetworkInterfaceName(vlan.Name, vlanPath.Child("name"))...)
+		if devices[vlan.Name] || bonds[vlan.Name] || vlans[vlan.Name] {
+			allErrs = append(allErrs, field.Duplicate(vlanPath.Child("name"), vlan.Name))
+		}
+		vlans[vlan.Name] = true
+		switch {
+		case !devices[vlan.Link] && !bonds[vlan.Link]:
+			allErrs = append(allErrs, field.NotFound(vlanPath.Child("link"), vlan.Link))
+		case bondMembers[vlan.Link]:
+			allErrs = append(allErrs, field.Invalid(vlanPath.Child("link"), vlan.Link, "cannot be a member of a bond"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateResourceAllocation validates the ResourceAllocation of a
+// VirtualMachineCloneSpec. It is also used on updates as the resource
+// allocation of existing virtual machines can be changed.
+func validateResourceAllocation(spec *infrav1.VirtualMachineCloneSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if spec.ResourceAllocation == nil {
+		return allErrs
+	}
+	allocationPath := fldPath.Child("resourceAllocation")
+
+	if cpu := spec.ResourceAllocation.CPU; cpu != nil {
+		cpuPath := allocationPath.Child("cpu")
+		if cpu.ReservationMHz != nil && cpu.LimitMHz != nil && *cpu.LimitMHz < *cpu.ReservationMHz {
+			allErrs = append(allErrs, field.Invalid(cpuPath.Child("limitMHz"), *cpu.LimitMHz, "cannot be lower than reservationMHz"))
+		}
+		allErrs = append(allErrs, validateShares(cpu.Shares, cpuPath.Child("shares"))...)
+	}
+
+	if memory := spec.ResourceAllocation.Memory; memory != nil {
+		memoryPath := allocationPath.Child("memory")
+		if memory.ReservationMiB != nil && memory.LimitMiB != nil && *memory.LimitMiB < *memory.ReservationMiB {
+			allErrs = append(allErrs, field.Invalid(memoryPath.Child("limitMiB"), *memory.LimitMiB, "cannot be lower than reservationMiB"))
+		}
+		if memory.ReservationMiB != nil && (len(spec.PciDevices) > 0 || hasSRIOVNetworkDevice(spec.Network.Devices)) {
+			allErrs = append(allErrs, field.Forbidden(memoryPath.Child("reservationMiB"), "cannot be set with PCI or SR-IOV devices, the memory of the virtual machine is fully reserved"))
+		}
+		allErrs = append(allErrs, validateShares(memory.Shares, memoryPath.Child("shares"))...)
+	}
+
+	return allErrs
+}
+
+func validateShares(shares *infrav1.Shares, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if shares == nil {
+		return allErrs
+	}
+	switch {
+	case shares.Level == infrav1.SharesLevelCustom && shares.Value == nil:
+		allErrs = append(allErrs, field.Required(fldPath.Child("value"), "must be set for the custom shares level"))
+	case shares.Level != infrav1.SharesLevelCustom && shares.Value != nil:
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("value"), "can only be set for the custom shares level"))
+	}
+
+	return allErrs
+}
+
+func hasSRIOVNetworkDevice(devices []infrav1.NetworkDeviceSpec) bool {
+	for _, device := range devices {
+		if device.AdapterType == infrav1.NetworkAdapterTypeSRIOV {
+			return true
+		}
+	}
+	return false
+}
+
+// validateNetworkInterfaceName validates the name of a bond or VLAN, which is
+// part of the names of the IPAddressClaims of the interface.
+func validateNetworkInterfaceName(name string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, msg := range validation.IsDNS1123Subdomain(name) {
+		allErrs = append(allErrs, field.Invalid(fldPath, name, msg))
+	}
+	return allErrs
+}
@@ -0,0 +1,274 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+func TestValidateVirtualMachineCloneSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    infrav1.VirtualMachineCloneSpec
+		wantErr bool
+	}{
+		{
+			name: "template",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204"},
+		},
+		{
+			name: "content library item",
+			spec: infrav1.VirtualMachineCloneSpec{ContentLibrary: &infrav1.ContentLibraryItem{Library: "golden-images", Item: "ubuntu-2204"}},
+		},
+		{
+			name:    "neither template nor content library item",
+			spec:    infrav1.VirtualMachineCloneSpec{},
+			wantErr: true,
+		},
+		{
+			name:    "content library item together with template",
+			spec:    infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", ContentLibrary: &infrav1.ContentLibraryItem{Library: "golden-images", Item: "ubuntu-2204"}},
+			wantErr: true,
+		},
+		{
+			name:    "linked clone of content library item",
+			spec:    infrav1.VirtualMachineCloneSpec{CloneMode: infrav1.LinkedClone, ContentLibrary: &infrav1.ContentLibraryItem{Library: "golden-images", Item: "ubuntu-2204"}},
+			wantErr: true,
+		},
+		{
+			name:    "content library item with a disk placed on a datastore",
+			spec:    infrav1.VirtualMachineCloneSpec{ContentLibrary: &infrav1.ContentLibraryItem{Library: "golden-images", Item: "ubuntu-2204"}, Disks: []infrav1.VirtualDiskSpec{{Name: "etcd", SizeGiB: 10, Datastore: "fast"}}},
+			wantErr: true,
+		},
+		{
+			name:    "content library item with a disk storage policy",
+			spec:    infrav1.VirtualMachineCloneSpec{ContentLibrary: &infrav1.ContentLibraryItem{Library: "golden-images", Item: "ubuntu-2204"}, Disks: []infrav1.VirtualDiskSpec{{Name: "etcd", SizeGiB: 10, StoragePolicyName: "gold"}}},
+			wantErr: true,
+		},
+		{
+			name: "content library item with a storage policy",
+			spec: infrav1.VirtualMachineCloneSpec{ContentLibrary: &infrav1.ContentLibraryItem{Library: "golden-images", Item: "ubuntu-2204"}, StoragePolicyName: "gold", Disks: []infrav1.VirtualDiskSpec{{Name: "etcd", SizeGiB: 10}}},
+		},
+		{
+			name:    "instant clone with disks",
+			spec:    infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", CloneMode: infrav1.InstantClone, Disks: []infrav1.VirtualDiskSpec{{Name: "etcd", SizeGiB: 10}}},
+			wantErr: true,
+		},
+		{
+			name: "disks",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", Disks: []infrav1.VirtualDiskSpec{{Name: "etcd", SizeGiB: 10}}},
+		},
+		{
+			name:    "disks together with additionalDisksGiB",
+			spec:    infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", AdditionalDisksGiB: []int32{10}, Disks: []infrav1.VirtualDiskSpec{{Name: "etcd", SizeGiB: 10}}},
+			wantErr: true,
+		}, {
+			name:    "linked clone with additional disk sizes",
+			spec:    infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", CloneMode: infrav1.LinkedClone, AdditionalDisksGiB: []int32{10}},
+			wantErr: true,
+		},
+		{
+			name: "linked clone with disk placement",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", CloneMode: infrav1.LinkedClone, Disks: []infrav1.VirtualDiskSpec{{Name: "etcd", Datastore: "fast"}}},
+		},
+
+		{
+			name: "sriov network device",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", Network: infrav1.NetworkSpec{Devices: []infrav1.NetworkDeviceSpec{{NetworkName: "VM Network", AdapterType: infrav1.NetworkAdapterTypeSRIOV, PhysicalFunction: "0000:3b:00.0"}}}},
+		},
+		{
+			name:    "sriov network device without physical function",
+			spec:    infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", Network: infrav1.NetworkSpec{Devices: []infrav1.NetworkDeviceSpec{{NetworkName: "VM Network", AdapterType: infrav1.NetworkAdapterTypeSRIOV}}}},
+			wantErr: true,
+		},
+		{
+			name:    "physical function of vmxnet3 network device",
+			spec:    infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", Network: infrav1.NetworkSpec{Devices: []infrav1.NetworkDeviceSpec{{NetworkName: "VM Network", PhysicalFunction: "0000:3b:00.0"}}}},
+			wantErr: true,
+		},
+		{
+			name: "datastore cluster",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", DatastoreCluster: "sdrs-pod"},
+		},
+		{
+			name:    "datastore cluster together with datastore",
+			spec:    infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", DatastoreCluster: "sdrs-pod", Datastore: "ds-1"},
+			wantErr: true,
+		},
+		{
+			name: "resource allocation",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", ResourceAllocation: &infrav1.ResourceAllocation{
+				CPU:    &infrav1.CPUAllocation{ReservationMHz: pointer.Int64(2000), LimitMHz: pointer.Int64(4000), Shares: &infrav1.Shares{Level: infrav1.SharesLevelHigh}},
+				Memory: &infrav1.MemoryAllocation{ReservationMiB: pointer.Int64(4096), Shares: &infrav1.Shares{Level: infrav1.SharesLevelCustom, Value: pointer.Int32(4000)}},
+			}},
+		},
+		{
+			name: "cpu limit lower than reservation",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", ResourceAllocation: &infrav1.ResourceAllocation{
+				CPU: &infrav1.CPUAllocation{ReservationMHz: pointer.Int64(2000), LimitMHz: pointer.Int64(1000)},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "custom shares level without value",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", ResourceAllocation: &infrav1.ResourceAllocation{
+				Memory: &infrav1.MemoryAllocation{Shares: &infrav1.Shares{Level: infrav1.SharesLevelCustom}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "shares value with predefined level",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", ResourceAllocation: &infrav1.ResourceAllocation{
+				CPU: &infrav1.CPUAllocation{Shares: &infrav1.Shares{Level: infrav1.SharesLevelLow, Value: pointer.Int32(500)}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "memory reservation with pci devices",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", PciDevices: []infrav1.PCIDeviceSpec{{DeviceID: pointer.Int32(0x1eb8), VendorID: pointer.Int32(0x10de)}}, ResourceAllocation: &infrav1.ResourceAllocation{
+				Memory: &infrav1.MemoryAllocation{ReservationMiB: pointer.Int64(4096)},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "secure boot and vtpm",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", Security: &infrav1.VirtualMachineSecuritySpec{Firmware: infrav1.FirmwareEFI, SecureBoot: true, VTPM: true, KeyProvider: "nkp"}},
+		},
+		{
+			name:    "secure boot with bios firmware",
+			spec:    infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", Security: &infrav1.VirtualMachineSecuritySpec{Firmware: infrav1.FirmwareBIOS, SecureBoot: true}},
+			wantErr: true,
+		},
+		{
+			name:    "key provider without vtpm",
+			spec:    infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", Security: &infrav1.VirtualMachineSecuritySpec{Firmware: infrav1.FirmwareEFI, KeyProvider: "nkp"}},
+			wantErr: true,
+		},
+		{
+			name:    "instant clone with security",
+			spec:    infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", CloneMode: infrav1.InstantClone, Security: &infrav1.VirtualMachineSecuritySpec{VTPM: true}},
+			wantErr: true,
+		},
+		{
+			name:    "windows machine with named customization spec",
+			spec:    infrav1.VirtualMachineCloneSpec{Template: "windows-2022", OS: infrav1.Windows, Customization: &infrav1.GuestCustomization{SpecName: "windows-sysprep"}},
+			wantErr: false,
+		},
+		{
+			name:    "linux machine with customization",
+			spec:    infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", Customization: &infrav1.GuestCustomization{SpecName: "windows-sysprep"}},
+			wantErr: true,
+		},
+		{
+			name: "customization with both spec name and sysprep",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "windows-2022", OS: infrav1.Windows,
+				Customization: &infrav1.GuestCustomization{SpecName: "windows-sysprep", Sysprep: "<unattend/>"}},
+			wantErr: true,
+		},
+		{
+			name:    "customization with invalid sysprep template",
+			spec:    infrav1.VirtualMachineCloneSpec{Template: "windows-2022", OS: infrav1.Windows, Customization: &infrav1.GuestCustomization{Sysprep: "{{ .Hostname "}},
+			wantErr: true,
+		},
+		{
+			name:    "instant clone with NoCloud bootstrap data transport",
+			spec:    infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", CloneMode: infrav1.InstantClone, BootstrapDataTransport: infrav1.BootstrapDataTransportNoCloud},
+			wantErr: true,
+		},
+		{
+			name: "vlan on bond of network devices",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", Network: infrav1.NetworkSpec{
+				Devices: []infrav1.NetworkDeviceSpec{{NetworkName: "trunk-a"}, {NetworkName: "trunk-b", DeviceName: "ens224"}},
+				Bonds:   []infrav1.NetworkBondSpec{{Name: "bond0", Interfaces: []string{"eth0", "ens224"}, Mode: infrav1.NetworkBondMode8023AD}},
+				VLANs:   []infrav1.NetworkVLANSpec{{Name: "vlan100", ID: 100, Link: "bond0", NetworkInterfaceSpec: infrav1.NetworkInterfaceSpec{DHCP4: true}}},
+			}},
+		},
+		{
+			name: "bond of unknown network device",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", Network: infrav1.NetworkSpec{
+				Devices: []infrav1.NetworkDeviceSpec{{NetworkName: "trunk-a"}},
+				Bonds:   []infrav1.NetworkBondSpec{{Name: "bond0", Interfaces: []string{"eth0", "eth1"}}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "network device in two bonds",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", Network: infrav1.NetworkSpec{
+				Devices: []infrav1.NetworkDeviceSpec{{NetworkName: "trunk-a"}, {NetworkName: "trunk-b"}},
+				Bonds:   []infrav1.NetworkBondSpec{{Name: "bond0", Interfaces: []string{"eth0", "eth1"}}, {Name: "bond1", Interfaces: []string{"eth1"}}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "vlan with the name of a network device",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", Network: infrav1.NetworkSpec{
+				Devices: []infrav1.NetworkDeviceSpec{{NetworkName: "trunk-a"}, {NetworkName: "trunk-b"}},
+				VLANs:   []infrav1.NetworkVLANSpec{{Name: "eth1", ID: 100, Link: "eth0"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "vlan on member of a bond",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", Network: infrav1.NetworkSpec{
+				Devices: []infrav1.NetworkDeviceSpec{{NetworkName: "trunk-a"}, {NetworkName: "trunk-b"}},
+				Bonds:   []infrav1.NetworkBondSpec{{Name: "bond0", Interfaces: []string{"eth0", "eth1"}}},
+				VLANs:   []infrav1.NetworkVLANSpec{{Name: "vlan100", ID: 100, Link: "eth0"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "vlan named after its link and id",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", Network: infrav1.NetworkSpec{
+				Devices: []infrav1.NetworkDeviceSpec{{NetworkName: "trunk-a"}},
+				VLANs:   []infrav1.NetworkVLANSpec{{Name: "eth0.100", ID: 100, Link: "eth0"}},
+			}},
+		},
+		{
+			name: "vlan with upper case name",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", Network: infrav1.NetworkSpec{
+				Devices: []infrav1.NetworkDeviceSpec{{NetworkName: "trunk-a"}},
+				VLANs:   []infrav1.NetworkVLANSpec{{Name: "VLAN100", ID: 100, Link: "eth0"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "bond with underscore in name",
+			spec: infrav1.VirtualMachineCloneSpec{Template: "ubuntu-2204", Network: infrav1.NetworkSpec{
+				Devices: []infrav1.NetworkDeviceSpec{{NetworkName: "trunk-a"}},
+				Bonds:   []infrav1.NetworkBondSpec{{Name: "bond_0", Interfaces: []string{"eth0"}}},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			errs := validateVirtualMachineCloneSpec(&tc.spec, field.NewPath("spec"))
+			if tc.wantErr {
+				g.Expect(errs).ToNot(BeEmpty())
+			} else {
+				g.Expect(errs).To(BeEmpty())
+			}
+		})
+	}
+}
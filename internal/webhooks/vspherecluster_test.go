@@ -0,0 +1,108 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+func TestVSphereCluster_ValidateUpdate(t *testing.T) {
+	g := NewWithT(t)
+
+	tests := []struct {
+		name              string
+		oldVSphereCluster *infrav1.VSphereCluster
+		vSphereCluster    *infrav1.VSphereCluster
+		wantErr           bool
+	}{
+		{
+			name:              "server can be updated without a load balancer",
+			oldVSphereCluster: createVSphereCluster("foo.com", nil),
+			vSphereCluster:    createVSphereCluster("bar.com", nil),
+			wantErr:           false,
+		},
+		{
+			name:              "load balancer can be added",
+			oldVSphereCluster: createVSphereCluster("foo.com", nil),
+			vSphereCluster:    createVSphereCluster("foo.com", createVSphereLoadBalancer("haproxy", nil)),
+			wantErr:           false,
+		},
+		{
+			name:              "load balancer can be removed",
+			oldVSphereCluster: createVSphereCluster("foo.com", createVSphereLoadBalancer("haproxy", nil)),
+			vSphereCluster:    createVSphereCluster("foo.com", nil),
+			wantErr:           false,
+		},
+		{
+			name:              "load balancer replicas can be updated",
+			oldVSphereCluster: createVSphereCluster("foo.com", createVSphereLoadBalancer("haproxy", nil)),
+			vSphereCluster:    createVSphereCluster("foo.com", createVSphereLoadBalancer("haproxy", pointer.Int32(1))),
+			wantErr:           false,
+		},
+		{
+			name:              "load balancer template cannot be updated",
+			oldVSphereCluster: createVSphereCluster("foo.com", createVSphereLoadBalancer("haproxy", nil)),
+			vSphereCluster:    createVSphereCluster("foo.com", createVSphereLoadBalancer("haproxy-v2", nil)),
+			wantErr:           true,
+		},
+		{
+			name:              "load balancer interface cannot be updated",
+			oldVSphereCluster: createVSphereCluster("foo.com", createVSphereLoadBalancer("haproxy", nil)),
+			vSphereCluster: func() *infrav1.VSphereCluster {
+				vsphereCluster := createVSphereCluster("foo.com", createVSphereLoadBalancer("haproxy", nil))
+				vsphereCluster.Spec.LoadBalancer.Interface = "ens192"
+				return vsphereCluster
+			}(),
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			webhook := &VSphereClusterWebhook{}
+			_, err := webhook.ValidateUpdate(context.Background(), tc.oldVSphereCluster, tc.vSphereCluster)
+			if tc.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func createVSphereCluster(server string, loadBalancer *infrav1.VSphereLoadBalancerSpec) *infrav1.VSphereCluster {
+	return &infrav1.VSphereCluster{
+		Spec: infrav1.VSphereClusterSpec{
+			Server:       server,
+			LoadBalancer: loadBalancer,
+		},
+	}
+}
+
+func createVSphereLoadBalancer(template string, replicas *int32) *infrav1.VSphereLoadBalancerSpec {
+	return &infrav1.VSphereLoadBalancerSpec{
+		VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+			Template: template,
+		},
+		Replicas: replicas,
+	}
+}
@@ -84,6 +84,8 @@ func (webhook *VSphereMachineWebhook) ValidateCreate(_ context.Context, raw runt
 		}
 	}
 
+	allErrs = append(allErrs, validateVirtualMachineCloneSpec(&spec.VirtualMachineCloneSpec, field.NewPath("spec"))...)
+
 	if spec.GuestSoftPowerOffTimeout != nil {
 		if spec.PowerOffMode != infrav1.VirtualMachinePowerOpModeTrySoft {
 			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "guestSoftPowerOffTimeout"), spec.GuestSoftPowerOffTimeout, "should not be set in templates unless the powerOffMode is trySoft"))
@@ -126,7 +128,10 @@ func (webhook *VSphereMachineWebhook) ValidateUpdate(_ context.Context, oldRaw r
 	newVSphereMachineSpec := newVSphereMachine["spec"].(map[string]interface{})
 	oldVSphereMachineSpec := oldVSphereMachine["spec"].(map[string]interface{})
 
-	allowChangeKeys := []string{"providerID", "powerOffMode", "guestSoftPowerOffTimeout"}
+	allowChangeKeys := []string{"providerID", "powerOffMode", "guestSoftPowerOffTimeout", "resourceAllocation", "resizePolicy", "driftPolicy", "metadataTemplate"}
+	if isResizable(newTyped.Spec.ResizePolicy) {
+		allowChangeKeys = append(allowChangeKeys, resizableSpecKeys...)
+	}
 	for _, key := range allowChangeKeys {
 		delete(oldVSphereMachineSpec, key)
 		delete(newVSphereMachineSpec, key)
@@ -149,6 +154,8 @@ func (webhook *VSphereMachineWebhook) ValidateUpdate(_ context.Context, oldRaw r
 		}
 	}
 
+	allErrs = append(allErrs, validateResourceAllocation(&newTyped.Spec.VirtualMachineCloneSpec, field.NewPath("spec"))...)
+
 	if !reflect.DeepEqual(oldVSphereMachineSpec, newVSphereMachineSpec) {
 		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec"), "cannot be modified"))
 	}
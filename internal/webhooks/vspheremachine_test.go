@@ -91,6 +91,26 @@ func TestVSphereMachine_ValidateCreate(t *testing.T) {
 			vsphereMachine: createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32", "192.168.0.3/32"}, infrav1.VirtualMachinePowerOpModeSoft, nil),
 			wantErr:        false,
 		},
+		{
+			name:           "contentLibrary set together with template",
+			vsphereMachine: withContentLibrary(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}, infrav1.VirtualMachinePowerOpModeTrySoft, nil), "ubuntu-2204"),
+			wantErr:        true,
+		},
+		{
+			name:           "successful VSphereMachine creation from content library",
+			vsphereMachine: withContentLibrary(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}, infrav1.VirtualMachinePowerOpModeTrySoft, nil), ""),
+			wantErr:        false,
+		},
+		{
+			name:           "instantClone with hardwareVersion",
+			vsphereMachine: withInstantClone(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}, infrav1.VirtualMachinePowerOpModeTrySoft, nil), "vmx-17"),
+			wantErr:        true,
+		},
+		{
+			name:           "successful VSphereMachine creation with instantClone",
+			vsphereMachine: withInstantClone(createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32"}, infrav1.VirtualMachinePowerOpModeTrySoft, nil), ""),
+			wantErr:        false,
+		},
 		{
 			name:           "successful VSphereMachine creation with powerOffMode set to trySoft and non-default timeout",
 			vsphereMachine: createVSphereMachine("foo.com", nil, "", []string{"192.168.0.1/32", "192.168.0.3/32"}, infrav1.VirtualMachinePowerOpModeTrySoft, &metav1.Duration{Duration: 1234}),
@@ -167,6 +187,12 @@ func TestVSphereMachine_ValidateUpdate(t *testing.T) {
 			vsphereMachine:    createVSphereMachine("foo.com", &someProviderID, "", []string{"192.168.0.1/32"}, infrav1.VirtualMachinePowerOpModeSoft, nil),
 			wantErr:           false,
 		},
+		{
+			name:              "metadataTemplate can be updated",
+			oldVSphereMachine: withMetadataTemplate(createVSphereMachine("foo.com", &someProviderID, "", []string{"192.168.0.1/32"}, infrav1.VirtualMachinePowerOpModeSoft, nil), "bonds"),
+			vsphereMachine:    withMetadataTemplate(createVSphereMachine("foo.com", &someProviderID, "", []string{"192.168.0.1/32"}, infrav1.VirtualMachinePowerOpModeSoft, nil), "vlans"),
+			wantErr:           false,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -185,7 +211,8 @@ func createVSphereMachine(server string, providerID *string, preferredAPIServerC
 	VSphereMachine := &infrav1.VSphereMachine{
 		Spec: infrav1.VSphereMachineSpec{
 			VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
-				Server: server,
+				Server:   server,
+				Template: "ubuntu-2204",
 				Network: infrav1.NetworkSpec{
 					PreferredAPIServerCIDR: preferredAPIServerCIDR,
 					Devices:                []infrav1.NetworkDeviceSpec{},
@@ -203,3 +230,24 @@ func createVSphereMachine(server string, providerID *string, preferredAPIServerC
 	}
 	return VSphereMachine
 }
+
+func withContentLibrary(vsphereMachine *infrav1.VSphereMachine, template string) *infrav1.VSphereMachine {
+	vsphereMachine.Spec.Template = template
+	vsphereMachine.Spec.ContentLibrary = &infrav1.ContentLibraryItem{
+		Library: "golden-images",
+		Item:    "ubuntu-2204-kube-v1.28.0",
+	}
+	return vsphereMachine
+}
+
+func withMetadataTemplate(vsphereMachine *infrav1.VSphereMachine, name string) *infrav1.VSphereMachine {
+	vsphereMachine.Spec.MetadataTemplate = &infrav1.MetadataTemplateReference{Name: name}
+	return vsphereMachine
+}
+
+func withInstantClone(vsphereMachine *infrav1.VSphereMachine, hardwareVersion string) *infrav1.VSphereMachine {
+	vsphereMachine.Spec.Template = "frozen-source-vm"
+	vsphereMachine.Spec.CloneMode = infrav1.InstantClone
+	vsphereMachine.Spec.HardwareVersion = hardwareVersion
+	return vsphereMachine
+}
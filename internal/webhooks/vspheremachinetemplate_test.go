@@ -152,7 +152,8 @@ func createVSphereMachineTemplate(server, hwVersion string, providerID *string,
 				Spec: infrav1.VSphereMachineSpec{
 					ProviderID: providerID,
 					VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
-						Server: server,
+						Server:   server,
+						Template: "ubuntu-2204",
 						Network: infrav1.NetworkSpec{
 							PreferredAPIServerCIDR: preferredAPIServerCIDR,
 							Devices:                []infrav1.NetworkDeviceSpec{},
@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"net"
 	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -84,6 +86,10 @@ func (webhook *VSphereVMWebhook) ValidateCreate(_ context.Context, raw runtime.O
 		}
 	}
 
+	allErrs = append(allErrs, validateVirtualMachineCloneSpec(&spec.VirtualMachineCloneSpec, field.NewPath("spec"))...)
+
+	allErrs = append(allErrs, validateGuestInfo(spec.GuestInfo, field.NewPath("spec", "guestInfo"))...)
+
 	if objValue.Spec.OS == infrav1.Windows && len(objValue.Name) > 15 {
 		allErrs = append(allErrs, field.Invalid(field.NewPath("name"), objValue.Name, "name has to be less than 16 characters for Windows VM"))
 	}
@@ -131,8 +137,12 @@ func (webhook *VSphereVMWebhook) ValidateUpdate(_ context.Context, oldRaw runtim
 	newVSphereVMSpec := newVSphereVM["spec"].(map[string]interface{})
 	oldVSphereVMSpec := oldVSphereVM["spec"].(map[string]interface{})
 
-	// Allow changes to bootstrapRef, thumbprint, powerOffMode, guestSoftPowerOffTimeout.
-	keys := []string{"bootstrapRef", "thumbprint", "powerOffMode", "guestSoftPowerOffTimeout"}
+	// Allow changes to bootstrapRef, thumbprint, powerOffMode, guestSoftPowerOffTimeout, datastoreSelectionPolicy, metadataTemplate, resourceAllocation, resizePolicy, driftPolicy.
+	keys := []string{"bootstrapRef", "thumbprint", "powerOffMode", "guestSoftPowerOffTimeout", "datastoreSelectionPolicy", "metadataTemplate", "resourceAllocation", "resizePolicy", "driftPolicy", "guestInfo"}
+	// Allow changes to the CPUs and memory only if they can be applied to the VM.
+	if isResizable(newTyped.Spec.ResizePolicy) {
+		keys = append(keys, resizableSpecKeys...)
+	}
 	// Allow changes to os only if the old spec has empty OS field.
 	if oldTyped.Spec.OS == "" {
 		keys = append(keys, "os")
@@ -152,6 +162,9 @@ func (webhook *VSphereVMWebhook) ValidateUpdate(_ context.Context, oldRaw runtim
 	webhook.deleteSpecKeys(oldVSphereVMNetwork, networkKeys)
 	webhook.deleteSpecKeys(newVSphereVMNetwork, networkKeys)
 
+	allErrs = append(allErrs, validateResourceAllocation(&newTyped.Spec.VirtualMachineCloneSpec, field.NewPath("spec"))...)
+	allErrs = append(allErrs, validateGuestInfo(newTyped.Spec.GuestInfo, field.NewPath("spec", "guestInfo"))...)
+
 	if !reflect.DeepEqual(oldVSphereVMSpec, newVSphereVMSpec) {
 		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec"), "cannot be modified"))
 	}
@@ -173,3 +186,36 @@ func (webhook *VSphereVMWebhook) deleteSpecKeys(spec map[string]interface{}, key
 		delete(spec, key)
 	}
 }
+
+// reservedGuestInfoKeys are the guestinfo variables, and the prefixes of the
+// variables nested under them, which are set by CAPV or by the bootstrap of
+// the guest and cannot be overridden with the GuestInfo of a VSphereVM.
+var reservedGuestInfoKeys = []string{
+	"userdata",
+	"metadata",
+	"vendordata",
+	"ignition",
+	"cluster-api",
+}
+
+func validateGuestInfo(guestInfo map[string]string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	keys := make([]string, 0, len(guestInfo))
+	for key := range guestInfo {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.HasPrefix(key, "guestinfo.") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(key), key, "must not contain the guestinfo. prefix"))
+			continue
+		}
+		for _, reserved := range reservedGuestInfoKeys {
+			if key == reserved || strings.HasPrefix(key, reserved+".") {
+				allErrs = append(allErrs, field.Forbidden(fldPath.Key(key), fmt.Sprintf("%s variables are reserved", reserved)))
+				break
+			}
+		}
+	}
+	return allErrs
+}
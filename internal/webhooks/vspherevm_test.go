@@ -114,6 +114,31 @@ func TestVSphereVM_ValidateCreate(t *testing.T) {
 			vSphereVM: createVSphereVM(linuxVMName, "foo.com", "", "", "", []string{"192.168.0.1/32", "192.168.0.3/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, &metav1.Duration{Duration: -1234}),
 			wantErr:   true,
 		},
+		{
+			name:      "successful VSphereVM creation with guestInfo",
+			vSphereVM: withGuestInfo(createVSphereVM("vsphere-vm-1", "foo.com", "", "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil), map[string]string{"haproxy.cfg": "Zm9v"}),
+			wantErr:   false,
+		},
+		{
+			name:      "guestInfo must not set the userdata",
+			vSphereVM: withGuestInfo(createVSphereVM("vsphere-vm-1", "foo.com", "", "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil), map[string]string{"userdata": "Zm9v"}),
+			wantErr:   true,
+		},
+		{
+			name:      "guestInfo must not set the encoding of the metadata",
+			vSphereVM: withGuestInfo(createVSphereVM("vsphere-vm-1", "foo.com", "", "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil), map[string]string{"metadata.encoding": "base64"}),
+			wantErr:   true,
+		},
+		{
+			name:      "guestInfo must not set the bootstrap status",
+			vSphereVM: withGuestInfo(createVSphereVM("vsphere-vm-1", "foo.com", "", "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil), map[string]string{"cluster-api.bootstrap-status": "success"}),
+			wantErr:   true,
+		},
+		{
+			name:      "guestInfo keys must not contain the guestinfo prefix",
+			vSphereVM: withGuestInfo(createVSphereVM("vsphere-vm-1", "foo.com", "", "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil), map[string]string{"guestinfo.haproxy.cfg": "Zm9v"}),
+			wantErr:   true,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -209,6 +234,42 @@ func TestVSphereVM_ValidateUpdate(t *testing.T) {
 			vSphereVM:    createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", "AA:BB:CC:DD:EE", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil),
 			wantErr:      true,
 		},
+		{
+			name:         "numCPUs and memoryMiB can be updated with a resize policy",
+			oldVSphereVM: withResize(createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil), infrav1.VirtualMachineResizePolicyHotAdd, 4, 8192),
+			vSphereVM:    withResize(createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil), infrav1.VirtualMachineResizePolicyHotAdd, 8, 16384),
+			wantErr:      false,
+		},
+		{
+			name:         "numCPUs and resizePolicy can be updated together",
+			oldVSphereVM: withResize(createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil), "", 4, 8192),
+			vSphereVM:    withResize(createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil), infrav1.VirtualMachineResizePolicyHotAddOrPowerCycle, 8, 8192),
+			wantErr:      false,
+		},
+		{
+			name:         "numCPUs cannot be updated when resizing is disabled",
+			oldVSphereVM: withResize(createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil), infrav1.VirtualMachineResizePolicyDisabled, 4, 8192),
+			vSphereVM:    withResize(createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil), infrav1.VirtualMachineResizePolicyDisabled, 8, 8192),
+			wantErr:      true,
+		},
+		{
+			name:         "driftPolicy can be updated",
+			oldVSphereVM: withDriftPolicy(createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil), infrav1.VirtualMachineDriftPolicyReport),
+			vSphereVM:    withDriftPolicy(createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil), infrav1.VirtualMachineDriftPolicyRemediate),
+			wantErr:      false,
+		},
+		{
+			name:         "guestInfo can be updated",
+			oldVSphereVM: withGuestInfo(createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil), map[string]string{"haproxy.cfg": "Zm9v"}),
+			vSphereVM:    withGuestInfo(createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil), map[string]string{"haproxy.cfg": "YmFy"}),
+			wantErr:      false,
+		},
+		{
+			name:         "guestInfo cannot be updated with reserved keys",
+			oldVSphereVM: createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil),
+			vSphereVM:    withGuestInfo(createVSphereVM("vsphere-vm-1", "foo.com", biosUUID, "", "", []string{"192.168.0.1/32"}, nil, infrav1.Linux, infrav1.VirtualMachinePowerOpModeTrySoft, nil), map[string]string{"userdata.encoding": "base64"}),
+			wantErr:      true,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -230,7 +291,8 @@ func createVSphereVM(name, server, biosUUID, preferredAPIServerCIDR, thumbprint
 		},
 		Spec: infrav1.VSphereVMSpec{
 			VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
-				Server: server,
+				Server:   server,
+				Template: "ubuntu-2204",
 				Network: infrav1.NetworkSpec{
 					PreferredAPIServerCIDR: preferredAPIServerCIDR,
 					Devices:                []infrav1.NetworkDeviceSpec{},
@@ -254,3 +316,20 @@ func createVSphereVM(name, server, biosUUID, preferredAPIServerCIDR, thumbprint
 	}
 	return VSphereVM
 }
+
+func withResize(vm *infrav1.VSphereVM, policy infrav1.VirtualMachineResizePolicy, numCPUs int32, memoryMiB int64) *infrav1.VSphereVM {
+	vm.Spec.ResizePolicy = policy
+	vm.Spec.NumCPUs = numCPUs
+	vm.Spec.MemoryMiB = memoryMiB
+	return vm
+}
+
+func withDriftPolicy(vm *infrav1.VSphereVM, policy infrav1.VirtualMachineDriftPolicy) *infrav1.VSphereVM {
+	vm.Spec.DriftPolicy = policy
+	return vm
+}
+
+func withGuestInfo(vm *infrav1.VSphereVM, guestInfo map[string]string) *infrav1.VSphereVM {
+	vm.Spec.GuestInfo = guestInfo
+	return vm
+}
@@ -56,6 +56,7 @@ import (
 	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/manager"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/version"
 )
 
@@ -87,6 +88,8 @@ var (
 
 	tlsOptions = flags.TLSOptions{}
 
+	tracingOpts tracing.Options
+
 	defaultProfilerAddr      = os.Getenv("PROFILER_ADDR")
 	defaultSyncPeriod        = manager.DefaultSyncPeriod
 	defaultLeaderElectionID  = manager.DefaultLeaderElectionID
@@ -167,6 +170,15 @@ func InitFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&managerOpts.MetricsBindAddress, "metrics-bind-addr", "localhost:8080",
 		"The address the metric endpoint binds to.")
 
+	fs.StringVar(&tracingOpts.Endpoint, "tracing-endpoint", "",
+		"The host:port of the OTLP gRPC collector the traces of reconciles and vCenter requests are exported to (e.g. localhost:4317). If unspecified, tracing is disabled.")
+
+	fs.BoolVar(&tracingOpts.Insecure, "tracing-insecure", false,
+		"Disable TLS for the connection to the OTLP collector.")
+
+	fs.Float64Var(&tracingOpts.SamplingRatio, "tracing-sampling-ratio", 1,
+		"The ratio of the reconciles which are traced, between 0 and 1.")
+
 	fs.BoolVar(&managerOpts.LeaderElection, "leader-elect", true,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
 
@@ -304,6 +316,21 @@ func main() {
 	// Set up the context that's going to be used in controllers and for the manager.
 	ctx := ctrl.SetupSignalHandler()
 
+	shutdownTracing, err := tracing.Setup(ctx, tracingOpts)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	defer func() {
+		// ctx is done once the manager stopped, so the pending spans are
+		// flushed with a new context.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			setupLog.Error(err, "failed to flush traces")
+		}
+	}()
+
 	mgr, err := manager.New(ctx, managerOpts)
 	if err != nil {
 		setupLog.Error(err, "Error creating manager")
@@ -331,6 +358,10 @@ func main() {
 }
 
 func setupVAPIControllers(ctx context.Context, controllerCtx *capvcontext.ControllerManagerContext, mgr ctrlmgr.Manager, tracker *remote.ClusterCacheTracker) error {
+	if err := (&webhooks.VSphereClusterWebhook{}).SetupWebhookWithManager(mgr); err != nil {
+		return err
+	}
+
 	if err := (&webhooks.VSphereClusterTemplateWebhook{}).SetupWebhookWithManager(mgr); err != nil {
 		return err
 	}
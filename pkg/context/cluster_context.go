@@ -45,6 +45,9 @@ func (c *ClusterContext) Patch(ctx context.Context) error {
 	conditions.SetSummary(c.VSphereCluster,
 		conditions.WithConditions(
 			infrav1.VCenterAvailableCondition,
+			infrav1.IPAddressClaimedCondition,
+			infrav1.LoadBalancerAvailableCondition,
+			infrav1.PrivilegesVerifiedCondition,
 		),
 	)
 
@@ -22,6 +22,7 @@ import (
 	"fmt"
 
 	"github.com/go-logr/logr"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/patch"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
@@ -37,6 +38,7 @@ type VMContext struct {
 	Logger               logr.Logger
 	Session              *session.Session
 	VSphereFailureDomain *infrav1.VSphereFailureDomain
+	Machine              *clusterv1.Machine
 }
 
 // String returns VSphereVMGroupVersionKind VSphereVMNamespace/VSphereVMName.
@@ -44,6 +44,7 @@ func (c *VSphereDeploymentZoneContext) Patch(ctx context.Context) error {
 			infrav1.VCenterAvailableCondition,
 			infrav1.VSphereFailureDomainValidatedCondition,
 			infrav1.PlacementConstraintMetCondition,
+			infrav1.PrivilegesVerifiedCondition,
 		),
 	)
 	return c.PatchHelper.Patch(ctx, c.VSphereDeploymentZone)
@@ -0,0 +1,169 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics contains the Prometheus metrics of vSphere operations,
+// which are exposed with the controller-runtime metrics of the manager.
+package metrics
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vmware/govmomi/vim25/soap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "capv"
+
+var (
+	// VCenterRequestsTotal counts the SOAP requests sent to vCenter by method.
+	VCenterRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "vcenter",
+		Name:      "requests_total",
+		Help:      "Total number of SOAP requests sent to vCenter.",
+	}, []string{"vcenter", "method"})
+
+	// VCenterRequestErrorsTotal counts the SOAP requests to vCenter which
+	// failed by method.
+	VCenterRequestErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "vcenter",
+		Name:      "request_errors_total",
+		Help:      "Total number of SOAP requests to vCenter which failed.",
+	}, []string{"vcenter", "method"})
+
+	// PropertyBatchSize observes the number of virtual machines whose
+	// properties are retrieved with a single RetrieveProperties request.
+	PropertyBatchSize = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "vcenter",
+		Name:      "property_batch_size",
+		Help:      "Number of virtual machines whose properties are retrieved in a single request.",
+		Buckets:   []float64{1, 2, 5, 10, 20, 50, 100, 200, 500},
+	}, []string{"vcenter"})
+
+	// TaskFailuresTotal counts the failed tasks of VSphereVMs by the type and
+	// the class of their fault, which is terminal or transient.
+	TaskFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "vcenter",
+		Name:      "task_failures_total",
+		Help:      "Total number of failed vCenter tasks by fault.",
+	}, []string{"vcenter", "fault", "class"})
+
+	// OperationDurationSeconds observes the durations of the tasks of
+	// VSphereVMs, from being queued to being completed, by operation.
+	OperationDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "vcenter",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of successful vCenter tasks of VSphereVMs by operation.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{"vcenter", "datacenter", "operation"})
+
+	// SessionsActive is the number of cached vCenter sessions.
+	SessionsActive = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "session",
+		Name:      "active",
+		Help:      "Number of cached vCenter sessions.",
+	}, []string{"vcenter"})
+
+	// SessionLoginsTotal counts the vCenter sessions created.
+	SessionLoginsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "session",
+		Name:      "logins_total",
+		Help:      "Total number of vCenter sessions created.",
+	}, []string{"vcenter"})
+
+	// SessionReloginsTotal counts the vCenter sessions created to replace a
+	// session which expired or became invalid.
+	SessionReloginsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "session",
+		Name:      "relogins_total",
+		Help:      "Total number of vCenter sessions created to replace an invalid session.",
+	}, []string{"vcenter"})
+
+	// KeepAliveFailuresTotal counts the failed keepalives of the SOAP and REST
+	// clients of vCenter sessions.
+	KeepAliveFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "session",
+		Name:      "keepalive_failures_total",
+		Help:      "Total number of failed vCenter session keepalives.",
+	}, []string{"vcenter", "client"})
+
+	// IPAddressClaimWaitSeconds observes the time VSphereVMs wait for their
+	// IPAddressClaims to be fulfilled by the IPAM provider.
+	IPAddressClaimWaitSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "ipam",
+		Name:      "claim_wait_seconds",
+		Help:      "Time from the creation of the IPAddressClaims of a VSphereVM until all of them are fulfilled.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 14),
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		VCenterRequestsTotal,
+		VCenterRequestErrorsTotal,
+		PropertyBatchSize,
+		TaskFailuresTotal,
+		OperationDurationSeconds,
+		SessionsActive,
+		SessionLoginsTotal,
+		SessionReloginsTotal,
+		KeepAliveFailuresTotal,
+		IPAddressClaimWaitSeconds,
+	)
+}
+
+// NewRoundTripper returns a soap.RoundTripper counting the requests sent
+// through rt to the vCenter with the given host.
+func NewRoundTripper(vcenter string, rt soap.RoundTripper) soap.RoundTripper {
+	return &roundTripper{vcenter: vcenter, rt: rt}
+}
+
+type roundTripper struct {
+	vcenter string
+	rt      soap.RoundTripper
+}
+
+func (r *roundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	method := Method(req)
+	VCenterRequestsTotal.WithLabelValues(r.vcenter, method).Inc()
+	err := r.rt.RoundTrip(ctx, req, res)
+	if err != nil {
+		VCenterRequestErrorsTotal.WithLabelValues(r.vcenter, method).Inc()
+	}
+	return err
+}
+
+// Method returns the name of the method of a SOAP request body, e.g.
+// RetrievePropertiesEx for a *methods.RetrievePropertiesExBody.
+func Method(req soap.HasFault) string {
+	t := reflect.TypeOf(req)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return strings.TrimSuffix(t.Name(), "Body")
+}
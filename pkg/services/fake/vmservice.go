@@ -31,9 +31,9 @@ type VMService struct {
 	mock.Mock
 }
 
-func (v *VMService) ReconcileVM(_ context.Context, vmCtx *capvcontext.VMContext) (infrav1.VirtualMachine, error) {
+func (v *VMService) ReconcileVM(_ context.Context, vmCtx *capvcontext.VMContext) (reconcile.Result, infrav1.VirtualMachine, error) {
 	args := v.Called(vmCtx)
-	return args.Get(0).(infrav1.VirtualMachine), args.Error(1)
+	return args.Get(0).(reconcile.Result), args.Get(1).(infrav1.VirtualMachine), args.Error(2)
 }
 
 func (v *VMService) DestroyVM(_ context.Context, vmCtx *capvcontext.VMContext) (reconcile.Result, infrav1.VirtualMachine, error) {
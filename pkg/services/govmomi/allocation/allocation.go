@@ -0,0 +1,119 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package allocation contains tools for the CPU and memory resource allocation of virtual machines.
+package allocation
+
+import (
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/utils/pointer"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// unlimited is the value of a resource allocation limit that does not limit
+// the consumption of the resource.
+const unlimited = int64(-1)
+
+// Apply sets the CPU and memory allocation of the given config spec to the
+// ones described by the resource allocation. Allocations which are not
+// described are left untouched.
+func Apply(spec *infrav1.ResourceAllocation, configSpec *types.VirtualMachineConfigSpec) {
+	if spec == nil {
+		return
+	}
+	if spec.CPU != nil {
+		configSpec.CpuAllocation = newResourceAllocationInfo(spec.CPU.ReservationMHz, spec.CPU.LimitMHz, spec.CPU.Shares)
+	}
+	if spec.Memory != nil {
+		configSpec.MemoryAllocation = newResourceAllocationInfo(spec.Memory.ReservationMiB, spec.Memory.LimitMiB, spec.Memory.Shares)
+	}
+}
+
+// CalculateChanges returns a config spec that updates the CPU and memory
+// allocation of a virtual machine with the given config to the ones described
+// by the resource allocation, or nil if they are already in place.
+//
+// The memory reservation is ignored if the memory of the virtual machine is
+// locked to its maximum, because it cannot be changed in that case.
+func CalculateChanges(spec *infrav1.ResourceAllocation, config *types.VirtualMachineConfigInfo) *types.VirtualMachineConfigSpec {
+	desired := &types.VirtualMachineConfigSpec{}
+	Apply(spec, desired)
+
+	changes := &types.VirtualMachineConfigSpec{}
+	changed := false
+	if desired.CpuAllocation != nil && !matches(desired.CpuAllocation, config.CpuAllocation, false) {
+		changes.CpuAllocation = desired.CpuAllocation
+		changed = true
+	}
+	if desired.MemoryAllocation != nil {
+		lockedToMax := config.MemoryReservationLockedToMax != nil && *config.MemoryReservationLockedToMax
+		if lockedToMax {
+			desired.MemoryAllocation.Reservation = nil
+		}
+		if !matches(desired.MemoryAllocation, config.MemoryAllocation, lockedToMax) {
+			changes.MemoryAllocation = desired.MemoryAllocation
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return changes
+}
+
+func newResourceAllocationInfo(reservation, limit *int64, shares *infrav1.Shares) *types.ResourceAllocationInfo {
+	info := &types.ResourceAllocationInfo{
+		Reservation: pointer.Int64(0),
+		Limit:       pointer.Int64(unlimited),
+		Shares:      &types.SharesInfo{Level: types.SharesLevelNormal},
+	}
+	if reservation != nil {
+		info.Reservation = pointer.Int64(*reservation)
+	}
+	if limit != nil {
+		info.Limit = pointer.Int64(*limit)
+	}
+	if shares != nil {
+		info.Shares.Level = types.SharesLevel(shares.Level)
+		if shares.Level == infrav1.SharesLevelCustom && shares.Value != nil {
+			info.Shares.Shares = *shares.Value
+		}
+	}
+	return info
+}
+
+// matches returns true if the actual resource allocation matches the desired
+// one. The number of shares is only compared for the custom shares level, as
+// it is computed by vSphere for the predefined levels.
+func matches(desired, actual *types.ResourceAllocationInfo, ignoreReservation bool) bool {
+	if actual == nil {
+		return false
+	}
+	if !ignoreReservation && pointer.Int64Deref(desired.Reservation, 0) != pointer.Int64Deref(actual.Reservation, 0) {
+		return false
+	}
+	if pointer.Int64Deref(desired.Limit, unlimited) != pointer.Int64Deref(actual.Limit, unlimited) {
+		return false
+	}
+	if actual.Shares == nil {
+		return false
+	}
+	if desired.Shares.Level != actual.Shares.Level {
+		return false
+	}
+	return desired.Shares.Level != types.SharesLevelCustom || desired.Shares.Shares == actual.Shares.Shares
+}
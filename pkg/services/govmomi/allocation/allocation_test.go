@@ -0,0 +1,116 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package allocation
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/utils/pointer"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+func Test_Apply(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	configSpec := &types.VirtualMachineConfigSpec{}
+	Apply(&infrav1.ResourceAllocation{
+		CPU: &infrav1.CPUAllocation{
+			ReservationMHz: pointer.Int64(2000),
+			Shares:         &infrav1.Shares{Level: infrav1.SharesLevelCustom, Value: pointer.Int32(3000)},
+		},
+	}, configSpec)
+
+	g.Expect(configSpec.MemoryAllocation).To(gomega.BeNil())
+	g.Expect(configSpec.CpuAllocation).ToNot(gomega.BeNil())
+	g.Expect(*configSpec.CpuAllocation.Reservation).To(gomega.Equal(int64(2000)))
+	g.Expect(*configSpec.CpuAllocation.Limit).To(gomega.Equal(unlimited))
+	g.Expect(configSpec.CpuAllocation.Shares.Level).To(gomega.Equal(types.SharesLevelCustom))
+	g.Expect(configSpec.CpuAllocation.Shares.Shares).To(gomega.Equal(int32(3000)))
+}
+
+func Test_CalculateChanges(t *testing.T) {
+	spec := &infrav1.ResourceAllocation{
+		CPU: &infrav1.CPUAllocation{ReservationMHz: pointer.Int64(2000)},
+		Memory: &infrav1.MemoryAllocation{
+			ReservationMiB: pointer.Int64(4096),
+			LimitMiB:       pointer.Int64(8192),
+			Shares:         &infrav1.Shares{Level: infrav1.SharesLevelHigh},
+		},
+	}
+	cpuInSync := &types.ResourceAllocationInfo{
+		Reservation: pointer.Int64(2000),
+		Limit:       pointer.Int64(-1),
+		Shares:      &types.SharesInfo{Level: types.SharesLevelNormal, Shares: 2000},
+	}
+	memoryInSync := &types.ResourceAllocationInfo{
+		Reservation: pointer.Int64(4096),
+		Limit:       pointer.Int64(8192),
+		Shares:      &types.SharesInfo{Level: types.SharesLevelHigh, Shares: 81920},
+	}
+
+	tests := []struct {
+		name           string
+		config         *types.VirtualMachineConfigInfo
+		expectedCPU    bool
+		expectedMemory bool
+	}{
+		{
+			name:   "allocations in sync",
+			config: &types.VirtualMachineConfigInfo{CpuAllocation: cpuInSync, MemoryAllocation: memoryInSync},
+		},
+		{
+			name: "cpu reservation drifted",
+			config: &types.VirtualMachineConfigInfo{
+				CpuAllocation:    &types.ResourceAllocationInfo{Reservation: pointer.Int64(0), Limit: pointer.Int64(-1), Shares: &types.SharesInfo{Level: types.SharesLevelNormal}},
+				MemoryAllocation: memoryInSync,
+			},
+			expectedCPU: true,
+		},
+		{
+			name: "memory shares level drifted",
+			config: &types.VirtualMachineConfigInfo{
+				CpuAllocation:    cpuInSync,
+				MemoryAllocation: &types.ResourceAllocationInfo{Reservation: pointer.Int64(4096), Limit: pointer.Int64(8192), Shares: &types.SharesInfo{Level: types.SharesLevelNormal}},
+			},
+			expectedMemory: true,
+		},
+		{
+			name: "memory reservation locked to max",
+			config: &types.VirtualMachineConfigInfo{
+				CpuAllocation:                cpuInSync,
+				MemoryAllocation:             &types.ResourceAllocationInfo{Reservation: pointer.Int64(16384), Limit: pointer.Int64(8192), Shares: &types.SharesInfo{Level: types.SharesLevelHigh}},
+				MemoryReservationLockedToMax: pointer.Bool(true),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			changes := CalculateChanges(spec, tt.config)
+			if !tt.expectedCPU && !tt.expectedMemory {
+				g.Expect(changes).To(gomega.BeNil())
+				return
+			}
+			g.Expect(changes).ToNot(gomega.BeNil())
+			g.Expect(changes.CpuAllocation != nil).To(gomega.Equal(tt.expectedCPU))
+			g.Expect(changes.MemoryAllocation != nil).To(gomega.Equal(tt.expectedMemory))
+		})
+	}
+}
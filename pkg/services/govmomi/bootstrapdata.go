@@ -0,0 +1,247 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"bytes"
+	"context"
+	"path"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	bootstrapv1 "sigs.k8s.io/cluster-api/bootstrap/kubeadm/api/v1beta1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/extra"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/nocloud"
+)
+
+// reconcileBootstrapDataISO uploads the NoCloud ISO image holding the
+// bootstrap data and the metadata of a VSphereVM using the NoCloud bootstrap
+// data transport to the directory of the VM, and attaches it to the VM
+// before it is powered on for the first time.
+func (vms *VMService) reconcileBootstrapDataISO(ctx context.Context, virtualMachineCtx *virtualMachineContext) (bool, error) {
+	if virtualMachineCtx.VSphereVM.Spec.BootstrapDataTransport != infrav1.BootstrapDataTransportNoCloud {
+		return true, nil
+	}
+
+	virtualMachine, err := virtualMachineCtx.Properties(ctx)
+	if err != nil {
+		return false, errors.Wrapf(err, "error getting devices of VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+	if virtualMachine.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOff {
+		// The data source is only read on the first boot.
+		return true, nil
+	}
+	if virtualMachine.Config == nil {
+		return false, errors.Errorf("error getting devices of VM %s: config is not available", virtualMachineCtx.VSphereVM.Name)
+	}
+	// The ISO image is not attached again once the bootstrap data was
+	// consumed and cleaned up.
+	completed, err := bootstrapCompleted(ctx, virtualMachineCtx)
+	if err != nil {
+		return false, err
+	}
+	if completed {
+		return true, nil
+	}
+	devices := object.VirtualDeviceList(virtualMachine.Config.Hardware.Device)
+	if isoPath := virtualMachineCtx.VSphereVM.Status.BootstrapDataISO; isoPath != "" && isISOAttached(devices, isoPath) {
+		return true, nil
+	}
+
+	bootstrapData, format, err := vms.getBootstrapData(ctx, &virtualMachineCtx.VMContext)
+	if err != nil {
+		return false, err
+	}
+	if len(bootstrapData) == 0 {
+		return true, nil
+	}
+	if format != bootstrapv1.CloudConfig {
+		return false, errors.Errorf("bootstrap data of format %q cannot be passed to VM %s through the NoCloud transport", format, virtualMachineCtx.VSphereVM.Name)
+	}
+	metadata, err := vms.getMachineMetadata(ctx, virtualMachineCtx)
+	if err != nil {
+		return false, err
+	}
+	image, err := nocloud.ISO(bootstrapData, metadata)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to build NoCloud ISO for VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+
+	var vmxPath object.DatastorePath
+	if !vmxPath.FromString(virtualMachine.Config.Files.VmPathName) {
+		return false, errors.Errorf("unable to parse path %q of VM %s", virtualMachine.Config.Files.VmPathName, virtualMachineCtx.VSphereVM.Name)
+	}
+	isoPath := object.DatastorePath{
+		Datastore: vmxPath.Datastore,
+		Path:      path.Join(path.Dir(vmxPath.Path), virtualMachineCtx.VSphereVM.Name+"-cidata.iso"),
+	}
+	datastore, err := virtualMachineCtx.Session.Finder.Datastore(ctx, isoPath.Datastore)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to find datastore %s of VM %s", isoPath.Datastore, virtualMachineCtx.VSphereVM.Name)
+	}
+	upload := soap.DefaultUpload
+	upload.ContentLength = int64(len(image))
+	virtualMachineCtx.Logger.Info("uploading NoCloud ISO", "path", isoPath.String())
+	if err := datastore.Upload(ctx, bytes.NewReader(image), isoPath.Path, &upload); err != nil {
+		return false, errors.Wrapf(err, "unable to upload NoCloud ISO for VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+	virtualMachineCtx.VSphereVM.Status.BootstrapDataISO = isoPath.String()
+
+	if err := attachISO(ctx, virtualMachineCtx, devices, isoPath.String()); err != nil {
+		return false, errors.Wrapf(err, "unable to attach NoCloud ISO to VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+	// The devices of the VM changed.
+	virtualMachineCtx.properties = nil
+	return true, nil
+}
+
+// reconcileBootstrapDataCleanup removes the bootstrap data from the VM once
+// the bootstrap completed, as it contains credentials like the join token.
+// The user data is removed from the guestinfo and the NoCloud ISO image is
+// detached and deleted. The metadata in the guestinfo is kept as the guest
+// still reads its network configuration on boot.
+func (vms *VMService) reconcileBootstrapDataCleanup(ctx context.Context, virtualMachineCtx *virtualMachineContext) (bool, error) {
+	completed, err := bootstrapCompleted(ctx, virtualMachineCtx)
+	if err != nil {
+		return false, err
+	}
+	if !completed {
+		return true, nil
+	}
+
+	virtualMachine, err := virtualMachineCtx.Properties(ctx)
+	if err != nil {
+		return false, errors.Wrapf(err, "error getting extra config of VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+	if virtualMachine.Config == nil {
+		return true, nil
+	}
+
+	if isoPath := virtualMachineCtx.VSphereVM.Status.BootstrapDataISO; isoPath != "" {
+		devices := object.VirtualDeviceList(virtualMachine.Config.Hardware.Device)
+		if cdrom := findISO(devices, isoPath); cdrom != nil {
+			virtualMachineCtx.Logger.Info("detaching NoCloud ISO", "path", isoPath)
+			if err := virtualMachineCtx.Obj.EditDevice(ctx, devices.EjectIso(cdrom)); err != nil {
+				return false, errors.Wrapf(err, "unable to detach NoCloud ISO from VM %s", virtualMachineCtx.VSphereVM.Name)
+			}
+			// The devices of the VM changed.
+			virtualMachineCtx.properties = nil
+		}
+		if err := deleteBootstrapDataISO(ctx, &virtualMachineCtx.VMContext); err != nil {
+			return false, err
+		}
+	}
+
+	if !extra.HasUserData(virtualMachine.Config.ExtraConfig) {
+		return true, nil
+	}
+
+	var extraConfig extra.Config
+	extraConfig.ClearUserData()
+	virtualMachineCtx.Logger.Info("removing bootstrap data from guestinfo")
+	task, err := virtualMachineCtx.Obj.Reconfigure(ctx, types.VirtualMachineConfigSpec{ExtraConfig: extraConfig})
+	if err != nil {
+		return false, errors.Wrapf(err, "error triggering reconfigure op to remove bootstrap data of VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+	virtualMachineCtx.VSphereVM.Status.TaskRef = task.Reference().Value
+	return false, nil
+}
+
+// bootstrapCompleted returns true once the node of the Machine of the VM
+// joined the cluster or, with the BootstrapSentinel cleanup policy, the guest
+// reported the success of the bootstrap.
+func bootstrapCompleted(ctx context.Context, virtualMachineCtx *virtualMachineContext) (bool, error) {
+	if virtualMachineCtx.Machine != nil && virtualMachineCtx.Machine.Status.NodeRef != nil {
+		return true, nil
+	}
+	if virtualMachineCtx.VSphereVM.Spec.BootstrapDataCleanupPolicy != infrav1.BootstrapDataCleanupPolicyBootstrapSentinel {
+		return false, nil
+	}
+	virtualMachine, err := virtualMachineCtx.Properties(ctx)
+	if err != nil {
+		return false, errors.Wrapf(err, "error getting extra config of VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+	return virtualMachine.Config != nil && extra.BootstrapSucceeded(virtualMachine.Config.ExtraConfig), nil
+}
+
+// attachISO inserts the ISO image into the first CD-ROM of the VM, or into
+// a new CD-ROM if the VM has none.
+func attachISO(ctx context.Context, virtualMachineCtx *virtualMachineContext, devices object.VirtualDeviceList, isoPath string) error {
+	connectable := &types.VirtualDeviceConnectInfo{StartConnected: true, AllowGuestControl: true}
+	if cdroms := devices.SelectByType((*types.VirtualCdrom)(nil)); len(cdroms) > 0 {
+		cdrom := devices.InsertIso(cdroms[0].(*types.VirtualCdrom), isoPath)
+		cdrom.Connectable = connectable
+		return virtualMachineCtx.Obj.EditDevice(ctx, cdrom)
+	}
+
+	controller, err := devices.FindIDEController("")
+	if err != nil {
+		return err
+	}
+	cdrom, err := devices.CreateCdrom(controller)
+	if err != nil {
+		return err
+	}
+	cdrom = devices.InsertIso(cdrom, isoPath)
+	cdrom.Connectable = connectable
+	return virtualMachineCtx.Obj.AddDevice(ctx, cdrom)
+}
+
+func isISOAttached(devices object.VirtualDeviceList, isoPath string) bool {
+	return findISO(devices, isoPath) != nil
+}
+
+// findISO returns the CD-ROM of the VM into which the ISO image is inserted,
+// or nil if the ISO image is not attached.
+func findISO(devices object.VirtualDeviceList, isoPath string) *types.VirtualCdrom {
+	for _, device := range devices.SelectByType((*types.VirtualCdrom)(nil)) {
+		if backing, ok := device.GetVirtualDevice().Backing.(*types.VirtualCdromIsoBackingInfo); ok && backing.FileName == isoPath {
+			return device.(*types.VirtualCdrom)
+		}
+	}
+	return nil
+}
+
+// deleteBootstrapDataISO deletes the NoCloud ISO image of the VSphereVM, if
+// any. It is called before the VM is destroyed so that its directory is
+// removed with it.
+func deleteBootstrapDataISO(ctx context.Context, vmCtx *capvcontext.VMContext) error {
+	isoPath := vmCtx.VSphereVM.Status.BootstrapDataISO
+	if isoPath == "" {
+		return nil
+	}
+
+	datacenter, err := vmCtx.Session.Finder.DatacenterOrDefault(ctx, vmCtx.VSphereVM.Spec.Datacenter)
+	if err != nil {
+		return errors.Wrapf(err, "unable to find datacenter to delete NoCloud ISO %s", isoPath)
+	}
+	vmCtx.Logger.Info("deleting NoCloud ISO", "path", isoPath)
+	task, err := object.NewFileManager(vmCtx.Session.Client.Client).DeleteDatastoreFile(ctx, isoPath, datacenter)
+	if err == nil {
+		err = task.Wait(ctx)
+	}
+	if err != nil && !types.IsFileNotFound(err) {
+		return errors.Wrapf(err, "unable to delete NoCloud ISO %s", isoPath)
+	}
+	vmCtx.VSphereVM.Status.BootstrapDataISO = ""
+	return nil
+}
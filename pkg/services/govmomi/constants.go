@@ -17,6 +17,8 @@ limitations under the License.
 // Package govmomi contains tools for interacting with vSphere APIs.
 package govmomi
 
+import "time"
+
 const (
 	morefTypeTask = "Task"
 )
@@ -24,3 +26,11 @@ const (
 const (
 	guestInfoKeyMetadata = "guestinfo.metadata"
 )
+
+const (
+	// taskRetryBaseDelay is the delay before retrying a task which failed
+	// with a transient fault, doubled with each consecutive failure up to
+	// taskRetryMaxDelay.
+	taskRetryBaseDelay = time.Minute
+	taskRetryMaxDelay  = 30 * time.Minute
+)
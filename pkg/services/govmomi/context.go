@@ -17,11 +17,15 @@ limitations under the License.
 package govmomi
 
 import (
+	"context"
+
+	"github.com/pkg/errors"
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
 )
 
 type virtualMachineContext struct {
@@ -30,8 +34,29 @@ type virtualMachineContext struct {
 	Obj       *object.VirtualMachine
 	State     *infrav1.VirtualMachine
 	IPAMState map[string]infrav1.NetworkDeviceSpec
+
+	// properties is the snapshot of the properties of the VM used during a
+	// single reconcile, nil until it is retrieved.
+	properties *session.VirtualMachine
 }
 
 func (c *virtualMachineContext) String() string {
 	return c.VMContext.String()
 }
+
+// Properties returns the snapshot of the session.VirtualMachineProperties of
+// the VM, retrieving it with the property batcher of the session if needed.
+func (c *virtualMachineContext) Properties(ctx context.Context) (*session.VirtualMachine, error) {
+	if c.properties != nil {
+		return c.properties, nil
+	}
+	vm, err := c.Session.RetrieveVirtualMachine(ctx, c.Obj.Reference())
+	if err != nil {
+		return nil, err
+	}
+	if vm == nil {
+		return nil, errors.Errorf("vm %s not found", c.Obj.Reference())
+	}
+	c.properties = vm
+	return vm, nil
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/netip"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// The customization states reported by VMware Tools in the guest info of
+// vSphere 7.0 U2 and later.
+const (
+	customizationStatusPending   = "TOOLSDEPLOYPKG_PENDING"
+	customizationStatusRunning   = "TOOLSDEPLOYPKG_RUNNING"
+	customizationStatusSucceeded = "TOOLSDEPLOYPKG_SUCCEEDED"
+	customizationStatusFailed    = "TOOLSDEPLOYPKG_FAILED"
+)
+
+// bootstrapRunOnceCommand runs the bootstrap data delivered through the
+// guestinfo user data as a PowerShell script at the first logon following the
+// customization. User data which is not a PowerShell script, e.g. a cloud-config
+// consumed by cloudbase-init, is ignored.
+const bootstrapRunOnceCommand = `powershell.exe -NoProfile -ExecutionPolicy Bypass -Command "$d=[Text.Encoding]::UTF8.GetString([Convert]::FromBase64String((& 'C:\Program Files\VMware\VMware Tools\rpctool.exe' 'info-get guestinfo.userdata')));if($d -like '#ps1*'){iex $d}"`
+
+// reconcileCustomization stages the guest OS customization of the VSphereVM
+// on the VM before it is powered on for the first time, and reports its
+// progress in the guest through the GuestCustomizedCondition afterwards.
+// A customization is staged only once, a VM that was powered on without it
+// is not customized anymore.
+func (vms *VMService) reconcileCustomization(ctx context.Context, virtualMachineCtx *virtualMachineContext) (bool, error) {
+	if virtualMachineCtx.VSphereVM.Spec.Customization == nil {
+		return true, nil
+	}
+
+	var virtualMachine mo.VirtualMachine
+	if err := virtualMachineCtx.Obj.Properties(ctx, virtualMachineCtx.Obj.Reference(), []string{"guest", "runtime.powerState"}, &virtualMachine); err != nil {
+		return false, errors.Wrapf(err, "error getting customization status of VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+
+	if conditions.Has(virtualMachineCtx.VSphereVM, infrav1.GuestCustomizedCondition) {
+		if !conditions.IsTrue(virtualMachineCtx.VSphereVM, infrav1.GuestCustomizedCondition) &&
+			virtualMachine.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOn {
+			updateCustomizationCondition(virtualMachineCtx, virtualMachine.Guest)
+		}
+		return true, nil
+	}
+
+	if virtualMachine.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOff {
+		virtualMachineCtx.Logger.Info("skipping guest customization of a VM which is not powered off", "powerState", virtualMachine.Runtime.PowerState)
+		return true, nil
+	}
+
+	spec, err := vms.getCustomizationSpec(ctx, virtualMachineCtx)
+	if err != nil {
+		conditions.MarkFalse(virtualMachineCtx.VSphereVM, infrav1.GuestCustomizedCondition, infrav1.GuestCustomizationFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		return false, err
+	}
+
+	virtualMachineCtx.Logger.Info("staging guest customization")
+	task, err := virtualMachineCtx.Obj.Customize(ctx, *spec)
+	if err == nil {
+		// Staging the customization only validates the spec and writes it to
+		// the VM, it runs in the guest once the VM is powered on.
+		err = task.Wait(ctx)
+	}
+	if err != nil {
+		// The condition is not set so that the customization is retried.
+		return false, errors.Wrapf(err, "error customizing VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+	conditions.MarkFalse(virtualMachineCtx.VSphereVM, infrav1.GuestCustomizedCondition, infrav1.GuestCustomizationPendingReason, clusterv1.ConditionSeverityInfo, "")
+	return true, nil
+}
+
+// updateCustomizationCondition sets the GuestCustomizedCondition according to
+// the customization status reported by VMware Tools. The customization stays
+// pending until VMware Tools reports its success, which is never the case on
+// vSphere versions older than 7.0 U2.
+func updateCustomizationCondition(virtualMachineCtx *virtualMachineContext, guest *types.GuestInfo) {
+	if guest == nil || guest.CustomizationInfo == nil {
+		return
+	}
+
+	switch info := guest.CustomizationInfo; info.CustomizationStatus {
+	case customizationStatusSucceeded:
+		conditions.MarkTrue(virtualMachineCtx.VSphereVM, infrav1.GuestCustomizedCondition)
+	case customizationStatusFailed:
+		conditions.MarkFalse(virtualMachineCtx.VSphereVM, infrav1.GuestCustomizedCondition, infrav1.GuestCustomizationFailedReason, clusterv1.ConditionSeverityWarning, info.ErrorMsg)
+	case customizationStatusPending, customizationStatusRunning:
+		conditions.MarkFalse(virtualMachineCtx.VSphereVM, infrav1.GuestCustomizedCondition, infrav1.GuestCustomizationPendingReason, clusterv1.ConditionSeverityInfo, "")
+	}
+}
+
+// getCustomizationSpec returns the customization spec for the VSphereVM,
+// either a copy of the named spec stored in vCenter or one wrapping the
+// inline sysprep answer file. The computer name and network settings are
+// set from the VSphereVM in both cases.
+func (vms *VMService) getCustomizationSpec(ctx context.Context, virtualMachineCtx *virtualMachineContext) (*types.CustomizationSpec, error) {
+	customization := virtualMachineCtx.VSphereVM.Spec.Customization
+	hostname := virtualMachineCtx.VSphereVM.Name
+
+	spec := &types.CustomizationSpec{}
+	if customization.SpecName != "" {
+		item, err := object.NewCustomizationSpecManager(virtualMachineCtx.Session.Client.Client).GetCustomizationSpec(ctx, customization.SpecName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get customization spec %q", customization.SpecName)
+		}
+		spec = &item.Spec
+		switch identity := spec.Identity.(type) {
+		case *types.CustomizationSysprep:
+			identity.UserData.ComputerName = &types.CustomizationFixedName{Name: hostname}
+			if runsBootstrapData(virtualMachineCtx) {
+				if identity.GuiRunOnce == nil {
+					identity.GuiRunOnce = &types.CustomizationGuiRunOnce{}
+				}
+				identity.GuiRunOnce.CommandList = append(identity.GuiRunOnce.CommandList, bootstrapRunOnceCommand)
+			}
+		case *types.CustomizationSysprepText:
+			// The computer name and the commands run at the first logon
+			// are part of the answer file.
+		default:
+			return nil, errors.Errorf("customization spec %q is not a Windows customization spec", customization.SpecName)
+		}
+	} else {
+		var bootstrapCommand string
+		if runsBootstrapData(virtualMachineCtx) {
+			bootstrapCommand = bootstrapRunOnceCommand
+		}
+		sysprep, err := renderSysprep(customization.Sysprep, hostname, bootstrapCommand)
+		if err != nil {
+			return nil, err
+		}
+		spec.Identity = &types.CustomizationSysprepText{Value: sysprep}
+	}
+
+	devices := virtualMachineCtx.VSphereVM.Spec.Network.Devices
+	nicSettingMap, err := buildAdapterMappings(devices, virtualMachineCtx.IPAMState, virtualMachineCtx.State.Network)
+	if err != nil {
+		return nil, err
+	}
+	spec.NicSettingMap = nicSettingMap
+	for i := range devices {
+		spec.GlobalIPSettings.DnsSuffixList = append(spec.GlobalIPSettings.DnsSuffixList, devices[i].SearchDomains...)
+	}
+	return spec, nil
+}
+
+// runsBootstrapData returns true if the bootstrap data is delivered through the
+// guestinfo user data, from which the bootstrapRunOnceCommand reads it.
+func runsBootstrapData(virtualMachineCtx *virtualMachineContext) bool {
+	return virtualMachineCtx.VSphereVM.Spec.BootstrapDataTransport != infrav1.BootstrapDataTransportNoCloud
+}
+
+// renderSysprep renders the inline sysprep answer file template for the
+// given hostname and command running the bootstrap data. The command is
+// escaped, as answer files are XML documents.
+func renderSysprep(sysprep, hostname, bootstrapCommand string) (string, error) {
+	tpl, err := template.New("sysprep").Parse(sysprep)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to parse sysprep template")
+	}
+	buf := &bytes.Buffer{}
+	data := struct {
+		Hostname         string
+		BootstrapCommand string
+	}{
+		Hostname:         hostname,
+		BootstrapCommand: template.HTMLEscapeString(bootstrapCommand),
+	}
+	if err := tpl.Execute(buf, data); err != nil {
+		return "", errors.Wrap(err, "unable to render sysprep template")
+	}
+	return buf.String(), nil
+}
+
+// buildAdapterMappings returns the customization settings of the network
+// devices of a VM, including the addresses allocated from IPAM pools. The
+// devices are matched with the network status by index, as for the metadata.
+func buildAdapterMappings(devices []infrav1.NetworkDeviceSpec, ipamState map[string]infrav1.NetworkDeviceSpec, networkStatus []infrav1.NetworkStatus) ([]types.CustomizationAdapterMapping, error) {
+	mappings := make([]types.CustomizationAdapterMapping, 0, len(devices))
+	for i := range devices {
+		device := devices[i]
+		if len(networkStatus) <= i {
+			return nil, errors.Errorf("network device %d has no MAC address", i)
+		}
+		macAddr := networkStatus[i].MACAddr
+
+		ipAddrs := device.IPAddrs
+		if state, ok := ipamState[macAddr]; ok {
+			ipAddrs = append(append([]string{}, ipAddrs...), state.IPAddrs...)
+			device.Gateway4 = state.Gateway4
+			device.Gateway6 = state.Gateway6
+		}
+
+		adapter := types.CustomizationIPSettings{
+			Ip:            &types.CustomizationDhcpIpGenerator{},
+			DnsServerList: device.Nameservers,
+		}
+		var ipv6Generators []types.BaseCustomizationIpV6Generator
+		for _, ipAddr := range ipAddrs {
+			prefix, err := netip.ParsePrefix(ipAddr)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid IP address %q of network device %d", ipAddr, i)
+			}
+			if prefix.Addr().Is4() {
+				// Windows customization supports a single static IPv4 address per adapter.
+				if _, ok := adapter.Ip.(*types.CustomizationFixedIp); ok || device.DHCP4 {
+					continue
+				}
+				adapter.Ip = &types.CustomizationFixedIp{IpAddress: prefix.Addr().String()}
+				adapter.SubnetMask = net.IP(net.CIDRMask(prefix.Bits(), 32)).String()
+				continue
+			}
+			if !device.DHCP6 {
+				ipv6Generators = append(ipv6Generators, &types.CustomizationFixedIpV6{
+					IpAddress:  prefix.Addr().String(),
+					SubnetMask: int32(prefix.Bits()),
+				})
+			}
+		}
+		if device.Gateway4 != "" {
+			adapter.Gateway = []string{device.Gateway4}
+		}
+		if device.DHCP6 {
+			ipv6Generators = []types.BaseCustomizationIpV6Generator{&types.CustomizationDhcpIpV6Generator{}}
+		}
+		if len(ipv6Generators) > 0 {
+			adapter.IpV6Spec = &types.CustomizationIPSettingsIpV6AddressSpec{Ip: ipv6Generators}
+			if device.Gateway6 != "" {
+				adapter.IpV6Spec.Gateway = []string{device.Gateway6}
+			}
+		}
+
+		mappings = append(mappings, types.CustomizationAdapterMapping{
+			MacAddress: macAddr,
+			Adapter:    adapter,
+		})
+	}
+	return mappings, nil
+}
@@ -0,0 +1,329 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// vmDrift are the differences between the spec of a VSphereVM and the
+// configuration of its VM, with the changes which correct them.
+type vmDrift struct {
+	// fields describes the differing fields of the spec.
+	fields []string
+
+	// resize changes the CPUs and memory of the VM back to the spec, it can
+	// be applied to the powered on VM if hotAddable is true.
+	resize     *types.VirtualMachineConfigSpec
+	hotAddable bool
+
+	// networkChanges attach the network devices of the VM back to the
+	// networks of the spec.
+	networkChanges []types.BaseVirtualDeviceConfigSpec
+
+	// folder and pool are the folder and resource pool of the spec, if the
+	// VM was moved out of them.
+	folder *object.Folder
+	pool   *object.ResourcePool
+}
+
+func (d *vmDrift) add(format string, args ...interface{}) {
+	d.fields = append(d.fields, fmt.Sprintf(format, args...))
+}
+
+func (d *vmDrift) String() string {
+	return strings.Join(d.fields, ", ")
+}
+
+// reconcileDrift compares the CPUs, memory, network devices, folder and
+// resource pool of the VM with the spec of the VSphereVM, and handles the
+// differences according to the drift policy of the VSphereVM. It returns
+// false while the VM is changed back to its spec, or once the VSphereVM is
+// failed for remediation.
+func (vms *VMService) reconcileDrift(ctx context.Context, virtualMachineCtx *virtualMachineContext) (bool, error) {
+	vsphereVM := virtualMachineCtx.VSphereVM
+
+	// The VM is only compared once it has been provisioned completely, as
+	// it differs from its spec while it is cloned and customized. The VM is
+	// also compared once the tasks started during the reconcile, e.g. the
+	// update of its storage policy, have completed.
+	if !vsphereVM.Status.Ready || vsphereVM.Status.TaskRef != "" {
+		return true, nil
+	}
+
+	virtualMachine, err := virtualMachineCtx.Properties(ctx)
+	if err != nil {
+		return false, errors.Wrapf(err, "error getting configuration of VM %s", vsphereVM.Name)
+	}
+	if virtualMachine.Config == nil {
+		return false, errors.Errorf("error getting configuration of VM %s: config is not available", vsphereVM.Name)
+	}
+
+	drift, err := calculateDrift(ctx, virtualMachineCtx, &virtualMachine.VirtualMachine)
+	if err != nil {
+		return false, err
+	}
+	if len(drift.fields) == 0 {
+		conditions.MarkTrue(vsphereVM, infrav1.VMConfigurationInSyncCondition)
+		return true, nil
+	}
+	if !conditions.IsFalse(vsphereVM, infrav1.VMConfigurationInSyncCondition) {
+		virtualMachineCtx.Logger.Info("VM configuration drifted from spec", "fields", drift.String(), "policy", vsphereVM.Spec.DriftPolicy)
+	}
+
+	switch vsphereVM.Spec.DriftPolicy {
+	case infrav1.VirtualMachineDriftPolicyCorrect:
+		return vms.correctDrift(ctx, virtualMachineCtx, drift)
+	case infrav1.VirtualMachineDriftPolicyRemediate:
+		// As for terminal task faults, failing the VSphereVM fails its
+		// VSphereMachine, so that the Machine is remediated.
+		failureReason := capierrors.UpdateMachineError
+		vsphereVM.Status.FailureReason = &failureReason
+		vsphereVM.Status.FailureMessage = pointer.String(fmt.Sprintf("VM configuration drifted from spec: %s", drift))
+		conditions.MarkFalse(vsphereVM, infrav1.VMConfigurationInSyncCondition, infrav1.DriftDetectedReason, clusterv1.ConditionSeverityError, "%s", drift)
+		return false, nil
+	default:
+		conditions.MarkFalse(vsphereVM, infrav1.VMConfigurationInSyncCondition, infrav1.DriftDetectedReason, clusterv1.ConditionSeverityWarning, "%s", drift)
+		return true, nil
+	}
+}
+
+// correctDrift starts the task which changes the VM back to its spec. The
+// CPUs, memory and networks are reconfigured first, then the VM is moved back
+// to its folder and relocated to its resource pool, one task per reconcile.
+func (vms *VMService) correctDrift(ctx context.Context, virtualMachineCtx *virtualMachineContext, drift *vmDrift) (bool, error) {
+	vsphereVM := virtualMachineCtx.VSphereVM
+
+	var task *object.Task
+	var err error
+	switch {
+	case (drift.resize != nil && drift.hotAddable) || len(drift.networkChanges) > 0:
+		configSpec := types.VirtualMachineConfigSpec{}
+		if drift.resize != nil && drift.hotAddable {
+			configSpec = *drift.resize
+		}
+		configSpec.DeviceChange = drift.networkChanges
+		virtualMachineCtx.Logger.Info("reconfiguring VM to correct drift", "fields", drift.String())
+		task, err = virtualMachineCtx.Obj.Reconfigure(ctx, configSpec)
+	case drift.folder != nil:
+		virtualMachineCtx.Logger.Info("moving VM back to its folder", "folder", drift.folder.InventoryPath)
+		task, err = drift.folder.MoveInto(ctx, []types.ManagedObjectReference{virtualMachineCtx.Ref})
+	case drift.pool != nil:
+		virtualMachineCtx.Logger.Info("relocating VM back to its resource pool", "resourcePool", drift.pool.InventoryPath)
+		pool := drift.pool.Reference()
+		task, err = virtualMachineCtx.Obj.Relocate(ctx, types.VirtualMachineRelocateSpec{Pool: &pool}, types.VirtualMachineMovePriorityDefaultPriority)
+	default:
+		// The remaining differences, e.g. removed network devices or CPUs
+		// which cannot be hot added, are reported.
+		conditions.MarkFalse(vsphereVM, infrav1.VMConfigurationInSyncCondition, infrav1.DriftDetectedReason, clusterv1.ConditionSeverityWarning,
+			"%s cannot be corrected on the powered on VM", drift)
+		return true, nil
+	}
+	if err != nil {
+		conditions.MarkFalse(vsphereVM, infrav1.VMConfigurationInSyncCondition, infrav1.DriftCorrectionFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		return false, errors.Wrapf(err, "error triggering op to correct drift of VM %s", vsphereVM.Name)
+	}
+	conditions.MarkFalse(vsphereVM, infrav1.VMConfigurationInSyncCondition, infrav1.CorrectingDriftReason, clusterv1.ConditionSeverityInfo, "%s", drift)
+	vsphereVM.Status.TaskRef = task.Reference().Value
+	return false, nil
+}
+
+// calculateDrift returns the differences between the spec of the VSphereVM
+// and the given properties of its VM.
+func calculateDrift(ctx context.Context, virtualMachineCtx *virtualMachineContext, virtualMachine *mo.VirtualMachine) (*vmDrift, error) {
+	spec := &virtualMachineCtx.VSphereVM.Spec
+	config := virtualMachine.Config
+	drift := &vmDrift{}
+
+	// The CPUs and memory of VSphereVMs with a resize policy are reconciled
+	// by the resize instead, and the ones of instant clones are inherited
+	// from the source VM.
+	resizable := spec.ResizePolicy != "" && spec.ResizePolicy != infrav1.VirtualMachineResizePolicyDisabled
+	if !resizable && spec.CloneMode != infrav1.InstantClone {
+		if configSpec, hotAddable := calculateResize(&spec.VirtualMachineCloneSpec, config); configSpec != nil {
+			drift.resize, drift.hotAddable = configSpec, hotAddable
+			if configSpec.NumCPUs != 0 {
+				drift.add("numCPUs (%d instead of %d)", config.Hardware.NumCPU, configSpec.NumCPUs)
+			}
+			if configSpec.NumCoresPerSocket != 0 {
+				drift.add("numCoresPerSocket (%d instead of %d)", config.Hardware.NumCoresPerSocket, configSpec.NumCoresPerSocket)
+			}
+			if configSpec.MemoryMB != 0 {
+				drift.add("memoryMiB (%d instead of %d)", config.Hardware.MemoryMB, configSpec.MemoryMB)
+			}
+		}
+	}
+
+	nics := object.VirtualDeviceList(config.Hardware.Device).SelectByType((*types.VirtualEthernetCard)(nil))
+	if len(nics) != len(spec.Network.Devices) {
+		drift.add("network.devices (%d instead of %d)", len(nics), len(spec.Network.Devices))
+	}
+	for i := range spec.Network.Devices {
+		if i >= len(nics) {
+			break
+		}
+		backing, err := networkBacking(ctx, virtualMachineCtx, spec.Network.Devices[i].NetworkName)
+		if err != nil {
+			return nil, err
+		}
+		nic := nics[i].(types.BaseVirtualEthernetCard).GetVirtualEthernetCard()
+		if sameNetwork(nic.Backing, backing) {
+			continue
+		}
+		drift.add("network.devices[%d].networkName", i)
+		nic.Backing = backing
+		drift.networkChanges = append(drift.networkChanges, &types.VirtualDeviceConfigSpec{
+			Operation: types.VirtualDeviceConfigSpecOperationEdit,
+			Device:    nics[i],
+		})
+	}
+
+	// The VM is cloned into the default folder if the spec has none.
+	folder, err := resolveInventoryObject(virtualMachineCtx, "Folder", spec.Folder, func() (inventoryObject, error) {
+		folder, err := virtualMachineCtx.Session.Finder.FolderOrDefault(ctx, spec.Folder)
+		if err != nil {
+			return inventoryObject{}, errors.Wrapf(err, "unable to get folder %q", spec.Folder)
+		}
+		return inventoryObject{ref: folder.Reference(), path: folder.InventoryPath}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if virtualMachine.Parent == nil || *virtualMachine.Parent != folder.ref {
+		drift.add("folder")
+		drift.folder = object.NewFolder(virtualMachineCtx.Session.Client.Client, folder.ref)
+		drift.folder.InventoryPath = folder.path
+	}
+
+	// The default resource pool cannot be determined in every inventory, so
+	// the resource pool is only compared if the spec has one.
+	if spec.ResourcePool != "" {
+		pool, err := resolveInventoryObject(virtualMachineCtx, "ResourcePool", spec.ResourcePool, func() (inventoryObject, error) {
+			pool, err := virtualMachineCtx.Session.Finder.ResourcePool(ctx, spec.ResourcePool)
+			if err != nil {
+				return inventoryObject{}, errors.Wrapf(err, "unable to get resource pool %q", spec.ResourcePool)
+			}
+			return inventoryObject{ref: pool.Reference(), path: pool.InventoryPath}, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if virtualMachine.ResourcePool == nil || *virtualMachine.ResourcePool != pool.ref {
+			drift.add("resourcePool")
+			drift.pool = object.NewResourcePool(virtualMachineCtx.Session.Client.Client, pool.ref)
+			drift.pool.InventoryPath = pool.path
+		}
+	}
+	return drift, nil
+}
+
+// networkBacking returns the backing of the network devices attached to the
+// network with the given name.
+func networkBacking(ctx context.Context, virtualMachineCtx *virtualMachineContext, networkName string) (types.BaseVirtualDeviceBackingInfo, error) {
+	network, err := resolveInventoryObject(virtualMachineCtx, "Network", networkName, func() (inventoryObject, error) {
+		ref, err := virtualMachineCtx.Session.Finder.Network(ctx, networkName)
+		if err != nil {
+			return inventoryObject{}, errors.Wrapf(err, "unable to find network %q", networkName)
+		}
+		backing, err := ref.EthernetCardBackingInfo(ctx)
+		if err != nil {
+			return inventoryObject{}, errors.Wrapf(err, "unable to create ethernet card backing info for network %q", networkName)
+		}
+		return inventoryObject{ref: ref.Reference(), backing: backing}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return network.backing, nil
+}
+
+// inventoryObjectTTL is the time during which the networks, folders and
+// resource pools found for the drift detection are reused, so that the
+// inventory is not searched again on every reconcile of every VM.
+const inventoryObjectTTL = 10 * time.Minute
+
+// inventoryObjects holds the inventoryObjects found for the drift detection,
+// keyed by the server, user, datacenter, type and name of the object.
+var inventoryObjects sync.Map
+
+// inventoryObject is a network, folder or resource pool found in the
+// inventory. Only its reference is kept, as the objects are bound to the
+// client of the session which found them.
+type inventoryObject struct {
+	ref     types.ManagedObjectReference
+	path    string
+	backing types.BaseVirtualDeviceBackingInfo
+	expires time.Time
+}
+
+// resolveInventoryObject returns the cached inventory object of the given
+// type and name, or finds it with the given func. The objects visible to a
+// user depend on its privileges, so they are cached per user.
+func resolveInventoryObject(virtualMachineCtx *virtualMachineContext, kind, name string, find func() (inventoryObject, error)) (inventoryObject, error) {
+	spec := &virtualMachineCtx.VSphereVM.Spec
+	user := ""
+	if u := virtualMachineCtx.Session.Client.URL().User; u != nil {
+		user = u.Username()
+	}
+	key := strings.Join([]string{spec.Server, user, spec.Datacenter, kind, name}, "#")
+	if obj, ok := inventoryObjects.Load(key); ok {
+		if obj := obj.(inventoryObject); time.Now().Before(obj.expires) {
+			return obj, nil
+		}
+	}
+	obj, err := find()
+	if err != nil {
+		return inventoryObject{}, err
+	}
+	obj.expires = time.Now().Add(inventoryObjectTTL)
+	inventoryObjects.Store(key, obj)
+	return obj, nil
+}
+
+// sameNetwork returns true if the backing of a network device is attached to
+// the network of the expected backing. Backings of other types, e.g. of
+// networks unknown to vCenter, are not compared.
+func sameNetwork(actual, expected types.BaseVirtualDeviceBackingInfo) bool {
+	switch expected := expected.(type) {
+	case *types.VirtualEthernetCardNetworkBackingInfo:
+		actual, ok := actual.(*types.VirtualEthernetCardNetworkBackingInfo)
+		return ok && actual.DeviceName == expected.DeviceName
+	case *types.VirtualEthernetCardDistributedVirtualPortBackingInfo:
+		actual, ok := actual.(*types.VirtualEthernetCardDistributedVirtualPortBackingInfo)
+		return ok && actual.Port.SwitchUuid == expected.Port.SwitchUuid && actual.Port.PortgroupKey == expected.Port.PortgroupKey
+	case *types.VirtualEthernetCardOpaqueNetworkBackingInfo:
+		actual, ok := actual.(*types.VirtualEthernetCardOpaqueNetworkBackingInfo)
+		return ok && actual.OpaqueNetworkId == expected.OpaqueNetworkId && actual.OpaqueNetworkType == expected.OpaqueNetworkType
+	default:
+		return true
+	}
+}
@@ -0,0 +1,388 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package events mirrors the vCenter events of the virtual machines of
+// VSphereVMs into Kubernetes events on the VSphereVMs and their
+// VSphereMachines, so that changes made directly in vCenter, e.g. migrations,
+// reconfigurations or power operations, are recorded next to the Kubernetes
+// objects. Each vCenter is watched by a single EventHistoryCollector per
+// vCenter user.
+package events
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/event"
+	"github.com/vmware/govmomi/property"
+	govmomisession "github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
+)
+
+var (
+	// EventTypes are the types of the vCenter events which are mirrored.
+	EventTypes = []string{
+		"DrsVmMigratedEvent",
+		"VmGuestRebootEvent",
+		"VmGuestShutdownEvent",
+		"VmMigratedEvent",
+		"VmPoweredOffEvent",
+		"VmPoweredOnEvent",
+		"VmReconfiguredEvent",
+		"VmRelocatedEvent",
+		"VmRemovedEvent",
+		"VmRenamedEvent",
+		"VmResettingEvent",
+		"VmSuspendedEvent",
+	}
+
+	// warningEventTypes are the types of the events which are mirrored as
+	// warnings, as the machine stops running.
+	warningEventTypes = map[string]bool{
+		"VmGuestShutdownEvent": true,
+		"VmPoweredOffEvent":    true,
+		"VmRemovedEvent":       true,
+		"VmSuspendedEvent":     true,
+	}
+
+	// collectors are the collectors of each vCenter server and user.
+	collectors sync.Map
+
+	// maxWaitSeconds is the maximum duration of a WaitForUpdatesEx call.
+	maxWaitSeconds int32 = 60
+
+	// pageSize is the maximum number of events read with a single
+	// ReadNextEvents call.
+	pageSize int32 = 100
+)
+
+// Watch records the vCenter events of the virtual machine with the given
+// reference on the VSphereVM of the context and on its VSphereMachine. The
+// events of the vCenter of the VSphereVM are collected with the session of
+// the context until ctx is done or the session becomes invalid, and collected
+// again with the session of the next call.
+func Watch(ctx context.Context, vmCtx *capvcontext.VMContext, ref types.ManagedObjectReference) error {
+	return getOrCreate(vmCtx).watch(ctx, vmCtx.Session, ref, newOwner(vmCtx.VSphereVM))
+}
+
+// Unwatch stops recording the events of the virtual machine of the VSphereVM
+// of the context.
+func Unwatch(vmCtx *capvcontext.VMContext) {
+	// The session of the context may not be the one the virtual machine
+	// was watched with, e.g. after the credentials were rotated.
+	collectors.Range(func(_, val interface{}) bool {
+		val.(*collector).unwatch(vmCtx.VSphereVM)
+		return true
+	})
+}
+
+// getOrCreate returns the collector of the vCenter of the VSphereVM and the
+// user of the session of the context. The events visible to a user depend
+// on its privileges, and the events of the user are not recorded.
+func getOrCreate(vmCtx *capvcontext.VMContext) *collector {
+	server := vmCtx.VSphereVM.Spec.Server
+	user := ""
+	if u := vmCtx.Session.Client.URL().User; u != nil {
+		user = u.Username()
+	}
+	key := server + "#" + user
+	if val, ok := collectors.Load(key); ok {
+		return val.(*collector)
+	}
+	val, _ := collectors.LoadOrStore(key, &collector{
+		recorder: vmCtx.ControllerContext.Recorder,
+		logger:   vmCtx.ControllerContext.Logger.WithName("events").WithValues("server", server, "user", user),
+		owners:   map[types.ManagedObjectReference]*owner{},
+	})
+	return val.(*collector)
+}
+
+// owner are the objects on which the events of a virtual machine are
+// recorded.
+type owner struct {
+	vsphereVM *infrav1.VSphereVM
+	// vsphereMachine is nil for the VSphereVMs of load balancers, which are
+	// owned by their VSphereCluster.
+	vsphereMachine *infrav1.VSphereMachine
+}
+
+func newOwner(vsphereVM *infrav1.VSphereVM) *owner {
+	o := &owner{vsphereVM: vsphereVM.DeepCopy()}
+	for _, ref := range vsphereVM.OwnerReferences {
+		if ref.Kind == "VSphereMachine" && strings.HasPrefix(ref.APIVersion, infrav1.GroupVersion.Group+"/") {
+			o.vsphereMachine = &infrav1.VSphereMachine{}
+			o.vsphereMachine.Namespace = vsphereVM.Namespace
+			o.vsphereMachine.Name = ref.Name
+			o.vsphereMachine.UID = ref.UID
+			break
+		}
+	}
+	return o
+}
+
+func (o *owner) objects() []runtime.Object {
+	if o.vsphereMachine == nil {
+		return []runtime.Object{o.vsphereVM}
+	}
+	return []runtime.Object{o.vsphereVM, o.vsphereMachine}
+}
+
+// collector records the events of the virtual machines of a vCenter which
+// are read from an EventHistoryCollector.
+type collector struct {
+	recorder record.Recorder
+	logger   logr.Logger
+
+	mu     sync.Mutex
+	owners map[types.ManagedObjectReference]*owner
+	// running is true while the events of the vCenter are collected.
+	running bool
+
+	// lastKey and lastTime are the key and the creation time of the last
+	// event which was read, from which the events are read again when the
+	// collector is restarted. They are only accessed by the reader while
+	// running.
+	lastKey  int32
+	lastTime time.Time
+}
+
+func (c *collector) watch(ctx context.Context, s *session.Session, ref types.ManagedObjectReference, o *owner) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The owner is replaced to record the events on its latest version.
+	c.owners[ref] = o
+	if c.running {
+		return nil
+	}
+	return c.start(ctx, s.Client.Client)
+}
+
+func (c *collector) unwatch(vsphereVM *infrav1.VSphereVM) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for ref, o := range c.owners {
+		if o.vsphereVM.Namespace == vsphereVM.Namespace && o.vsphereVM.Name == vsphereVM.Name {
+			delete(c.owners, ref)
+		}
+	}
+}
+
+// start creates the EventHistoryCollector of the vCenter and reads its
+// events in the background. It must be called with the lock held.
+func (c *collector) start(ctx context.Context, client *vim25.Client) error {
+	userSession, err := govmomisession.NewManager(client).UserSession(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to get user session")
+	}
+	user := ""
+	if userSession != nil {
+		user = userSession.UserName
+	}
+
+	filter := types.EventFilterSpec{
+		EventTypeId: EventTypes,
+	}
+	// When the collector is restarted, the events which occurred since the
+	// last event which was read are read, the ones read already are skipped
+	// by key.
+	resume := c.lastKey > 0
+	if resume {
+		beginTime := c.lastTime
+		filter.Time = &types.EventFilterSpecByTime{BeginTime: &beginTime}
+	}
+	hc, err := event.NewManager(client).CreateCollectorForEvents(ctx, filter)
+	if err != nil {
+		return errors.Wrap(err, "unable to create event history collector")
+	}
+	if resume {
+		if err := hc.Rewind(ctx); err != nil {
+			_ = hc.Destroy(context.Background())
+			return errors.Wrap(err, "unable to rewind event history collector")
+		}
+	} else {
+		// The events which occurred before the collector was first created
+		// are skipped.
+		latestPage, err := hc.LatestPage(ctx)
+		if err != nil {
+			_ = hc.Destroy(context.Background())
+			return errors.Wrap(err, "unable to read latest events")
+		}
+		if err := hc.Reset(ctx); err != nil {
+			_ = hc.Destroy(context.Background())
+			return errors.Wrap(err, "unable to reset event history collector")
+		}
+		for _, e := range latestPage {
+			if ev := e.GetEvent(); ev.Key > c.lastKey {
+				c.lastKey, c.lastTime = ev.Key, ev.CreatedTime
+			}
+		}
+	}
+	c.running = true
+	c.logger.Info("collecting vCenter events", "lastKey", c.lastKey)
+
+	r := &reader{collector: c, client: client, hc: hc, user: user}
+	// The events are not part of the reconcile which started the collector.
+	go r.run(tracing.Detach(ctx))
+	return nil
+}
+
+// reader reads the events of an EventHistoryCollector.
+type reader struct {
+	*collector
+	client *vim25.Client
+	hc     *event.HistoryCollector
+
+	// user is the user of the session, whose events are not recorded as
+	// they are caused by CAPV.
+	user string
+}
+
+// run reads the events until ctx is done or the session becomes invalid.
+func (r *reader) run(ctx context.Context) {
+	err := r.waitForEvents(ctx)
+	if err != nil {
+		r.logger.Error(err, "stopped collecting vCenter events")
+	} else {
+		r.logger.Info("stopped collecting vCenter events")
+	}
+
+	r.mu.Lock()
+	r.running = false
+	r.mu.Unlock()
+	// Use the background context, as ctx may be done.
+	_ = r.hc.Destroy(context.Background())
+}
+
+// waitForEvents reads the new events whenever the latest page of the
+// EventHistoryCollector changes.
+func (r *reader) waitForEvents(ctx context.Context) error {
+	pc, err := property.DefaultCollector(r.client).Create(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to create property collector")
+	}
+	defer func() {
+		_ = pc.Destroy(context.Background())
+	}()
+
+	filter := new(property.WaitFilter)
+	filter.Add(r.hc.Reference(), r.hc.Reference().Type, []string{"latestPage"})
+	if err := pc.CreateFilter(ctx, filter.CreateFilter); err != nil {
+		return errors.Wrap(err, "unable to create property filter")
+	}
+
+	// As for the watcher, the wait is bounded so that it ends shortly after
+	// ctx is done.
+	opts := &types.WaitOptions{MaxWaitSeconds: &maxWaitSeconds}
+	version := ""
+	for ctx.Err() == nil {
+		set, err := pc.WaitForUpdates(ctx, version, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "unable to wait for updates")
+		}
+		// MaxWaitSeconds was exceeded without updates.
+		if set == nil {
+			continue
+		}
+		version = set.Version
+		if err := r.readEvents(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readEvents reads the events which occurred since the last read.
+func (r *reader) readEvents(ctx context.Context) error {
+	for {
+		events, err := r.hc.ReadNextEvents(ctx, pageSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "unable to read events")
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		for _, e := range events {
+			// The collector may return events which were already read, e.g.
+			// the latest page after it was reset.
+			if ev := e.GetEvent(); ev.Key > r.lastKey {
+				r.lastKey, r.lastTime = ev.Key, ev.CreatedTime
+				r.record(e)
+			}
+		}
+	}
+}
+
+// record records the event on the owners of its virtual machine, if any.
+func (r *reader) record(e types.BaseEvent) {
+	ev := e.GetEvent()
+	if ev.Vm == nil || (r.user != "" && ev.UserName == r.user) {
+		return
+	}
+	r.mu.Lock()
+	o, ok := r.owners[ev.Vm.Vm]
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	eventType := typeName(e)
+	reason := strings.TrimSuffix(eventType, "Event")
+	message := ev.FullFormattedMessage
+	if ev.UserName != "" {
+		message = fmt.Sprintf("%s (vCenter user %s)", message, ev.UserName)
+	}
+	r.logger.V(4).Info("recording vCenter event", "type", eventType, "key", ev.Key,
+		"VSphereVM", o.vsphereVM.Namespace+"/"+o.vsphereVM.Name)
+	for _, obj := range o.objects() {
+		if warningEventTypes[eventType] {
+			r.recorder.Warn(obj, reason, message)
+		} else {
+			r.recorder.Event(obj, reason, message)
+		}
+	}
+}
+
+// typeName returns the name of the type of a vCenter event, e.g.
+// VmMigratedEvent.
+func typeName(e types.BaseEvent) string {
+	t := reflect.TypeOf(e)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientrecord "k8s.io/client-go/tools/record"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/record"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestWatch(t *testing.T) {
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("unable to create simulator: %s", err)
+	}
+	defer simr.Destroy()
+
+	// The collector is stopped before the simulator, which waits for the
+	// pending WaitForUpdatesEx call.
+	defer func(seconds int32) { maxWaitSeconds = seconds }(maxWaitSeconds)
+	maxWaitSeconds = 1
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fakeRecorder := clientrecord.NewFakeRecorder(1024)
+	controllerCtx := fake.NewControllerContext(fake.NewControllerManagerContext())
+	controllerCtx.Recorder = record.New(fakeRecorder)
+	vmCtx := fake.NewVMContext(ctx, controllerCtx)
+	vmCtx.VSphereVM.Spec.Server = simr.ServerURL().Host
+	vmCtx.VSphereVM.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: infrav1.GroupVersion.String(),
+		Kind:       "VSphereMachine",
+		Name:       "machine",
+	}}
+
+	vmCtx.Session, err = session.GetOrCreate(ctx,
+		session.NewParams().
+			WithServer(vmCtx.VSphereVM.Spec.Server).
+			WithUserInfo(simr.Username(), simr.Password()).
+			WithDatacenter("*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The changes made in vCenter by other users are recorded.
+	u := simr.ServerURL()
+	u.User = url.UserPassword("operator@vsphere.local", "password")
+	operator, err := govmomi.NewClient(ctx, u, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = operator.Logout(context.Background()) }()
+
+	simVM := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine)
+	vm := object.NewVirtualMachine(vmCtx.Session.Client.Client, simVM.Reference())
+	operatorVM := object.NewVirtualMachine(operator.Client, simVM.Reference())
+
+	watchCtx, watchCancel := context.WithCancel(ctx)
+	if err := Watch(watchCtx, vmCtx, vm.Reference()); err != nil {
+		t.Fatalf("unable to watch VM events: %s", err)
+	}
+	// The events of CAPV are not recorded.
+	powerOff(ctx, t, vm)
+	expectNoEvent(t, fakeRecorder.Events)
+	powerOn(ctx, t, vm)
+	expectNoEvent(t, fakeRecorder.Events)
+
+	powerOff(ctx, t, operatorVM)
+	// The event is recorded on both the VSphereVM and the VSphereMachine.
+	expectEvent(t, fakeRecorder.Events, "Warning VmPoweredOff")
+	expectEvent(t, fakeRecorder.Events, "Warning VmPoweredOff")
+	expectNoEvent(t, fakeRecorder.Events)
+
+	// The events which occurred while the collector was stopped are recorded
+	// once it is restarted.
+	watchCancel()
+	waitForStop(t, getOrCreate(vmCtx))
+	powerOn(ctx, t, operatorVM)
+	if err := Watch(ctx, vmCtx, vm.Reference()); err != nil {
+		t.Fatalf("unable to watch VM events: %s", err)
+	}
+	expectEvent(t, fakeRecorder.Events, "Normal VmPoweredOn")
+	expectEvent(t, fakeRecorder.Events, "Normal VmPoweredOn")
+	expectNoEvent(t, fakeRecorder.Events)
+
+	Unwatch(vmCtx)
+	powerOff(ctx, t, operatorVM)
+	expectNoEvent(t, fakeRecorder.Events)
+}
+
+func waitForStop(t *testing.T, c *collector) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		c.mu.Lock()
+		running := c.running
+		c.mu.Unlock()
+		if !running {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatal("Expected the collector to stop")
+}
+
+func powerOff(ctx context.Context, t *testing.T, vm *object.VirtualMachine) {
+	t.Helper()
+	task, err := vm.PowerOff(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := task.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func powerOn(ctx context.Context, t *testing.T, vm *object.VirtualMachine) {
+	t.Helper()
+	task, err := vm.PowerOn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := task.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectEvent(t *testing.T, events <-chan string, prefix string) {
+	t.Helper()
+	select {
+	case e := <-events:
+		if !strings.HasPrefix(e, prefix) {
+			t.Errorf("Expected an event starting with %q, got %q", prefix, e)
+		}
+		if !strings.Contains(e, "operator@vsphere.local") {
+			t.Errorf("Expected the event %q to contain the vCenter user", e)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Expected an event starting with %q", prefix)
+	}
+}
+
+func expectNoEvent(t *testing.T, events <-chan string) {
+	t.Helper()
+	select {
+	case e := <-events:
+		t.Fatalf("Expected no event, got %q", e)
+	case <-time.After(2 * time.Second):
+	}
+}
@@ -27,12 +27,25 @@ import (
 type Config []types.BaseOptionValue
 
 const (
+	guestInfoPrefix            = "guestinfo."
 	guestInfoIgnitionData      = "guestinfo.ignition.config.data"
 	guestInfoIgnitionEncoding  = "guestinfo.ignition.config.data.encoding"
 	guestInfoCloudInitData     = "guestinfo.userdata"
 	guestInfoCloudInitEncoding = "guestinfo.userdata.encoding"
+	guestInfoBootstrapStatus   = "guestinfo.cluster-api.bootstrap-status"
 )
 
+// bootstrapStatusSuccess is the value of the guestInfoBootstrapStatus key set
+// by the guest once the bootstrap succeeded.
+const bootstrapStatusSuccess = "success"
+
+var userDataKeys = []string{
+	guestInfoIgnitionData,
+	guestInfoIgnitionEncoding,
+	guestInfoCloudInitData,
+	guestInfoCloudInitEncoding,
+}
+
 // SetCustomVMXKeys sets the custom VMX keys as
 // OptionValues in extraConfig.
 func (e *Config) SetCustomVMXKeys(customKeys map[string]string) error {
@@ -45,6 +58,17 @@ func (e *Config) SetCustomVMXKeys(customKeys map[string]string) error {
 	return nil
 }
 
+// SetGuestInfo sets the guestinfo variables, whose keys are prefixed with
+// "guestinfo." in extraConfig.
+func (e *Config) SetGuestInfo(guestInfo map[string]string) {
+	for k, v := range guestInfo {
+		*e = append(*e, &types.OptionValue{
+			Key:   guestInfoPrefix + k,
+			Value: v,
+		})
+	}
+}
+
 // SetCloudInitUserData sets the cloud init user data at the key
 // "guestinfo.userdata" as a base64-encoded string.
 func (e *Config) SetCloudInitUserData(data []byte) {
@@ -72,6 +96,59 @@ func (e *Config) SetIgnitionUserData(data []byte) {
 	e.setUserData(guestInfoIgnitionData, guestInfoIgnitionEncoding, data)
 }
 
+// ClearUserData blanks the cloud init and ignition user data, which removes
+// their keys from the extra config of a VM when it is reconfigured.
+func (e *Config) ClearUserData() {
+	for _, key := range userDataKeys {
+		*e = append(*e, &types.OptionValue{
+			Key:   key,
+			Value: "",
+		})
+	}
+}
+
+// HasUserData returns true if the given extra config of a VM contains cloud
+// init or ignition user data.
+func HasUserData(extraConfig []types.BaseOptionValue) bool {
+	for _, option := range extraConfig {
+		value := option.GetOptionValue()
+		if value.Key != guestInfoCloudInitData && value.Key != guestInfoIgnitionData {
+			continue
+		}
+		if s, ok := value.Value.(string); ok && s != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// GuestInfo returns the value of the guestinfo variable with the given key,
+// without the "guestinfo." prefix, in the given extra config of a VM.
+func GuestInfo(extraConfig []types.BaseOptionValue, key string) string {
+	for _, option := range extraConfig {
+		value := option.GetOptionValue()
+		if value.Key != guestInfoPrefix+key {
+			continue
+		}
+		if s, ok := value.Value.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// BootstrapSucceeded returns true if the guest reported the success of the
+// bootstrap in the given extra config of a VM.
+func BootstrapSucceeded(extraConfig []types.BaseOptionValue) bool {
+	for _, option := range extraConfig {
+		value := option.GetOptionValue()
+		if value.Key == guestInfoBootstrapStatus {
+			return value.Value == bootstrapStatusSuccess
+		}
+	}
+	return false
+}
+
 // setUserData sets the user data at the provided key
 // as a base64-encoded string.
 func (e *Config) setUserData(userdataKey, encodingKey string, data []byte) {
@@ -77,6 +77,82 @@ var _ = Describe("Config_SetCloudInitMetadata", func() {
 	)
 })
 
+var _ = Describe("Config_ClearUserData", func() {
+	Context("we clear the user data of a VM with cloud init user data", func() {
+		var config Config
+		config.SetCloudInitUserData([]byte("some sample data"))
+		config.SetCloudInitMetadata([]byte("some sample metadata"))
+
+		It("reports the user data", func() {
+			Expect(HasUserData(config)).To(BeTrue())
+		})
+
+		It("blanks the user data keys", func() {
+			var cleared Config
+			cleared.ClearUserData()
+
+			Expect(cleared).To(ContainElement(&types.OptionValue{
+				Key:   "guestinfo.userdata",
+				Value: "",
+			}))
+			Expect(cleared).To(ContainElement(&types.OptionValue{
+				Key:   "guestinfo.ignition.config.data",
+				Value: "",
+			}))
+			Expect(HasUserData(cleared)).To(BeFalse())
+		})
+
+		It("keeps the metadata", func() {
+			var cleared Config
+			cleared.ClearUserData()
+
+			for _, option := range cleared {
+				Expect(option.GetOptionValue().Key).ToNot(HavePrefix("guestinfo.metadata"))
+			}
+		})
+	})
+})
+
+var _ = Describe("BootstrapSucceeded", func() {
+	Context("we check the bootstrap status reported by the guest", func() {
+		It("reports the success of the bootstrap", func() {
+			Expect(BootstrapSucceeded([]types.BaseOptionValue{&types.OptionValue{
+				Key:   "guestinfo.cluster-api.bootstrap-status",
+				Value: "success",
+			}})).To(BeTrue())
+		})
+
+		It("does not report a bootstrap in progress", func() {
+			Expect(BootstrapSucceeded(nil)).To(BeFalse())
+			Expect(BootstrapSucceeded([]types.BaseOptionValue{&types.OptionValue{
+				Key:   "guestinfo.cluster-api.bootstrap-status",
+				Value: "running",
+			}})).To(BeFalse())
+		})
+	})
+})
+
+var _ = Describe("Config_SetGuestInfo", func() {
+	Context("we set guestinfo variables in the config", func() {
+		var config Config
+		config.SetGuestInfo(map[string]string{
+			"haproxy.cfg": "c29tZSBjb25maWc=",
+		})
+
+		It("prefixes the keys with guestinfo", func() {
+			Expect(config).To(ConsistOf(&types.OptionValue{
+				Key:   "guestinfo.haproxy.cfg",
+				Value: "c29tZSBjb25maWc=",
+			}))
+		})
+
+		It("returns the value of the variables", func() {
+			Expect(GuestInfo(config, "haproxy.cfg")).To(Equal("c29tZSBjb25maWc="))
+			Expect(GuestInfo(config, "metadata")).To(BeEmpty())
+		})
+	})
+})
+
 func base64Encode(s string) string {
 	return base64.StdEncoding.EncodeToString([]byte(s))
 }
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package faults classifies the faults of failed vCenter tasks into terminal
+// faults, which fail again when the task is retried, and transient ones.
+package faults
+
+import (
+	"reflect"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// Class is the class of a task fault.
+type Class string
+
+const (
+	// Terminal faults are caused by the configuration of the machine or of
+	// vCenter, and require a manual intervention or a new machine.
+	Terminal Class = "terminal"
+
+	// Transient faults may not occur when the task is retried, e.g. because
+	// the host or the datastore was temporarily unavailable.
+	Transient Class = "transient"
+)
+
+// Classify returns the class of the fault of a failed task. Unknown faults
+// are transient, so that a machine is only failed for faults which are known
+// to never succeed on retry.
+func Classify(fault *types.LocalizedMethodFault) Class {
+	if fault == nil {
+		return Transient
+	}
+	switch fault.Fault.(type) {
+	case *types.InvalidDatastore,
+		*types.InvalidDatastorePath,
+		*types.FileNotFound,
+		*types.InvalidLicense,
+		*types.LicenseEntityNotFound,
+		*types.LicenseExpired,
+		*types.LicenseRestricted,
+		*types.NotEnoughLicenses,
+		*types.InvalidArgument,
+		*types.InvalidName,
+		*types.InvalidController,
+		*types.InvalidDeviceSpec,
+		*types.InvalidDiskFormat,
+		*types.DeviceUnsupportedForVmVersion,
+		*types.UnsupportedGuest,
+		*types.NotSupported:
+		return Terminal
+	default:
+		return Transient
+	}
+}
+
+// Name returns the name of the type of the fault of a failed task, e.g.
+// InvalidDatastorePath, or Unknown if the task has no fault.
+func Name(fault *types.LocalizedMethodFault) string {
+	if fault == nil || fault.Fault == nil {
+		return "Unknown"
+	}
+	t := reflect.TypeOf(fault.Fault)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
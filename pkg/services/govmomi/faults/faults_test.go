@@ -0,0 +1,74 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faults
+
+import (
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestClassify(t *testing.T) {
+	testCases := []struct {
+		name          string
+		fault         *types.LocalizedMethodFault
+		expectedClass Class
+		expectedName  string
+	}{
+		{
+			name:          "when the task has no fault",
+			expectedClass: Transient,
+			expectedName:  "Unknown",
+		},
+		{
+			name:          "when the datastore path is invalid",
+			fault:         &types.LocalizedMethodFault{Fault: &types.InvalidDatastorePath{DatastorePath: "[ds0] vm/vm.vmx"}},
+			expectedClass: Terminal,
+			expectedName:  "InvalidDatastorePath",
+		},
+		{
+			name:          "when the license expired",
+			fault:         &types.LocalizedMethodFault{Fault: &types.LicenseExpired{}},
+			expectedClass: Terminal,
+			expectedName:  "LicenseExpired",
+		},
+		{
+			name:          "when the host is not reachable",
+			fault:         &types.LocalizedMethodFault{Fault: &types.HostCommunication{}},
+			expectedClass: Transient,
+			expectedName:  "HostCommunication",
+		},
+		{
+			name:          "when the datastore is full",
+			fault:         &types.LocalizedMethodFault{Fault: &types.NoDiskSpace{}},
+			expectedClass: Transient,
+			expectedName:  "NoDiskSpace",
+		},
+	}
+
+	for _, test := range testCases {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			if class := Classify(tc.fault); class != tc.expectedClass {
+				t.Errorf("Expected class %q, got %q", tc.expectedClass, class)
+			}
+			if name := Name(tc.fault); name != tc.expectedName {
+				t.Errorf("Expected name %q, got %q", tc.expectedName, name)
+			}
+		})
+	}
+}
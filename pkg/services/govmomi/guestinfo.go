@@ -0,0 +1,70 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/extra"
+)
+
+// reconcileGuestInfo keeps the guestinfo variables of the VM in sync with the
+// GuestInfo of the VSphereVM, which passes configuration changing over the
+// lifetime of the VM to the guest.
+func (vms *VMService) reconcileGuestInfo(ctx context.Context, virtualMachineCtx *virtualMachineContext) (bool, error) {
+	guestInfo := virtualMachineCtx.VSphereVM.Spec.GuestInfo
+	if len(guestInfo) == 0 {
+		return true, nil
+	}
+
+	virtualMachine, err := virtualMachineCtx.Properties(ctx)
+	if err != nil {
+		return false, errors.Wrapf(err, "error getting extra config of VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+	if virtualMachine.Config == nil {
+		return false, errors.Errorf("error getting extra config of VM %s: config is not available", virtualMachineCtx.VSphereVM.Name)
+	}
+
+	changed := map[string]string{}
+	for key, value := range guestInfo {
+		if extra.GuestInfo(virtualMachine.Config.ExtraConfig, key) != value {
+			changed[key] = value
+		}
+	}
+	if len(changed) == 0 {
+		return true, nil
+	}
+
+	keys := make([]string, 0, len(changed))
+	for key := range changed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var extraConfig extra.Config
+	extraConfig.SetGuestInfo(changed)
+	virtualMachineCtx.Logger.Info("updating guestinfo", "keys", keys)
+	task, err := virtualMachineCtx.Obj.Reconfigure(ctx, types.VirtualMachineConfigSpec{ExtraConfig: extraConfig})
+	if err != nil {
+		return false, errors.Wrapf(err, "error triggering reconfigure op to update guestinfo of VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+	virtualMachineCtx.VSphereVM.Status.TaskRef = task.Reference().Value
+	return false, nil
+}
@@ -76,26 +76,26 @@ func parseGateway(ipamAddress *ipamv1.IPAddress, addressWithPrefix netip.Prefix,
 
 	if gatewayAddr.Is4() {
 		if areGatewaysMismatched(ipamDeviceConfig.NetworkSpecGateway4, ipamAddress.Spec.Gateway) {
-			return nil, fmt.Errorf("the IPv4 Gateway for IPAddress %s does not match the Gateway4 already configured on device (index %d)",
+			return nil, fmt.Errorf("the IPv4 Gateway for IPAddress %s does not match the Gateway4 already configured on %s",
 				ipamAddress.Name,
-				ipamDeviceConfig.DeviceIndex,
+				ipamDeviceConfig,
 			)
 		}
 		if areGatewaysMismatched(ipamDeviceConfig.IPAMConfigGateway4, ipamAddress.Spec.Gateway) {
-			return nil, fmt.Errorf("the IPv4 IPAddresses assigned to the same device (index %d) do not have the same gateway",
-				ipamDeviceConfig.DeviceIndex,
+			return nil, fmt.Errorf("the IPv4 IPAddresses assigned to the same %s do not have the same gateway",
+				ipamDeviceConfig,
 			)
 		}
 	} else {
 		if areGatewaysMismatched(ipamDeviceConfig.NetworkSpecGateway6, ipamAddress.Spec.Gateway) {
-			return nil, fmt.Errorf("the IPv6 Gateway for IPAddress %s does not match the Gateway6 already configured on device (index %d)",
+			return nil, fmt.Errorf("the IPv6 Gateway for IPAddress %s does not match the Gateway6 already configured on %s",
 				ipamAddress.Name,
-				ipamDeviceConfig.DeviceIndex,
+				ipamDeviceConfig,
 			)
 		}
 		if areGatewaysMismatched(ipamDeviceConfig.IPAMConfigGateway6, ipamAddress.Spec.Gateway) {
-			return nil, fmt.Errorf("the IPv6 IPAddresses assigned to the same device (index %d) do not have the same gateway",
-				ipamDeviceConfig.DeviceIndex,
+			return nil, fmt.Errorf("the IPv6 IPAddresses assigned to the same %s do not have the same gateway",
+				ipamDeviceConfig,
 			)
 		}
 	}
@@ -31,13 +31,17 @@ import (
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
 )
 
 // ipamDeviceConfig aids and holds state for the process
 // of parsing IPAM addresses for a given device.
 type ipamDeviceConfig struct {
-	DeviceIndex         int
+	DeviceIndex int
+	// InterfaceName is the name of a bond or VLAN interface, it is empty
+	// for network devices.
+	InterfaceName       string
 	IPAMAddresses       []*ipamv1.IPAddress
 	MACAddress          string
 	NetworkSpecGateway4 string
@@ -46,8 +50,30 @@ type ipamDeviceConfig struct {
 	IPAMConfigGateway6  string
 }
 
+// stateKey returns the key of the device in the IPAM state, the MAC address
+// of a network device or the name of a bond or VLAN interface.
+func (c ipamDeviceConfig) stateKey() string {
+	if c.InterfaceName != "" {
+		return c.InterfaceName
+	}
+	return c.MACAddress
+}
+
+// String returns the description of the device used in error messages.
+func (c ipamDeviceConfig) String() string {
+	if c.InterfaceName != "" {
+		return fmt.Sprintf("interface %s", c.InterfaceName)
+	}
+	return fmt.Sprintf("device (index %d)", c.DeviceIndex)
+}
+
 // BuildState checks if IPAddressClaims are satisfied and returns a map of NetworkDeviceSpec.
+// The state of network devices is keyed by their MAC address, the state of
+// bonds and VLANs by their name.
 func BuildState(ctx context.Context, vmCtx capvcontext.VMContext, networkStatus []infrav1.NetworkStatus) (map[string]infrav1.NetworkDeviceSpec, error) {
+	ctx, span := tracing.Start(ctx, "ipam.BuildState")
+	defer span.End()
+
 	state := map[string]infrav1.NetworkDeviceSpec{}
 
 	ipamDeviceConfigs, err := buildIPAMDeviceConfigs(ctx, vmCtx, networkStatus)
@@ -92,7 +118,7 @@ func BuildState(ctx context.Context, vmCtx capvcontext.VMContext, networkStatus
 		}
 
 		if len(addressWithPrefixes) > 0 {
-			state[ipamDeviceConfig.MACAddress] = infrav1.NetworkDeviceSpec{
+			state[ipamDeviceConfig.stateKey()] = infrav1.NetworkDeviceSpec{
 				IPAddrs:  prefixesAsStrings(addressWithPrefixes),
 				Gateway4: ipamDeviceConfig.IPAMConfigGateway4,
 				Gateway6: ipamDeviceConfig.IPAMConfigGateway6,
@@ -113,7 +139,7 @@ func BuildState(ctx context.Context, vmCtx capvcontext.VMContext, networkStatus
 
 // buildIPAMDeviceConfigs checks that all the IPAddressClaims have been satisfied.
 // If each IPAddressClaim has an associated IPAddress, a slice of ipamDeviceConfig
-// is returned, one for each device with addressesFromPools.
+// is returned, one for each device, bond and VLAN.
 // If any of the IPAddressClaims do not have an associated IPAddress yet,
 // a custom error is returned.
 func buildIPAMDeviceConfigs(ctx context.Context, vmCtx capvcontext.VMContext, networkStatus []infrav1.NetworkStatus) ([]ipamDeviceConfig, error) {
@@ -137,34 +163,36 @@ func buildIPAMDeviceConfigs(ctx context.Context, vmCtx capvcontext.VMContext, ne
 		for poolRefIdx := range networkSpecDevice.AddressesFromPools {
 			totalClaims++
 			ipAddrClaimName := util.IPAddressClaimName(vmCtx.VSphereVM.Name, ipamDeviceConfig.DeviceIndex, poolRefIdx)
-			ipAddrClaim, err := getIPAddrClaim(ctx, vmCtx, ipAddrClaimName)
+			ipAddr, err := getBoundIPAddr(ctx, vmCtx, ipAddrClaimName)
 			if err != nil {
-				vmCtx.Logger.Error(err, "error fetching IPAddressClaim", "name", ipAddrClaimName)
-				if apierrors.IsNotFound(err) {
-					// it would be odd for this to occur, a findorcreate just happened in a previous step
-					continue
-				}
 				return nil, err
 			}
-
-			vmCtx.Logger.V(5).Info("fetched IPAddressClaim", "name", ipAddrClaimName, "namespace", vmCtx.VSphereVM.Namespace)
-			ipAddrName := ipAddrClaim.Status.AddressRef.Name
-			if ipAddrName == "" {
-				vmCtx.Logger.V(5).Info("IPAddress not yet bound to IPAddressClaim", "name", ipAddrClaimName, "namespace", vmCtx.VSphereVM.Namespace)
-				continue
+			if ipAddr != nil {
+				ipamDeviceConfig.IPAMAddresses = append(ipamDeviceConfig.IPAMAddresses, ipAddr)
+				boundClaims++
 			}
+		}
+		ipamDeviceConfigs = append(ipamDeviceConfigs, ipamDeviceConfig)
+	}
+	for _, iface := range util.NetworkInterfaces(&vmCtx.VSphereVM.Spec.Network) {
+		ipamDeviceConfig := ipamDeviceConfig{
+			InterfaceName:       iface.Name,
+			IPAMAddresses:       []*ipamv1.IPAddress{},
+			NetworkSpecGateway4: iface.Gateway4,
+			NetworkSpecGateway6: iface.Gateway6,
+		}
 
-			ipAddr := &ipamv1.IPAddress{}
-			ipAddrKey := apitypes.NamespacedName{
-				Namespace: vmCtx.VSphereVM.Namespace,
-				Name:      ipAddrName,
-			}
-			if err := vmCtx.Client.Get(ctx, ipAddrKey, ipAddr); err != nil {
-				// because the ref was set on the claim, it is expected this error should not occur
+		for poolRefIdx := range iface.AddressesFromPools {
+			totalClaims++
+			ipAddrClaimName := util.InterfaceIPAddressClaimName(vmCtx.VSphereVM.Name, iface.Name, poolRefIdx)
+			ipAddr, err := getBoundIPAddr(ctx, vmCtx, ipAddrClaimName)
+			if err != nil {
 				return nil, err
 			}
-			ipamDeviceConfig.IPAMAddresses = append(ipamDeviceConfig.IPAMAddresses, ipAddr)
-			boundClaims++
+			if ipAddr != nil {
+				ipamDeviceConfig.IPAMAddresses = append(ipamDeviceConfig.IPAMAddresses, ipAddr)
+				boundClaims++
+			}
 		}
 		ipamDeviceConfigs = append(ipamDeviceConfigs, ipamDeviceConfig)
 	}
@@ -177,6 +205,38 @@ func buildIPAMDeviceConfigs(ctx context.Context, vmCtx capvcontext.VMContext, ne
 	return ipamDeviceConfigs, nil
 }
 
+// getBoundIPAddr fetches the IPAddress bound to the IPAddressClaim with the
+// given name. It returns nil if the claim does not exist or is not bound yet.
+func getBoundIPAddr(ctx context.Context, vmCtx capvcontext.VMContext, ipAddrClaimName string) (*ipamv1.IPAddress, error) {
+	ipAddrClaim, err := getIPAddrClaim(ctx, vmCtx, ipAddrClaimName)
+	if err != nil {
+		vmCtx.Logger.Error(err, "error fetching IPAddressClaim", "name", ipAddrClaimName)
+		if apierrors.IsNotFound(err) {
+			// it would be odd for this to occur, a findorcreate just happened in a previous step
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	vmCtx.Logger.V(5).Info("fetched IPAddressClaim", "name", ipAddrClaimName, "namespace", vmCtx.VSphereVM.Namespace)
+	ipAddrName := ipAddrClaim.Status.AddressRef.Name
+	if ipAddrName == "" {
+		vmCtx.Logger.V(5).Info("IPAddress not yet bound to IPAddressClaim", "name", ipAddrClaimName, "namespace", vmCtx.VSphereVM.Namespace)
+		return nil, nil
+	}
+
+	ipAddr := &ipamv1.IPAddress{}
+	ipAddrKey := apitypes.NamespacedName{
+		Namespace: vmCtx.VSphereVM.Namespace,
+		Name:      ipAddrName,
+	}
+	if err := vmCtx.Client.Get(ctx, ipAddrKey, ipAddr); err != nil {
+		// because the ref was set on the claim, it is expected this error should not occur
+		return nil, err
+	}
+	return ipAddr, nil
+}
+
 // getIPAddrClaim fetches an IPAddressClaim from the api with the given name.
 func getIPAddrClaim(ctx context.Context, vmCtx capvcontext.VMContext, ipAddrClaimName string) (*ipamv1.IPAddressClaim, error) {
 	ipAddrClaim := &ipamv1.IPAddressClaim{}
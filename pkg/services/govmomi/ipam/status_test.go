@@ -438,6 +438,68 @@ func Test_BuildState(t *testing.T) {
 		g.Expect(state).To(gomega.HaveLen(0))
 	})
 
+	t.Run("when a VLAN has a IPAddressPool", func(_ *testing.T) {
+		before()
+		vmCtx.VSphereVM = &infrav1.VSphereVM{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "vsphereVM1",
+				Namespace: "my-namespace",
+			},
+			Spec: infrav1.VSphereVMSpec{
+				VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+					Network: infrav1.NetworkSpec{
+						Devices: []infrav1.NetworkDeviceSpec{
+							{
+								MACAddr: devMAC,
+								DHCP4:   true,
+							},
+						},
+						VLANs: []infrav1.NetworkVLANSpec{
+							{
+								Name: "vlan100",
+								ID:   100,
+								Link: "eth0",
+								NetworkInterfaceSpec: infrav1.NetworkInterfaceSpec{
+									AddressesFromPools: []corev1.TypedLocalObjectReference{
+										{
+											APIGroup: &myAPIGroup,
+											Name:     "my-pool-1",
+											Kind:     "my-pool-kind",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		claim := &ipamv1a1.IPAddressClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      util.InterfaceIPAddressClaimName("vsphereVM1", "vlan100", 0),
+				Namespace: "my-namespace",
+			},
+		}
+		g.Expect(vmCtx.Client.Create(ctx, claim)).NotTo(gomega.HaveOccurred())
+
+		// IP provider has not provided Addresses yet
+		_, err := BuildState(ctx, vmCtx, networkStatus)
+		g.Expect(err).To(gomega.Equal(ErrWaitingForIPAddr))
+
+		// Simulate IP provider reconciling the claim
+		g.Expect(vmCtx.Client.Create(ctx, address1)).NotTo(gomega.HaveOccurred())
+		claim.Status.AddressRef.Name = address1.Name
+		g.Expect(vmCtx.Client.Update(ctx, claim)).NotTo(gomega.HaveOccurred())
+
+		state, err := BuildState(ctx, vmCtx, networkStatus)
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(state).To(gomega.HaveLen(1))
+		g.Expect(state).To(gomega.HaveKey("vlan100"))
+		g.Expect(state["vlan100"].IPAddrs).To(gomega.Equal([]string{"10.0.0.50/24"}))
+		g.Expect(state["vlan100"].Gateway4).To(gomega.Equal("10.0.0.1"))
+	})
+
 	t.Run("when one device has no pool and is DHCP true, and one device has a IPAddressPool", func(_ *testing.T) {
 		before()
 		devMAC0 := "0:0:0:0:a"
@@ -65,6 +65,12 @@ func GetNetworkStatus(
 	if err := pc.RetrieveOne(ctx, moRef, props, &obj); err != nil {
 		return nil, errors.Wrapf(err, "unable to fetch props %v for vm %v", props, moRef)
 	}
+	return NetworkStatusOf(obj)
+}
+
+// NetworkStatusOf returns the network information of a VM from its
+// config.hardware.device and guest.net properties.
+func NetworkStatusOf(obj mo.VirtualMachine) ([]NetworkStatus, error) {
 	if obj.Config == nil {
 		return nil, errors.New("config.hardware.device is nil")
 	}
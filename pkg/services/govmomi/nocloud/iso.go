@@ -0,0 +1,208 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nocloud builds the ISO images read by the cloud-init NoCloud data source.
+package nocloud
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// VolumeLabel is the volume label identifying a NoCloud data source.
+const VolumeLabel = "CIDATA"
+
+const (
+	sectorSize = 2048
+
+	// The sectors of the fixed part of the image. The first 16 sectors are
+	// the unused system area.
+	primaryVolumeDescriptorSector = 16
+	terminatorSector              = 17
+	lPathTableSector              = 18
+	mPathTableSector              = 19
+	rootDirectorySector           = 20
+	firstFileSector               = 21
+)
+
+type file struct {
+	name string
+	data []byte
+}
+
+// ISO returns an ISO 9660 image with the NoCloud volume label containing
+// the user data and the metadata. The network configuration of the
+// metadata, if any, is written to the separate network-config file read by
+// cloud-init.
+func ISO(userData, metadata []byte) ([]byte, error) {
+	files := []file{
+		{name: "user-data", data: userData},
+		{name: "meta-data", data: metadata},
+	}
+
+	var meta map[string]interface{}
+	if err := yaml.Unmarshal(metadata, &meta); err != nil {
+		return nil, errors.Wrap(err, "unable to parse metadata")
+	}
+	if network, ok := meta["network"]; ok {
+		networkConfig, err := yaml.Marshal(map[string]interface{}{"network": network})
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to marshal network config")
+		}
+		files = append(files, file{name: "network-config", data: networkConfig})
+	}
+
+	return writeISO(VolumeLabel, files, time.Now().UTC())
+}
+
+// writeISO returns an ISO 9660 image with the given volume label containing
+// the files in its root directory. Linux reads the identifiers of the files
+// as lower case names without version, which is why Rock Ridge and Joliet
+// extensions are not needed for the file names used by cloud-init.
+func writeISO(label string, files []file, now time.Time) ([]byte, error) {
+	sort.Slice(files, func(i, j int) bool { return identifier(files[i].name) < identifier(files[j].name) })
+
+	root := &bytes.Buffer{}
+	root.Write(directoryRecord([]byte{0}, rootDirectorySector, sectorSize, true, now))
+	root.Write(directoryRecord([]byte{1}, rootDirectorySector, sectorSize, true, now))
+	sector := uint32(firstFileSector)
+	for _, f := range files {
+		root.Write(directoryRecord([]byte(identifier(f.name)), sector, uint32(len(f.data)), false, now))
+		sector += sectors(len(f.data))
+	}
+	if root.Len() > sectorSize {
+		return nil, errors.Errorf("root directory of %d bytes exceeds a sector", root.Len())
+	}
+
+	image := make([]byte, int(sector)*sectorSize)
+	writePrimaryVolumeDescriptor(image[primaryVolumeDescriptorSector*sectorSize:], label, sector, now)
+	terminator := image[terminatorSector*sectorSize:]
+	terminator[0] = 255
+	copy(terminator[1:6], "CD001")
+	terminator[6] = 1
+	writePathTable(image[lPathTableSector*sectorSize:], binary.LittleEndian)
+	writePathTable(image[mPathTableSector*sectorSize:], binary.BigEndian)
+	copy(image[rootDirectorySector*sectorSize:], root.Bytes())
+
+	sector = firstFileSector
+	for _, f := range files {
+		copy(image[int(sector)*sectorSize:], f.data)
+		sector += sectors(len(f.data))
+	}
+	return image, nil
+}
+
+func writePrimaryVolumeDescriptor(b []byte, label string, volumeSize uint32, now time.Time) {
+	b[0] = 1
+	copy(b[1:6], "CD001")
+	b[6] = 1
+	padded(b[8:40], "LINUX")
+	padded(b[40:72], label)
+	putBothEndian32(b[80:], volumeSize)
+	putBothEndian16(b[120:], 1)
+	putBothEndian16(b[124:], 1)
+	putBothEndian16(b[128:], sectorSize)
+	putBothEndian32(b[132:], pathTableSize)
+	binary.LittleEndian.PutUint32(b[140:], lPathTableSector)
+	binary.BigEndian.PutUint32(b[148:], mPathTableSector)
+	copy(b[156:190], directoryRecord([]byte{0}, rootDirectorySector, sectorSize, true, now))
+	padded(b[190:318], "")
+	padded(b[318:446], "")
+	padded(b[446:574], "")
+	padded(b[574:702], "CLUSTER API PROVIDER VSPHERE")
+	padded(b[702:813], "")
+	copy(b[813:830], decimalDateTime(now))
+	copy(b[830:847], decimalDateTime(now))
+	copy(b[847:864], decimalDateTime(time.Time{}))
+	copy(b[864:881], decimalDateTime(time.Time{}))
+	b[881] = 1
+}
+
+// pathTableSize is the size of a path table with the single entry of the
+// root directory.
+const pathTableSize = 10
+
+func writePathTable(b []byte, order binary.ByteOrder) {
+	b[0] = 1
+	order.PutUint32(b[2:], rootDirectorySector)
+	order.PutUint16(b[6:], 1)
+}
+
+func directoryRecord(name []byte, extent, size uint32, directory bool, now time.Time) []byte {
+	length := 33 + len(name)
+	if length%2 == 1 {
+		length++
+	}
+	r := make([]byte, length)
+	r[0] = byte(length)
+	putBothEndian32(r[2:], extent)
+	putBothEndian32(r[10:], size)
+	r[18] = byte(now.Year() - 1900)
+	r[19] = byte(now.Month())
+	r[20] = byte(now.Day())
+	r[21] = byte(now.Hour())
+	r[22] = byte(now.Minute())
+	r[23] = byte(now.Second())
+	if directory {
+		r[25] = 2
+	}
+	putBothEndian16(r[28:], 1)
+	r[32] = byte(len(name))
+	copy(r[33:], name)
+	return r
+}
+
+// identifier returns the ISO 9660 file identifier of a file name.
+func identifier(name string) string {
+	return strings.ToUpper(name) + ";1"
+}
+
+// decimalDateTime returns the date and time format of the volume descriptor,
+// in which the zero time means not specified.
+func decimalDateTime(t time.Time) []byte {
+	if t.IsZero() {
+		return append([]byte(strings.Repeat("0", 16)), 0)
+	}
+	return append([]byte(fmt.Sprintf("%04d%02d%02d%02d%02d%02d00", t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second())), 0)
+}
+
+func padded(b []byte, s string) {
+	copy(b, s)
+	for i := len(s); i < len(b); i++ {
+		b[i] = ' '
+	}
+}
+
+func sectors(size int) uint32 {
+	return uint32((size + sectorSize - 1) / sectorSize)
+}
+
+func putBothEndian16(b []byte, v uint16) {
+	binary.LittleEndian.PutUint16(b, v)
+	binary.BigEndian.PutUint16(b[2:], v)
+}
+
+func putBothEndian32(b []byte, v uint32) {
+	binary.LittleEndian.PutUint32(b, v)
+	binary.BigEndian.PutUint32(b[4:], v)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nocloud
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestISO(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	userData := []byte("#cloud-config\n" + strings.Repeat("# padding\n", 500))
+	metadata := []byte(`
+instance-id: "vm-0"
+local-hostname: "vm-0"
+network:
+  version: 2
+  ethernets:
+    id0:
+      dhcp4: true
+`)
+	image, err := ISO(userData, metadata)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(len(image) % sectorSize).To(gomega.Equal(0))
+
+	pvd := image[primaryVolumeDescriptorSector*sectorSize:]
+	g.Expect(string(pvd[1:6])).To(gomega.Equal("CD001"))
+	g.Expect(strings.TrimRight(string(pvd[40:72]), " ")).To(gomega.Equal(VolumeLabel))
+	g.Expect(binary.LittleEndian.Uint32(pvd[80:])).To(gomega.Equal(uint32(len(image) / sectorSize)))
+
+	files := readRootDirectory(image)
+	g.Expect(files).To(gomega.HaveLen(3))
+	g.Expect(files).To(gomega.HaveKeyWithValue("USER-DATA;1", userData))
+	g.Expect(files).To(gomega.HaveKeyWithValue("META-DATA;1", metadata))
+	g.Expect(string(files["NETWORK-CONFIG;1"])).To(gomega.ContainSubstring("version: 2"))
+}
+
+func TestISOWithoutNetwork(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	image, err := ISO([]byte("#cloud-config\n"), []byte(`instance-id: "vm-0"`))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	files := readRootDirectory(image)
+	g.Expect(files).To(gomega.HaveLen(2))
+	g.Expect(files).ToNot(gomega.HaveKey("NETWORK-CONFIG;1"))
+}
+
+// readRootDirectory returns the files in the root directory of an ISO image
+// by their identifiers.
+func readRootDirectory(image []byte) map[string][]byte {
+	rootRecord := image[primaryVolumeDescriptorSector*sectorSize+156:]
+	root := image[int(binary.LittleEndian.Uint32(rootRecord[2:]))*sectorSize:]
+	files := map[string][]byte{}
+	for offset := 0; root[offset] != 0; offset += int(root[offset]) {
+		record := root[offset:]
+		name := string(record[33 : 33+int(record[32])])
+		if record[25]&2 != 0 {
+			continue
+		}
+		extent := int(binary.LittleEndian.Uint32(record[2:])) * sectorSize
+		size := int(binary.LittleEndian.Uint32(record[10:]))
+		files[name] = image[extent : extent+size]
+	}
+	return files
+}
@@ -31,12 +31,12 @@ import (
 )
 
 func (vms *VMService) getPowerState(ctx context.Context, virtualMachineCtx *virtualMachineContext) (infrav1.VirtualMachinePowerState, error) {
-	powerState, err := virtualMachineCtx.Obj.PowerState(ctx)
+	properties, err := virtualMachineCtx.Properties(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	switch powerState {
+	switch powerState := properties.Runtime.PowerState; powerState {
 	case types.VirtualMachinePowerStatePoweredOn:
 		return infrav1.VirtualMachinePowerStatePoweredOn, nil
 	case types.VirtualMachinePowerStatePoweredOff:
@@ -70,6 +70,29 @@ func (vms *VMService) isSoftPowerOffTimeoutExceeded(vm *infrav1.VSphereVM) bool
 	return timeout.Seconds() > 0 && diff.Seconds() >= timeout.Seconds()
 }
 
+// softPowerOffTimeoutRemaining returns the time until the timeout of the
+// pending soft power off of the trySoft mode is exceeded, or zero if there is
+// no such timeout.
+func (vms *VMService) softPowerOffTimeoutRemaining(vm *infrav1.VSphereVM) time.Duration {
+	if vm.Spec.PowerOffMode != infrav1.VirtualMachinePowerOpModeTrySoft || !conditions.Has(vm, infrav1.GuestSoftPowerOffSucceededCondition) {
+		return 0
+	}
+	timeout := infrav1.GuestSoftPowerOffDefaultTimeout
+	if vm.Spec.GuestSoftPowerOffTimeout != nil {
+		timeout = vm.Spec.GuestSoftPowerOffTimeout.Duration
+	}
+	if timeout <= 0 {
+		return 0
+	}
+	timeSoftPowerOff := conditions.GetLastTransitionTime(vm, infrav1.GuestSoftPowerOffSucceededCondition)
+	remaining := timeout - time.Since(timeSoftPowerOff.Time)
+	if remaining < time.Second {
+		// The timeout is exceeded by the next reconcile.
+		return time.Second
+	}
+	return remaining
+}
+
 // triggerSoftPowerOff tries to trigger a soft power off for a VM to shut down the guest.
 // It returns true if the soft power off operation is pending.
 func (vms *VMService) triggerSoftPowerOff(ctx context.Context, virtualMachineCtx *virtualMachineContext) (bool, error) {
@@ -0,0 +1,376 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package privileges verifies that the user of a vCenter session has the
+// privileges required to manage virtual machines on inventory objects.
+package privileges
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/utils/pointer"
+)
+
+// The privileges required on each kind of inventory object. Privileges
+// granted on a folder propagate to the virtual machines created in it.
+// Privileges which are not bound to an inventory object, e.g. the ones of
+// Content Libraries and storage policies, are checked on the root folder.
+var (
+	DatacenterPrivileges = []string{
+		"System.View",
+	}
+	FolderPrivileges = []string{
+		"VirtualMachine.Inventory.CreateFromExisting",
+		"VirtualMachine.Inventory.Delete",
+		"VirtualMachine.Config.AddNewDisk",
+		"VirtualMachine.Config.AddRemoveDevice",
+		"VirtualMachine.Config.AdvancedConfig",
+		"VirtualMachine.Config.CPUCount",
+		"VirtualMachine.Config.DiskExtend",
+		"VirtualMachine.Config.EditDevice",
+		"VirtualMachine.Config.Memory",
+		"VirtualMachine.Config.Resource",
+		"VirtualMachine.Interact.DeviceConnection",
+		"VirtualMachine.Interact.PowerOff",
+		"VirtualMachine.Interact.PowerOn",
+		"VirtualMachine.Interact.SetCDMedia",
+		"VirtualMachine.Provisioning.Customize",
+		"InventoryService.Tagging.AttachTag",
+	}
+	// EncryptionPrivileges are required on the folder of virtual machines
+	// with a virtual TPM, whose files are encrypted.
+	EncryptionPrivileges = []string{
+		"Cryptographer.Access",
+		"Cryptographer.AddDisk",
+		"Cryptographer.Clone",
+		"Cryptographer.Encrypt",
+		"Cryptographer.EncryptNew",
+	}
+	// TemplatePrivileges are required on a template and VirtualMachinePrivileges
+	// on a virtual machine which is cloned.
+	TemplatePrivileges = []string{
+		"VirtualMachine.Provisioning.DeployTemplate",
+	}
+	VirtualMachinePrivileges = []string{
+		"VirtualMachine.Provisioning.Clone",
+	}
+	ContentLibraryPrivileges = []string{
+		"ContentLibrary.DownloadSession",
+		"ContentLibrary.ReadStorage",
+		"VirtualMachine.Provisioning.DeployTemplate",
+	}
+	StoragePolicyPrivileges = []string{
+		"StorageProfile.View",
+	}
+	ResourcePoolPrivileges = []string{
+		"Resource.AssignVMToPool",
+	}
+	DatastorePrivileges = []string{
+		"Datastore.AllocateSpace",
+		"Datastore.Browse",
+		"Datastore.FileManagement",
+	}
+	NetworkPrivileges = []string{
+		"Network.Assign",
+	}
+)
+
+// Object is an inventory object and the privileges required on it.
+type Object struct {
+	// Reference is the managed object reference of the object.
+	Reference types.ManagedObjectReference
+	// Name is the name or inventory path of the object.
+	Name string
+	// Privileges are the IDs of the privileges required on the object.
+	Privileges []string
+}
+
+// Missing are the privileges missing on an object.
+type Missing struct {
+	Object     Object
+	Privileges []string
+}
+
+// String returns the missing privileges of the object, e.g.
+// "Datastore ds0: Datastore.AllocateSpace, Datastore.Browse".
+func (m Missing) String() string {
+	return fmt.Sprintf("%s %s: %s", m.Object.Reference.Type, m.Object.Name, strings.Join(m.Privileges, ", "))
+}
+
+// Message returns a message listing the missing privileges of each object.
+func Message(missing []Missing) string {
+	objects := make([]string, 0, len(missing))
+	for _, m := range missing {
+		objects = append(objects, m.String())
+	}
+	return "missing privileges on " + strings.Join(objects, "; ")
+}
+
+// Verify returns the privileges the user of the session is missing on the
+// objects, using the AuthorizationManager. Objects referenced more than once
+// are checked for the union of their privileges.
+func Verify(ctx context.Context, c *govmomi.Client, objects []Object) ([]Missing, error) {
+	if c.ServiceContent.AuthorizationManager == nil {
+		return nil, errors.New("authorization manager is not available")
+	}
+	objects = merge(objects)
+	if len(objects) == 0 {
+		return nil, nil
+	}
+
+	userSession, err := c.SessionManager.UserSession(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get current session")
+	}
+	if userSession == nil {
+		return nil, errors.New("current session is not available")
+	}
+
+	var results []types.EntityPrivilege
+	for privileges, refs := range groupByPrivileges(objects) {
+		res, err := methods.HasPrivilegeOnEntities(ctx, c.Client, &types.HasPrivilegeOnEntities{
+			This:      *c.ServiceContent.AuthorizationManager,
+			Entity:    refs,
+			SessionId: userSession.Key,
+			PrivId:    strings.Split(privileges, ","),
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to check privileges")
+		}
+		results = append(results, res.Returnval...)
+	}
+	return missing(objects, results), nil
+}
+
+// merge deduplicates the objects by reference, merging their privileges.
+func merge(objects []Object) []Object {
+	index := map[types.ManagedObjectReference]int{}
+	merged := make([]Object, 0, len(objects))
+	for _, obj := range objects {
+		i, ok := index[obj.Reference]
+		if !ok {
+			index[obj.Reference] = len(merged)
+			merged = append(merged, Object{Reference: obj.Reference, Name: obj.Name})
+			i = len(merged) - 1
+		}
+		merged[i].Privileges = union(merged[i].Privileges, obj.Privileges)
+	}
+	return merged
+}
+
+func union(a, b []string) []string {
+	set := map[string]bool{}
+	for _, s := range append(append([]string{}, a...), b...) {
+		set[s] = true
+	}
+	result := make([]string, 0, len(set))
+	for s := range set {
+		result = append(result, s)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// groupByPrivileges groups the objects requiring the same privileges, so
+// that they are checked with a single call.
+func groupByPrivileges(objects []Object) map[string][]types.ManagedObjectReference {
+	groups := map[string][]types.ManagedObjectReference{}
+	for _, obj := range objects {
+		key := strings.Join(obj.Privileges, ",")
+		groups[key] = append(groups[key], obj.Reference)
+	}
+	return groups
+}
+
+// missing returns the privileges which are not granted on the objects, in
+// the order of the objects.
+func missing(objects []Object, results []types.EntityPrivilege) []Missing {
+	granted := map[types.ManagedObjectReference]map[string]bool{}
+	for _, result := range results {
+		if granted[result.Entity] == nil {
+			granted[result.Entity] = map[string]bool{}
+		}
+		for _, availability := range result.PrivAvailability {
+			if availability.IsGranted {
+				granted[result.Entity][availability.PrivId] = true
+			}
+		}
+	}
+
+	var result []Missing
+	for _, obj := range objects {
+		var privileges []string
+		for _, privilege := range obj.Privileges {
+			if !granted[obj.Reference][privilege] {
+				privileges = append(privileges, privilege)
+			}
+		}
+		if len(privileges) > 0 {
+			result = append(result, Missing{Object: obj, Privileges: privileges})
+		}
+	}
+	return result
+}
+
+// Placement names the inventory objects on which virtual machines are
+// placed.
+type Placement struct {
+	// Datacenter is the name or inventory path of the datacenter, the default
+	// datacenter if empty.
+	Datacenter string
+	// Folder is the name or inventory path of the folder, the virtual
+	// machine folder of the datacenter if empty.
+	Folder string
+	// ResourcePool is the name or inventory path of the resource pool, the
+	// root resource pool of ComputeCluster or the default resource pool if
+	// empty.
+	ResourcePool string
+	// ComputeCluster is the name or inventory path of the compute cluster.
+	ComputeCluster string
+	// Datastore is the name or inventory path of the datastore, which is not
+	// checked if empty.
+	Datastore string
+	// Networks are the names or inventory paths of the networks.
+	Networks []string
+	// Template is the name, inventory path or instance UUID of the template
+	// which is cloned, which is not checked if empty.
+	Template string
+	// ContentLibrary, StoragePolicy and Encryption are true if the virtual
+	// machines are deployed from a Content Library item, placed with a
+	// storage policy or encrypted.
+	ContentLibrary bool
+	StoragePolicy  bool
+	Encryption     bool
+}
+
+// Objects finds the inventory objects of the placement, and returns them with
+// the privileges required on them.
+func Objects(ctx context.Context, c *vim25.Client, placement Placement) ([]Object, error) {
+	finder := find.NewFinder(c, false)
+	datacenter, err := finder.DatacenterOrDefault(ctx, placement.Datacenter)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to find datacenter %q", placement.Datacenter)
+	}
+	finder.SetDatacenter(datacenter)
+	objects := []Object{newObject(datacenter.Reference(), datacenter.InventoryPath, DatacenterPrivileges)}
+
+	var folder *object.Folder
+	if placement.Folder == "" {
+		folders, err := datacenter.Folders(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get folders of datacenter %s", datacenter.InventoryPath)
+		}
+		folder = folders.VmFolder
+	} else if folder, err = finder.Folder(ctx, placement.Folder); err != nil {
+		return nil, errors.Wrapf(err, "unable to find folder %q", placement.Folder)
+	}
+	objects = append(objects, newObject(folder.Reference(), folder.InventoryPath, FolderPrivileges))
+	if placement.Encryption {
+		objects = append(objects, newObject(folder.Reference(), folder.InventoryPath, EncryptionPrivileges))
+	}
+
+	// Virtual machines are placed in the root resource pool of the compute
+	// cluster, or the default resource pool, without a resource pool.
+	poolPath := placement.ResourcePool
+	if poolPath == "" && placement.ComputeCluster != "" {
+		poolPath = placement.ComputeCluster + "/Resources"
+	}
+	pool, err := finder.ResourcePoolOrDefault(ctx, poolPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to find resource pool %q", poolPath)
+	}
+	objects = append(objects, newObject(pool.Reference(), pool.InventoryPath, ResourcePoolPrivileges))
+
+	if placement.Datastore != "" {
+		datastore, err := finder.Datastore(ctx, placement.Datastore)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to find datastore %q", placement.Datastore)
+		}
+		objects = append(objects, newObject(datastore.Reference(), datastore.InventoryPath, DatastorePrivileges))
+	}
+
+	for _, name := range placement.Networks {
+		network, err := finder.Network(ctx, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to find network %q", name)
+		}
+		objects = append(objects, newObject(network.Reference(), network.GetInventoryPath(), NetworkPrivileges))
+	}
+
+	if placement.Template != "" {
+		template, err := templateObject(ctx, c, finder, datacenter, placement.Template)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, template)
+	}
+
+	rootFolder := c.ServiceContent.RootFolder
+	if placement.ContentLibrary {
+		objects = append(objects, newObject(rootFolder, "/", ContentLibraryPrivileges))
+	}
+	if placement.StoragePolicy {
+		objects = append(objects, newObject(rootFolder, "/", StoragePolicyPrivileges))
+	}
+	return objects, nil
+}
+
+// templateObject finds the template or virtual machine with the given name,
+// inventory path or instance UUID, and returns it with the privileges
+// required to clone it.
+func templateObject(ctx context.Context, c *vim25.Client, finder *find.Finder, datacenter *object.Datacenter, name string) (Object, error) {
+	var vm *object.VirtualMachine
+	if _, err := uuid.Parse(name); err == nil {
+		ref, err := object.NewSearchIndex(c).FindByUuid(ctx, datacenter, name, true, pointer.Bool(true))
+		if err != nil {
+			return Object{}, errors.Wrapf(err, "unable to find template %q", name)
+		}
+		if ref != nil {
+			vm = object.NewVirtualMachine(c, ref.Reference())
+		}
+	}
+	if vm == nil {
+		var err error
+		if vm, err = finder.VirtualMachine(ctx, name); err != nil {
+			return Object{}, errors.Wrapf(err, "unable to find template %q", name)
+		}
+	}
+
+	var obj mo.VirtualMachine
+	if err := vm.Properties(ctx, vm.Reference(), []string{"config.template"}, &obj); err != nil {
+		return Object{}, errors.Wrapf(err, "unable to get configuration of template %q", name)
+	}
+	if obj.Config != nil && obj.Config.Template {
+		return newObject(vm.Reference(), name, TemplatePrivileges), nil
+	}
+	return newObject(vm.Reference(), name, VirtualMachinePrivileges), nil
+}
+
+func newObject(ref types.ManagedObjectReference, name string, privileges []string) Object {
+	return Object{Reference: ref, Name: name, Privileges: privileges}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package privileges
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+var (
+	folderRef    = types.ManagedObjectReference{Type: "Folder", Value: "group-v1"}
+	datastoreRef = types.ManagedObjectReference{Type: "Datastore", Value: "datastore-1"}
+)
+
+func TestMerge(t *testing.T) {
+	objects := merge([]Object{
+		{Reference: folderRef, Name: "vm", Privileges: []string{"b", "a"}},
+		{Reference: datastoreRef, Name: "ds0", Privileges: []string{"c"}},
+		{Reference: folderRef, Name: "vm", Privileges: []string{"c", "a"}},
+	})
+
+	expected := []Object{
+		{Reference: folderRef, Name: "vm", Privileges: []string{"a", "b", "c"}},
+		{Reference: datastoreRef, Name: "ds0", Privileges: []string{"c"}},
+	}
+	if !reflect.DeepEqual(objects, expected) {
+		t.Errorf("Expected objects %+v, got %+v", expected, objects)
+	}
+}
+
+func TestMissing(t *testing.T) {
+	objects := []Object{
+		{Reference: folderRef, Name: "vm", Privileges: []string{"VirtualMachine.Inventory.Delete"}},
+		{Reference: datastoreRef, Name: "ds0", Privileges: []string{"Datastore.AllocateSpace", "Datastore.Browse"}},
+	}
+
+	testCases := []struct {
+		name     string
+		results  []types.EntityPrivilege
+		expected string
+	}{
+		{
+			name: "when all privileges are granted",
+			results: []types.EntityPrivilege{
+				{Entity: folderRef, PrivAvailability: []types.PrivilegeAvailability{{PrivId: "VirtualMachine.Inventory.Delete", IsGranted: true}}},
+				{Entity: datastoreRef, PrivAvailability: []types.PrivilegeAvailability{
+					{PrivId: "Datastore.AllocateSpace", IsGranted: true},
+					{PrivId: "Datastore.Browse", IsGranted: true},
+				}},
+			},
+		},
+		{
+			name: "when privileges are not granted",
+			results: []types.EntityPrivilege{
+				{Entity: folderRef, PrivAvailability: []types.PrivilegeAvailability{{PrivId: "VirtualMachine.Inventory.Delete", IsGranted: true}}},
+				{Entity: datastoreRef, PrivAvailability: []types.PrivilegeAvailability{
+					{PrivId: "Datastore.AllocateSpace", IsGranted: false},
+					{PrivId: "Datastore.Browse", IsGranted: true},
+				}},
+			},
+			expected: "missing privileges on Datastore ds0: Datastore.AllocateSpace",
+		},
+		{
+			name: "when an object is not in the results",
+			results: []types.EntityPrivilege{
+				{Entity: datastoreRef, PrivAvailability: []types.PrivilegeAvailability{
+					{PrivId: "Datastore.AllocateSpace", IsGranted: false},
+				}},
+			},
+			expected: "missing privileges on Folder vm: VirtualMachine.Inventory.Delete; Datastore ds0: Datastore.AllocateSpace, Datastore.Browse",
+		},
+	}
+
+	for _, test := range testCases {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			m := missing(objects, tc.results)
+			if tc.expected == "" {
+				if len(m) != 0 {
+					t.Fatalf("Expected no missing privileges, got %v", m)
+				}
+				return
+			}
+			if message := Message(m); message != tc.expected {
+				t.Errorf("Expected message %q, got %q", tc.expected, message)
+			}
+		})
+	}
+}
+
+func TestObjects(t *testing.T) {
+	simulator.Test(func(ctx context.Context, c *vim25.Client) {
+		objects, err := Objects(ctx, c, Placement{
+			ComputeCluster: "DC0_C0",
+			Template:       "DC0_H0_VM0",
+			ContentLibrary: true,
+			StoragePolicy:  true,
+			Encryption:     true,
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		privileges := map[string][]string{}
+		for _, obj := range merge(objects) {
+			privileges[obj.Reference.Type+" "+obj.Name] = obj.Privileges
+		}
+		for _, name := range []string{"Datacenter /DC0", "Folder /DC0/vm", "ResourcePool /DC0/host/DC0_C0/Resources", "VirtualMachine DC0_H0_VM0", "Folder /"} {
+			if _, ok := privileges[name]; !ok {
+				t.Errorf("Expected %s in the objects, got %v", name, privileges)
+			}
+		}
+		if !reflect.DeepEqual(privileges["VirtualMachine DC0_H0_VM0"], VirtualMachinePrivileges) {
+			t.Errorf("Expected the privileges %v on the template, got %v", VirtualMachinePrivileges, privileges["VirtualMachine DC0_H0_VM0"])
+		}
+		if len(privileges["Folder /DC0/vm"]) != len(FolderPrivileges)+len(EncryptionPrivileges) {
+			t.Errorf("Expected the folder and encryption privileges on the folder, got %v", privileges["Folder /DC0/vm"])
+		}
+		if len(privileges["Folder /"]) != len(ContentLibraryPrivileges)+len(StoragePolicyPrivileges) {
+			t.Errorf("Expected the content library and storage policy privileges on the root folder, got %v", privileges["Folder /"])
+		}
+	})
+}
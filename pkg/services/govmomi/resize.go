@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// reconcileResize applies changes to the CPUs and memory of the VSphereVM to
+// the VM according to its resize policy. Changes are hot added to a powered
+// on VM if possible, otherwise they are applied while the VM is powered off,
+// powering it off first if the policy allows it. It returns false while the
+// VM is being reconfigured or powered off, with the result after which the
+// VSphereVM is reconciled again if needed.
+func (vms *VMService) reconcileResize(ctx context.Context, virtualMachineCtx *virtualMachineContext) (reconcile.Result, bool, error) {
+	policy := virtualMachineCtx.VSphereVM.Spec.ResizePolicy
+	if policy == "" || policy == infrav1.VirtualMachineResizePolicyDisabled {
+		return reconcile.Result{}, true, nil
+	}
+	// The CPUs and memory of instant clones are inherited from the source VM.
+	if virtualMachineCtx.VSphereVM.Spec.CloneMode == infrav1.InstantClone {
+		return reconcile.Result{}, true, nil
+	}
+
+	virtualMachine, err := virtualMachineCtx.Properties(ctx)
+	if err != nil {
+		return reconcile.Result{}, false, errors.Wrapf(err, "error getting hardware configuration of VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+	if virtualMachine.Config == nil {
+		return reconcile.Result{}, false, errors.Errorf("error getting hardware configuration of VM %s: config is not available", virtualMachineCtx.VSphereVM.Name)
+	}
+
+	configSpec, hotAddable := calculateResize(&virtualMachineCtx.VSphereVM.Spec.VirtualMachineCloneSpec, virtualMachine.Config)
+	if configSpec == nil {
+		if conditions.Has(virtualMachineCtx.VSphereVM, infrav1.VMResizedCondition) {
+			conditions.MarkTrue(virtualMachineCtx.VSphereVM, infrav1.VMResizedCondition)
+		}
+		return reconcile.Result{}, true, nil
+	}
+
+	switch virtualMachine.Runtime.PowerState {
+	case types.VirtualMachinePowerStatePoweredOff:
+		// The guest shutdown of a power cycle has completed.
+		conditions.Delete(virtualMachineCtx.VSphereVM, infrav1.GuestSoftPowerOffSucceededCondition)
+	case types.VirtualMachinePowerStatePoweredOn:
+		if !hotAddable {
+			return vms.powerOffForResize(ctx, virtualMachineCtx)
+		}
+	default:
+		virtualMachineCtx.Logger.Info("skipping resize", "powerState", virtualMachine.Runtime.PowerState)
+		return reconcile.Result{}, true, nil
+	}
+
+	virtualMachineCtx.Logger.Info("resizing VM",
+		"numCPUs", configSpec.NumCPUs,
+		"numCoresPerSocket", configSpec.NumCoresPerSocket,
+		"memoryMiB", configSpec.MemoryMB,
+		"powerState", virtualMachine.Runtime.PowerState)
+	task, err := virtualMachineCtx.Obj.Reconfigure(ctx, *configSpec)
+	if err != nil {
+		conditions.MarkFalse(virtualMachineCtx.VSphereVM, infrav1.VMResizedCondition, infrav1.ResizeFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
+		return reconcile.Result{}, false, errors.Wrapf(err, "error triggering reconfigure op to resize VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+	conditions.MarkFalse(virtualMachineCtx.VSphereVM, infrav1.VMResizedCondition, infrav1.ResizingReason, clusterv1.ConditionSeverityInfo, "")
+	virtualMachineCtx.VSphereVM.Status.TaskRef = task.Reference().Value
+	return reconcile.Result{}, false, nil
+}
+
+// powerOffForResize powers off a VM whose resize cannot be hot added if the
+// resize policy allows it. The VM is powered off according to its power off
+// mode, the VSphereVM is reconciled again once the guest has shut down.
+func (vms *VMService) powerOffForResize(ctx context.Context, virtualMachineCtx *virtualMachineContext) (reconcile.Result, bool, error) {
+	if virtualMachineCtx.VSphereVM.Spec.ResizePolicy != infrav1.VirtualMachineResizePolicyHotAddOrPowerCycle {
+		conditions.MarkFalse(virtualMachineCtx.VSphereVM, infrav1.VMResizedCondition, infrav1.WaitingForPowerOffReason, clusterv1.ConditionSeverityInfo,
+			"changes cannot be hot added and are applied the next time the VM is powered off")
+		return reconcile.Result{}, true, nil
+	}
+
+	conditions.MarkFalse(virtualMachineCtx.VSphereVM, infrav1.VMResizedCondition, infrav1.PowerCyclingReason, clusterv1.ConditionSeverityInfo, "")
+
+	softPowerOffPending, err := vms.triggerSoftPowerOff(ctx, virtualMachineCtx)
+	if err != nil {
+		return reconcile.Result{}, false, err
+	}
+	if softPowerOffPending {
+		// The watcher triggers a reconcile once the guest has shut down. The
+		// VSphereVM is also reconciled again when the timeout is exceeded,
+		// which falls back to a hard power off for the trySoft mode.
+		virtualMachineCtx.Logger.Info("wait for guest to shut down for resize")
+		return reconcile.Result{RequeueAfter: vms.softPowerOffTimeoutRemaining(virtualMachineCtx.VSphereVM)}, false, nil
+	}
+
+	virtualMachineCtx.Logger.Info("powering off for resize")
+	task, err := virtualMachineCtx.Obj.PowerOff(ctx)
+	if err != nil {
+		return reconcile.Result{}, false, errors.Wrapf(err, "error triggering power off op to resize VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+	virtualMachineCtx.VSphereVM.Status.TaskRef = task.Reference().Value
+	return reconcile.Result{}, false, nil
+}
+
+// calculateResize returns a config spec that changes the CPUs and memory of a
+// VM with the given config to the ones in the clone spec, or nil if they are
+// already in place. The returned bool is true if the changes can be applied
+// to the VM while it is powered on.
+func calculateResize(spec *infrav1.VirtualMachineCloneSpec, config *types.VirtualMachineConfigInfo) (*types.VirtualMachineConfigSpec, bool) {
+	// The same defaults as for cloning the VM apply.
+	numCPUs := spec.NumCPUs
+	if numCPUs < 2 {
+		numCPUs = 2
+	}
+	numCoresPerSocket := spec.NumCoresPerSocket
+	if numCoresPerSocket == 0 {
+		// Keep the current number of cores per socket if possible, it cannot
+		// be changed on a powered on VM.
+		numCoresPerSocket = numCPUs
+		if current := config.Hardware.NumCoresPerSocket; current > 0 && numCPUs%current == 0 {
+			numCoresPerSocket = current
+		}
+	}
+	memMiB := spec.MemoryMiB
+	if memMiB == 0 {
+		memMiB = 2048
+	}
+
+	configSpec := &types.VirtualMachineConfigSpec{}
+	changed, hotAddable := false, true
+	if numCPUs != config.Hardware.NumCPU {
+		configSpec.NumCPUs = numCPUs
+		changed = true
+		hotAddable = hotAddable && numCPUs > config.Hardware.NumCPU && pointer.BoolDeref(config.CpuHotAddEnabled, false)
+	}
+	if numCoresPerSocket != config.Hardware.NumCoresPerSocket {
+		configSpec.NumCoresPerSocket = numCoresPerSocket
+		changed = true
+		hotAddable = false
+	}
+	if memMiB != int64(config.Hardware.MemoryMB) {
+		configSpec.MemoryMB = memMiB
+		changed = true
+		hotAddable = hotAddable && memMiB > int64(config.Hardware.MemoryMB) && pointer.BoolDeref(config.MemoryHotAddEnabled, false) &&
+			(config.HotPlugMemoryLimit == 0 || memMiB <= config.HotPlugMemoryLimit)
+	}
+	if !changed {
+		return nil, false
+	}
+	return configSpec, hotAddable
+}
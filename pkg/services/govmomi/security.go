@@ -0,0 +1,77 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package govmomi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// reconcileSecurity reports through the SecurityConfiguredCondition whether
+// the security features requested for the VSphereVM are in place on the VM.
+// The features are applied when the VM is cloned, a VM missing some of them
+// is reported but not modified as they can only be changed while powered off.
+func (vms *VMService) reconcileSecurity(ctx context.Context, virtualMachineCtx *virtualMachineContext) error {
+	security := virtualMachineCtx.VSphereVM.Spec.Security
+	if security == nil {
+		conditions.Delete(virtualMachineCtx.VSphereVM, infrav1.SecurityConfiguredCondition)
+		return nil
+	}
+
+	var virtualMachine mo.VirtualMachine
+	if err := virtualMachineCtx.Obj.Properties(ctx, virtualMachineCtx.Obj.Reference(), []string{"config.firmware", "config.bootOptions", "config.hardware.device"}, &virtualMachine); err != nil {
+		return errors.Wrapf(err, "error getting security configuration of VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+	if virtualMachine.Config == nil {
+		return errors.Errorf("error getting security configuration of VM %s: config is not available", virtualMachineCtx.VSphereVM.Name)
+	}
+
+	if missing := missingSecurityFeatures(security, virtualMachine.Config); len(missing) > 0 {
+		virtualMachineCtx.Logger.Info("security features are not applied to the VM", "features", missing)
+		conditions.MarkFalse(virtualMachineCtx.VSphereVM, infrav1.SecurityConfiguredCondition, infrav1.SecurityNotAppliedReason, clusterv1.ConditionSeverityWarning,
+			"%s not applied to the VM", strings.Join(missing, ", "))
+		return nil
+	}
+	conditions.MarkTrue(virtualMachineCtx.VSphereVM, infrav1.SecurityConfiguredCondition)
+	return nil
+}
+
+// missingSecurityFeatures returns the names of the fields of the security
+// spec which are not reflected by the given VM config.
+func missingSecurityFeatures(security *infrav1.VirtualMachineSecuritySpec, config *types.VirtualMachineConfigInfo) []string {
+	var missing []string
+	if security.Firmware != "" && config.Firmware != string(security.Firmware) {
+		missing = append(missing, "firmware")
+	}
+	if security.SecureBoot && (config.BootOptions == nil || !pointer.BoolDeref(config.BootOptions.EfiSecureBootEnabled, false)) {
+		missing = append(missing, "secureBoot")
+	}
+	if security.VTPM && len(object.VirtualDeviceList(config.Hardware.Device).SelectByType((*types.VirtualTPM)(nil))) == 0 {
+		missing = append(missing, "vtpm")
+	}
+	return missing
+}
@@ -20,14 +20,13 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"net/netip"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/pbm"
 	pbmTypes "github.com/vmware/govmomi/pbm/types"
-	"github.com/vmware/govmomi/property"
-	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	corev1 "k8s.io/api/core/v1"
 	apitypes "k8s.io/apimachinery/pkg/types"
@@ -38,12 +37,17 @@ import (
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/allocation"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/cluster"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/clustermodules"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/events"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/extra"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/ipam"
 	govmominet "sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/net"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/pci"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/vcenter"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/watcher"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/util"
 )
 
@@ -55,7 +59,10 @@ type VMService struct{}
 //  2. Updating the VM with the bootstrap data, such as the cloud-init meta and user data, before...
 //  3. Powering on the VM, and finally...
 //  4. Returning the real-time state of the VM to the caller
-func (vms *VMService) ReconcileVM(ctx context.Context, vmCtx *capvcontext.VMContext) (vm infrav1.VirtualMachine, _ error) {
+func (vms *VMService) ReconcileVM(ctx context.Context, vmCtx *capvcontext.VMContext) (_ reconcile.Result, vm infrav1.VirtualMachine, _ error) {
+	ctx, span := tracing.Start(ctx, "VMService.ReconcileVM")
+	defer span.End()
+
 	// Initialize the result.
 	vm = infrav1.VirtualMachine{
 		Name:  vmCtx.VSphereVM.Name,
@@ -65,20 +72,20 @@ func (vms *VMService) ReconcileVM(ctx context.Context, vmCtx *capvcontext.VMCont
 	// If there is an in-flight task associated with this VM then do not
 	// reconcile the VM until the task is completed.
 	if inFlight, err := reconcileInFlightTask(ctx, vmCtx); err != nil || inFlight {
-		return vm, err
+		return reconcile.Result{}, vm, err
 	}
 
 	// This deferred function will trigger a reconcile event for the
 	// VSphereVM resource once its associated task completes. If
 	// there is no task for the VSphereVM resource then no reconcile
 	// event is triggered.
-	defer reconcileVSphereVMOnTaskCompletion(ctx, vmCtx)
+	defer watchTask(ctx, vmCtx)
 
 	// Before going further, we need the VM's managed object reference.
-	vmRef, err := findVM(ctx, vmCtx)
+	vmRef, properties, err := findVMWithProperties(ctx, vmCtx)
 	if err != nil {
 		if !isNotFound(err) {
-			return vm, err
+			return reconcile.Result{}, vm, err
 		}
 
 		// If the machine was not found by BIOS UUID, it could mean that the machine got deleted from vcenter directly,
@@ -87,7 +94,7 @@ func (vms *VMService) ReconcileVM(ctx context.Context, vmCtx *capvcontext.VMCont
 		if wasNotFoundByBIOSUUID(err) {
 			conditions.MarkFalse(vmCtx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.NotFoundByBIOSUUIDReason, clusterv1.ConditionSeverityWarning, err.Error())
 			vm.State = infrav1.VirtualMachineStateNotFound
-			return vm, err
+			return reconcile.Result{}, vm, err
 		}
 
 		// Otherwise, this is a new machine and the VM should be created.
@@ -101,16 +108,22 @@ func (vms *VMService) ReconcileVM(ctx context.Context, vmCtx *capvcontext.VMCont
 		bootstrapData, format, err := vms.getBootstrapData(ctx, vmCtx)
 		if err != nil {
 			conditions.MarkFalse(vmCtx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.CloningFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
-			return vm, err
+			return reconcile.Result{}, vm, err
 		}
 
 		// Create the VM.
 		err = createVM(ctx, vmCtx, bootstrapData, format)
+		if vcenter.IsInsufficientCapacity(err) {
+			// Returning the error requeues the VSphereVM with the backoff of
+			// the controller until capacity is available.
+			conditions.MarkFalse(vmCtx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.InsufficientCapacityReason, clusterv1.ConditionSeverityWarning, err.Error())
+			return reconcile.Result{}, vm, err
+		}
 		if err != nil {
 			conditions.MarkFalse(vmCtx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.CloningFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
-			return vm, err
+			return reconcile.Result{}, vm, err
 		}
-		return vm, nil
+		return reconcile.Result{}, vm, nil
 	}
 
 	//
@@ -123,62 +136,120 @@ func (vms *VMService) ReconcileVM(ctx context.Context, vmCtx *capvcontext.VMCont
 		Obj:       object.NewVirtualMachine(vmCtx.Session.Client.Client, vmRef),
 		Ref:       vmRef,
 		State:     &vm,
+		// The properties are not reused across reconciles, as they change
+		// with the tasks started by a reconcile.
+		properties: properties,
 	}
 	vm.VMRef = vmRef.String()
 
-	vms.reconcileUUID(ctx, virtualMachineCtx)
+	// Trigger a reconcile event for the VSphereVM resource whenever the power
+	// state, the guest networks, the host or the configuration of the VM
+	// change, e.g. when the VM reports IP addresses after being powered on.
+	if err := watcher.Watch(ctx, vmCtx, vmRef); err != nil {
+		vmCtx.Logger.Error(err, "failed to watch VM")
+	}
+	// Record the changes made to the VM directly in vCenter, e.g. migrations
+	// or power operations, as events of the VSphereVM and VSphereMachine.
+	if err := events.Watch(ctx, vmCtx, vmRef); err != nil {
+		vmCtx.Logger.Error(err, "failed to watch VM events")
+	}
+
+	if err := vms.reconcileUUID(ctx, virtualMachineCtx); err != nil {
+		return reconcile.Result{}, vm, err
+	}
+
+	if ok, err := vms.reconcileInstanceUUID(ctx, virtualMachineCtx); err != nil || !ok {
+		return reconcile.Result{}, vm, err
+	}
 
 	if ok, err := vms.reconcileHardwareVersion(ctx, virtualMachineCtx); err != nil || !ok {
-		return vm, err
+		return reconcile.Result{}, vm, err
 	}
 
 	if err := vms.reconcilePCIDevices(ctx, virtualMachineCtx); err != nil {
-		return vm, err
+		return reconcile.Result{}, vm, err
+	}
+
+	if ok, err := vms.reconcileResourceAllocation(ctx, virtualMachineCtx); err != nil || !ok {
+		return reconcile.Result{}, vm, err
+	}
+
+	if err := vms.reconcileSecurity(ctx, virtualMachineCtx); err != nil {
+		return reconcile.Result{}, vm, err
+	}
+
+	if result, ok, err := vms.reconcileResize(ctx, virtualMachineCtx); err != nil || !ok {
+		return result, vm, err
 	}
 
 	if err := vms.reconcileNetworkStatus(ctx, virtualMachineCtx); err != nil {
-		return vm, err
+		return reconcile.Result{}, vm, err
 	}
 
 	if ok, err := vms.reconcileIPAddresses(ctx, virtualMachineCtx); err != nil || !ok {
-		return vm, err
+		return reconcile.Result{}, vm, err
 	}
 
+	vms.reconcileNetworkInterfaceStatus(virtualMachineCtx)
+
 	if ok, err := vms.reconcileMetadata(ctx, virtualMachineCtx); err != nil || !ok {
-		return vm, err
+		return reconcile.Result{}, vm, err
+	}
+
+	if ok, err := vms.reconcileGuestInfo(ctx, virtualMachineCtx); err != nil || !ok {
+		return reconcile.Result{}, vm, err
+	}
+
+	if ok, err := vms.reconcileBootstrapDataISO(ctx, virtualMachineCtx); err != nil || !ok {
+		return reconcile.Result{}, vm, err
+	}
+
+	if ok, err := vms.reconcileCustomization(ctx, virtualMachineCtx); err != nil || !ok {
+		return reconcile.Result{}, vm, err
 	}
 
 	if err := vms.reconcileStoragePolicy(ctx, virtualMachineCtx); err != nil {
-		return vm, err
+		return reconcile.Result{}, vm, err
 	}
 
 	if ok, err := vms.reconcileVMGroupInfo(ctx, virtualMachineCtx); err != nil || !ok {
-		return vm, err
+		return reconcile.Result{}, vm, err
 	}
 
 	if err := vms.reconcileClusterModuleMembership(ctx, virtualMachineCtx); err != nil {
-		return vm, err
+		return reconcile.Result{}, vm, err
 	}
 
 	if ok, err := vms.reconcilePowerState(ctx, virtualMachineCtx); err != nil || !ok {
-		return vm, err
+		return reconcile.Result{}, vm, err
+	}
+
+	if ok, err := vms.reconcileBootstrapDataCleanup(ctx, virtualMachineCtx); err != nil || !ok {
+		return reconcile.Result{}, vm, err
 	}
 
 	if err := vms.reconcileHostInfo(ctx, virtualMachineCtx); err != nil {
-		return vm, err
+		return reconcile.Result{}, vm, err
 	}
 
 	if err := vms.reconcileTags(ctx, virtualMachineCtx); err != nil {
 		conditions.MarkFalse(vmCtx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.TagsAttachmentFailedReason, clusterv1.ConditionSeverityError, err.Error())
-		return vm, err
+		return reconcile.Result{}, vm, err
+	}
+
+	if ok, err := vms.reconcileDrift(ctx, virtualMachineCtx); err != nil || !ok {
+		return reconcile.Result{}, vm, err
 	}
 
 	vm.State = infrav1.VirtualMachineStateReady
-	return vm, nil
+	return reconcile.Result{}, vm, nil
 }
 
 // DestroyVM powers off and destroys a virtual machine.
 func (vms *VMService) DestroyVM(ctx context.Context, vmCtx *capvcontext.VMContext) (reconcile.Result, infrav1.VirtualMachine, error) {
+	ctx, span := tracing.Start(ctx, "VMService.DestroyVM")
+	defer span.End()
+
 	vm := infrav1.VirtualMachine{
 		Name:  vmCtx.VSphereVM.Name,
 		State: infrav1.VirtualMachineStatePending,
@@ -194,7 +265,7 @@ func (vms *VMService) DestroyVM(ctx context.Context, vmCtx *capvcontext.VMContex
 	// VSphereVM resource once its associated task completes. If
 	// there is no task for the VSphereVM resource then no reconcile
 	// event is triggered.
-	defer reconcileVSphereVMOnTaskCompletion(ctx, vmCtx)
+	defer watchTask(ctx, vmCtx)
 
 	// Before going further, we need the VM's managed object reference.
 	vmRef, err := findVM(ctx, vmCtx)
@@ -202,6 +273,13 @@ func (vms *VMService) DestroyVM(ctx context.Context, vmCtx *capvcontext.VMContex
 		// If the VM's MoRef could not be found then the VM no longer exists. This
 		// is the desired state.
 		if isNotFound(err) || isFolderNotFound(err) {
+			if err := deleteBootstrapDataISO(ctx, vmCtx); err != nil {
+				return reconcile.Result{}, vm, err
+			}
+			if err := watcher.Unwatch(ctx, vmCtx); err != nil {
+				vmCtx.Logger.Error(err, "failed to stop watching VM")
+			}
+			events.Unwatch(vmCtx)
 			vm.State = infrav1.VirtualMachineStateNotFound
 			return reconcile.Result{}, vm, nil
 		}
@@ -269,6 +347,10 @@ func (vms *VMService) DestroyVM(ctx context.Context, vmCtx *capvcontext.VMContex
 		vmCtx.VSphereVM.Status.ModuleUUID = nil
 	}
 
+	if err := deleteBootstrapDataISO(ctx, vmCtx); err != nil {
+		return reconcile.Result{}, vm, err
+	}
+
 	// At this point the VM is not powered on and can be destroyed. Store the
 	// destroy task's reference and return a requeue error.
 	vmCtx.Logger.Info("destroying vm")
@@ -307,13 +389,45 @@ func (vms *VMService) reconcileIPAddresses(ctx context.Context, virtualMachineCt
 	return true, nil
 }
 
+// reconcileNetworkInterfaceStatus sets the network status of the bonds and
+// VLANs of the VSphereVM. Their MAC address, network and connection state are
+// the ones of the network device they are created on. Their addresses are the
+// static addresses and the addresses allocated from IPAM pools, as the
+// addresses reported by VMware Tools cannot be attributed to an interface
+// sharing the MAC address of a network device.
+func (vms *VMService) reconcileNetworkInterfaceStatus(virtualMachineCtx *virtualMachineContext) {
+	network := &virtualMachineCtx.VSphereVM.Spec.Network
+	var statuses []infrav1.NetworkStatus
+	for _, iface := range util.NetworkInterfaces(network) {
+		status := infrav1.NetworkStatus{
+			Interface: iface.Name,
+		}
+		if i := util.NetworkInterfaceDeviceIndex(network, iface.Name); i >= 0 && i < len(virtualMachineCtx.State.Network) {
+			device := virtualMachineCtx.State.Network[i]
+			status.MACAddr = device.MACAddr
+			status.NetworkName = device.NetworkName
+			status.Connected = device.Connected
+		}
+		ipAddrs := append(append([]string{}, iface.IPAddrs...), virtualMachineCtx.IPAMState[iface.Name].IPAddrs...)
+		for _, ipAddr := range ipAddrs {
+			if prefix, err := netip.ParsePrefix(ipAddr); err == nil {
+				status.IPAddrs = append(status.IPAddrs, prefix.Addr().String())
+			} else if addr, err := netip.ParseAddr(ipAddr); err == nil {
+				status.IPAddrs = append(status.IPAddrs, addr.String())
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	virtualMachineCtx.VSphereVM.Status.NetworkInterfaces = statuses
+}
+
 func (vms *VMService) reconcileMetadata(ctx context.Context, virtualMachineCtx *virtualMachineContext) (bool, error) {
 	existingMetadata, err := vms.getMetadata(ctx, virtualMachineCtx)
 	if err != nil {
 		return false, err
 	}
 
-	newMetadata, err := util.GetMachineMetadata(virtualMachineCtx.VSphereVM.Name, *virtualMachineCtx.VSphereVM, virtualMachineCtx.IPAMState, virtualMachineCtx.State.Network...)
+	newMetadata, err := vms.getMachineMetadata(ctx, virtualMachineCtx)
 	if err != nil {
 		return false, err
 	}
@@ -356,9 +470,8 @@ func (vms *VMService) reconcilePowerState(ctx context.Context, virtualMachineCtx
 			return false, err
 		}
 
-		// Once the VM is successfully powered on, a reconcile request should be
-		// triggered once the VM reports IP addresses are available.
-		reconcileVSphereVMWhenNetworkIsReady(ctx, virtualMachineCtx, task)
+		// Once the VM is successfully powered on, a reconcile request is
+		// triggered by the watcher when the VM reports IP addresses.
 
 		virtualMachineCtx.Logger.Info("wait for VM to be powered on")
 		return false, nil
@@ -460,16 +573,52 @@ func (vms *VMService) reconcileStoragePolicy(ctx context.Context, virtualMachine
 	return nil
 }
 
-func (vms *VMService) reconcileUUID(ctx context.Context, virtualMachineCtx *virtualMachineContext) {
-	virtualMachineCtx.State.BiosUUID = virtualMachineCtx.Obj.UUID(ctx)
+func (vms *VMService) reconcileUUID(ctx context.Context, virtualMachineCtx *virtualMachineContext) error {
+	properties, err := virtualMachineCtx.Properties(ctx)
+	if err != nil {
+		return err
+	}
+	if properties.Config != nil {
+		virtualMachineCtx.State.BiosUUID = properties.Config.Uuid
+	}
+	return nil
+}
+
+// reconcileInstanceUUID assigns the UID of the VSphereVM as the instance UUID
+// of an instant clone in a reconfigure following the clone, as the instant
+// clone operation cannot set it. Like any other clone, the VM can then be found
+// by its instance UUID until its BIOS UUID is known.
+func (vms *VMService) reconcileInstanceUUID(ctx context.Context, virtualMachineCtx *virtualMachineContext) (bool, error) {
+	if virtualMachineCtx.VSphereVM.Status.CloneMode != infrav1.InstantClone {
+		return true, nil
+	}
+	properties, err := virtualMachineCtx.Properties(ctx)
+	if err != nil {
+		return false, err
+	}
+	instanceUUID := string(virtualMachineCtx.VSphereVM.UID)
+	if properties.Config == nil || properties.Config.InstanceUuid == instanceUUID {
+		return true, nil
+	}
+
+	virtualMachineCtx.Logger.Info("assigning instance uuid to instant clone", "instanceuuid", instanceUUID)
+	task, err := virtualMachineCtx.Obj.Reconfigure(ctx, types.VirtualMachineConfigSpec{InstanceUuid: instanceUUID})
+	if err != nil {
+		return false, errors.Wrapf(err, "error trigging reconfigure op for machine %s", virtualMachineCtx.VSphereVM.Name)
+	}
+	virtualMachineCtx.VSphereVM.Status.TaskRef = task.Reference().Value
+	return false, nil
 }
 
 func (vms *VMService) reconcileHardwareVersion(ctx context.Context, virtualMachineCtx *virtualMachineContext) (bool, error) {
 	if virtualMachineCtx.VSphereVM.Spec.HardwareVersion != "" {
-		var virtualMachine mo.VirtualMachine
-		if err := virtualMachineCtx.Obj.Properties(ctx, virtualMachineCtx.Obj.Reference(), []string{"config.version"}, &virtualMachine); err != nil {
+		virtualMachine, err := virtualMachineCtx.Properties(ctx)
+		if err != nil {
 			return false, errors.Wrapf(err, "error getting guestInfo version information from VM %s", virtualMachineCtx.VSphereVM.Name)
 		}
+		if virtualMachine.Config == nil {
+			return false, errors.Errorf("error getting guestInfo version information from VM %s: config is not available", virtualMachineCtx.VSphereVM.Name)
+		}
 		toUpgrade, err := util.LessThan(virtualMachine.Config.Version, virtualMachineCtx.VSphereVM.Spec.HardwareVersion)
 		if err != nil {
 			return false, errors.Wrapf(err, "failed to parse hardware version")
@@ -523,20 +672,47 @@ func (vms *VMService) reconcilePCIDevices(ctx context.Context, virtualMachineCtx
 		if err := virtualMachineCtx.Obj.AddDevice(ctx, pci.ConstructDeviceSpecs(specsToBeAdded)...); err != nil {
 			return errors.Wrapf(err, "error adding pci devices for %q", ctx)
 		}
+		// The devices of the VM changed.
+		virtualMachineCtx.properties = nil
 	}
 	return nil
 }
 
-func (vms *VMService) getMetadata(ctx context.Context, virtualMachineCtx *virtualMachineContext) (string, error) {
-	var (
-		obj mo.VirtualMachine
+// reconcileResourceAllocation updates the CPU and memory resource allocation
+// of the VM if it differs from the one in the VSphereVM spec. It returns false
+// while the VM is being reconfigured.
+func (vms *VMService) reconcileResourceAllocation(ctx context.Context, virtualMachineCtx *virtualMachineContext) (bool, error) {
+	if virtualMachineCtx.VSphereVM.Spec.ResourceAllocation == nil {
+		return true, nil
+	}
 
-		pc    = property.DefaultCollector(virtualMachineCtx.Session.Client.Client)
-		props = []string{"config.extraConfig"}
-	)
+	virtualMachine, err := virtualMachineCtx.Properties(ctx)
+	if err != nil {
+		return false, errors.Wrapf(err, "error getting resource allocation of VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+	if virtualMachine.Config == nil {
+		return false, errors.Errorf("error getting resource allocation of VM %s: config is not available", virtualMachineCtx.VSphereVM.Name)
+	}
 
-	if err := pc.RetrieveOne(ctx, virtualMachineCtx.Ref, props, &obj); err != nil {
-		return "", errors.Wrapf(err, "unable to fetch props %v for vm %s", props, ctx)
+	configSpec := allocation.CalculateChanges(virtualMachineCtx.VSphereVM.Spec.ResourceAllocation, virtualMachine.Config)
+	if configSpec == nil {
+		virtualMachineCtx.Logger.V(5).Info("resource allocation is up to date")
+		return true, nil
+	}
+
+	virtualMachineCtx.Logger.Info("updating resource allocation")
+	task, err := virtualMachineCtx.Obj.Reconfigure(ctx, *configSpec)
+	if err != nil {
+		return false, errors.Wrapf(err, "error triggering reconfigure op for resource allocation of VM %s", virtualMachineCtx.VSphereVM.Name)
+	}
+	virtualMachineCtx.VSphereVM.Status.TaskRef = task.Reference().Value
+	return false, nil
+}
+
+func (vms *VMService) getMetadata(ctx context.Context, virtualMachineCtx *virtualMachineContext) (string, error) {
+	obj, err := virtualMachineCtx.Properties(ctx)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to fetch props for vm %s", ctx)
 	}
 	if obj.Config == nil {
 		return "", nil
@@ -564,15 +740,14 @@ func (vms *VMService) getMetadata(ctx context.Context, virtualMachineCtx *virtua
 }
 
 func (vms *VMService) reconcileHostInfo(ctx context.Context, virtualMachineCtx *virtualMachineContext) error {
-	host, err := virtualMachineCtx.Obj.HostSystem(ctx)
+	properties, err := virtualMachineCtx.Properties(ctx)
 	if err != nil {
 		return err
 	}
-	name, err := host.ObjectName(ctx)
-	if err != nil {
-		return err
+	if properties.HostName == "" {
+		return errors.Errorf("host of vm %s is not available", ctx)
 	}
-	virtualMachineCtx.VSphereVM.Status.Host = name
+	virtualMachineCtx.VSphereVM.Status.Host = properties.HostName
 	return nil
 }
 
@@ -592,7 +767,11 @@ func (vms *VMService) setMetadata(ctx context.Context, virtualMachineCtx *virtua
 }
 
 func (vms *VMService) getNetworkStatus(ctx context.Context, virtualMachineCtx *virtualMachineContext) ([]infrav1.NetworkStatus, error) {
-	allNetStatus, err := govmominet.GetNetworkStatus(ctx, virtualMachineCtx.Session.Client.Client, virtualMachineCtx.Ref)
+	properties, err := virtualMachineCtx.Properties(ctx)
+	if err != nil {
+		return nil, err
+	}
+	allNetStatus, err := govmominet.NetworkStatusOf(properties.VirtualMachine)
 	if err != nil {
 		return nil, err
 	}
@@ -640,6 +819,53 @@ func (vms *VMService) getBootstrapData(ctx context.Context, vmCtx *capvcontext.V
 	return value, bootstrapv1.Format(format), nil
 }
 
+// getMachineMetadata returns the cloud-init metadata of the VSphereVM, rendered
+// from the template of the ConfigMap referenced by the VSphereVM if any.
+func (vms *VMService) getMachineMetadata(ctx context.Context, virtualMachineCtx *virtualMachineContext) ([]byte, error) {
+	vsphereVM := virtualMachineCtx.VSphereVM
+	if vsphereVM.Spec.MetadataTemplate == nil {
+		conditions.Delete(vsphereVM, infrav1.MetadataTemplateRenderedCondition)
+		return util.GetMachineMetadata(vsphereVM.Name, *vsphereVM, virtualMachineCtx.IPAMState, virtualMachineCtx.State.Network...)
+	}
+
+	metadata, err := renderMetadataTemplate(ctx, virtualMachineCtx)
+	if err != nil {
+		conditions.MarkFalse(vsphereVM, infrav1.MetadataTemplateRenderedCondition, infrav1.MetadataTemplateInvalidReason, clusterv1.ConditionSeverityWarning, err.Error())
+		return nil, err
+	}
+	conditions.MarkTrue(vsphereVM, infrav1.MetadataTemplateRenderedCondition)
+	return metadata, nil
+}
+
+func renderMetadataTemplate(ctx context.Context, virtualMachineCtx *virtualMachineContext) ([]byte, error) {
+	vsphereVM := virtualMachineCtx.VSphereVM
+	ref := vsphereVM.Spec.MetadataTemplate
+
+	configMap := &corev1.ConfigMap{}
+	configMapKey := apitypes.NamespacedName{
+		Namespace: vsphereVM.Namespace,
+		Name:      ref.Name,
+	}
+	if err := virtualMachineCtx.Client.Get(ctx, configMapKey, configMap); err != nil {
+		return nil, errors.Wrapf(err, "failed to retrieve metadata template ConfigMap %s", configMapKey)
+	}
+
+	key := ref.Key
+	if key == "" {
+		key = infrav1.DefaultMetadataTemplateKey
+	}
+	metadataTemplate, ok := configMap.Data[key]
+	if !ok {
+		return nil, errors.Errorf("metadata template ConfigMap %s has no key %q", configMapKey, key)
+	}
+
+	metadata, err := util.RenderMachineMetadata(metadataTemplate, vsphereVM.Name, *vsphereVM, virtualMachineCtx.IPAMState, virtualMachineCtx.State.Network...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid metadata template in ConfigMap %s", configMapKey)
+	}
+	return metadata, nil
+}
+
 func (vms *VMService) reconcileVMGroupInfo(ctx context.Context, virtualMachineCtx *virtualMachineContext) (bool, error) {
 	if virtualMachineCtx.VSphereFailureDomain == nil || virtualMachineCtx.VSphereFailureDomain.Spec.Topology.Hosts == nil {
 		virtualMachineCtx.Logger.V(5).Info("hosts topology in failure domain not defined. skipping reconcile VM group")
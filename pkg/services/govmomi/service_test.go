@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	. "github.com/onsi/gomega"
@@ -29,12 +30,17 @@ import (
 	"github.com/vmware/govmomi/simulator"
 	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/types"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/extra"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
 )
 
@@ -109,6 +115,510 @@ func Test_reconcilePCIDevices(t *testing.T) {
 	})
 }
 
+func Test_missingSecurityFeatures(t *testing.T) {
+	security := &infrav1.VirtualMachineSecuritySpec{Firmware: infrav1.FirmwareEFI, SecureBoot: true, VTPM: true}
+
+	t.Run("all security features applied", func(t *testing.T) {
+		g := NewWithT(t)
+		config := &types.VirtualMachineConfigInfo{
+			Firmware:    string(types.GuestOsDescriptorFirmwareTypeEfi),
+			BootOptions: &types.VirtualMachineBootOptions{EfiSecureBootEnabled: pointer.Bool(true)},
+			Hardware:    types.VirtualHardware{Device: []types.BaseVirtualDevice{&types.VirtualTPM{}}},
+		}
+		g.Expect(missingSecurityFeatures(security, config)).To(BeEmpty())
+	})
+
+	t.Run("no security features applied", func(t *testing.T) {
+		g := NewWithT(t)
+		config := &types.VirtualMachineConfigInfo{
+			Firmware: string(types.GuestOsDescriptorFirmwareTypeBios),
+		}
+		g.Expect(missingSecurityFeatures(security, config)).To(Equal([]string{"firmware", "secureBoot", "vtpm"}))
+	})
+}
+
+func Test_calculateResize(t *testing.T) {
+	config := &types.VirtualMachineConfigInfo{
+		Hardware:            types.VirtualHardware{NumCPU: 4, NumCoresPerSocket: 2, MemoryMB: 8192},
+		CpuHotAddEnabled:    pointer.Bool(true),
+		MemoryHotAddEnabled: pointer.Bool(true),
+		HotPlugMemoryLimit:  16384,
+	}
+
+	tests := []struct {
+		name               string
+		spec               infrav1.VirtualMachineCloneSpec
+		expectedChange     bool
+		expectedHotAddable bool
+	}{
+		{
+			name: "no change",
+			spec: infrav1.VirtualMachineCloneSpec{NumCPUs: 4, NumCoresPerSocket: 2, MemoryMiB: 8192},
+		},
+		{
+			name:               "cpus added keeping the cores per socket",
+			spec:               infrav1.VirtualMachineCloneSpec{NumCPUs: 8, MemoryMiB: 8192},
+			expectedChange:     true,
+			expectedHotAddable: true,
+		},
+		{
+			name:               "memory added within the hot plug limit",
+			spec:               infrav1.VirtualMachineCloneSpec{NumCPUs: 4, MemoryMiB: 16384},
+			expectedChange:     true,
+			expectedHotAddable: true,
+		},
+		{
+			name:           "memory added beyond the hot plug limit",
+			spec:           infrav1.VirtualMachineCloneSpec{NumCPUs: 4, MemoryMiB: 32768},
+			expectedChange: true,
+		},
+		{
+			name:           "cpus removed",
+			spec:           infrav1.VirtualMachineCloneSpec{NumCPUs: 2, MemoryMiB: 8192},
+			expectedChange: true,
+		},
+		{
+			name:           "cores per socket changed",
+			spec:           infrav1.VirtualMachineCloneSpec{NumCPUs: 4, NumCoresPerSocket: 4, MemoryMiB: 8192},
+			expectedChange: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			configSpec, hotAddable := calculateResize(&tt.spec, config)
+			g.Expect(configSpec != nil).To(Equal(tt.expectedChange))
+			g.Expect(hotAddable).To(Equal(tt.expectedHotAddable))
+		})
+	}
+}
+
+func Test_reconcileResize(t *testing.T) {
+	var vmCtx *virtualMachineContext
+	var g *WithT
+	var vms *VMService
+
+	// before sets up a VSphereVM with fewer CPUs than its VM, which cannot be
+	// applied to the powered on VM.
+	before := func(ctx context.Context, c *vim25.Client, model *simulator.Model, cloneMode infrav1.CloneMode) {
+		vmCtx = emptyVirtualMachineContext()
+		vmCtx.Client = fake.NewClientBuilder().Build()
+		vms = &VMService{}
+
+		authSession, err := getAuthSession(ctx, model.Service.Listen.Host)
+		g.Expect(err).ToNot(HaveOccurred())
+		vmCtx.Session = authSession
+
+		vm, err := find.NewFinder(c).VirtualMachine(ctx, "DC0_H0_VM0")
+		g.Expect(err).ToNot(HaveOccurred())
+		reconfigure(ctx, g, vm, types.VirtualMachineConfigSpec{NumCPUs: 4, NumCoresPerSocket: 4, MemoryMB: 4096})
+		vmCtx.Obj = vm
+		vmCtx.Ref = vm.Reference()
+		vmCtx.VSphereVM = &infrav1.VSphereVM{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "vsphereVM1",
+				Namespace: "my-namespace",
+			},
+			Spec: infrav1.VSphereVMSpec{
+				VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+					CloneMode: cloneMode,
+					NumCPUs:   2,
+					MemoryMiB: 4096,
+				},
+				PowerOffMode:             infrav1.VirtualMachinePowerOpModeTrySoft,
+				GuestSoftPowerOffTimeout: &metav1.Duration{Duration: 3 * time.Minute},
+				ResizePolicy:             infrav1.VirtualMachineResizePolicyHotAddOrPowerCycle,
+			},
+		}
+	}
+
+	t.Run("when the VM is an instant clone it is not resized", func(t *testing.T) {
+		g = NewWithT(t)
+		model := simulator.VPX()
+		g.Expect(model.Create()).To(Succeed())
+
+		simulator.Run(func(ctx context.Context, c *vim25.Client) error {
+			before(ctx, c, model, infrav1.InstantClone)
+
+			// The CPUs and memory of the source VM are kept.
+			result, ok, err := vms.reconcileResize(ctx, vmCtx)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(ok).To(BeTrue())
+			g.Expect(result.IsZero()).To(BeTrue())
+			g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+			g.Expect(conditions.Has(vmCtx.VSphereVM, infrav1.VMResizedCondition)).To(BeFalse())
+			return nil
+		}, model)
+	})
+
+	t.Run("when the guest is shut down for the resize it is requeued until the timeout", func(t *testing.T) {
+		g = NewWithT(t)
+		model := simulator.VPX()
+		g.Expect(model.Create()).To(Succeed())
+
+		simulator.Run(func(ctx context.Context, c *vim25.Client) error {
+			before(ctx, c, model, infrav1.FullClone)
+			vmCtx.VSphereVM.Status.Conditions = clusterv1.Conditions{{
+				Type:               infrav1.GuestSoftPowerOffSucceededCondition,
+				Status:             corev1.ConditionFalse,
+				Reason:             infrav1.GuestSoftPowerOffInProgressReason,
+				LastTransitionTime: metav1.NewTime(time.Now().Add(-2 * time.Minute)),
+			}}
+
+			result, ok, err := vms.reconcileResize(ctx, vmCtx)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(ok).To(BeFalse())
+			g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+			g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.VMResizedCondition)).To(Equal(infrav1.PowerCyclingReason))
+			g.Expect(result.RequeueAfter).To(BeNumerically(">", 50*time.Second))
+			g.Expect(result.RequeueAfter).To(BeNumerically("<=", time.Minute))
+			return nil
+		}, model)
+	})
+}
+
+func Test_reconcileDrift(t *testing.T) {
+	var vmCtx *virtualMachineContext
+	var g *WithT
+	var vms *VMService
+
+	// before matches the VM of the simulator with the spec of the VSphereVM.
+	before := func(ctx context.Context, c *vim25.Client, model *simulator.Model, policy infrav1.VirtualMachineDriftPolicy) {
+		vmCtx = emptyVirtualMachineContext()
+		vmCtx.Client = fake.NewClientBuilder().Build()
+		vms = &VMService{}
+
+		authSession, err := getAuthSession(ctx, model.Service.Listen.Host)
+		g.Expect(err).ToNot(HaveOccurred())
+		vmCtx.Session = authSession
+
+		vm, err := find.NewFinder(c).VirtualMachine(ctx, "DC0_H0_VM0")
+		g.Expect(err).ToNot(HaveOccurred())
+		reconfigure(ctx, g, vm, types.VirtualMachineConfigSpec{NumCPUs: 2, NumCoresPerSocket: 2, MemoryMB: 2048})
+		vmCtx.Obj = vm
+		vmCtx.Ref = vm.Reference()
+		vmCtx.VSphereVM = &infrav1.VSphereVM{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "vsphereVM1",
+				Namespace: "my-namespace",
+			},
+			Spec: infrav1.VSphereVMSpec{
+				VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+					Server:    model.Service.Listen.Host,
+					NumCPUs:   2,
+					MemoryMiB: 2048,
+					Network: infrav1.NetworkSpec{
+						Devices: []infrav1.NetworkDeviceSpec{{NetworkName: "DC0_DVPG0"}},
+					},
+					ResourcePool: "DC0_H0/Resources",
+				},
+				DriftPolicy: policy,
+			},
+			Status: infrav1.VSphereVMStatus{
+				Ready: true,
+			},
+		}
+	}
+
+	t.Run("when the VSphereVM has not been ready the VM is not compared", func(t *testing.T) {
+		g = NewWithT(t)
+		model := simulator.VPX()
+		g.Expect(model.Create()).To(Succeed())
+
+		simulator.Run(func(ctx context.Context, c *vim25.Client) error {
+			before(ctx, c, model, infrav1.VirtualMachineDriftPolicyRemediate)
+			vmCtx.VSphereVM.Status.Ready = false
+			reconfigure(ctx, g, vmCtx.Obj, types.VirtualMachineConfigSpec{NumCPUs: 4})
+
+			g.Expect(vms.reconcileDrift(ctx, vmCtx)).To(BeTrue())
+			g.Expect(vmCtx.VSphereVM.Status.FailureReason).To(BeNil())
+			g.Expect(conditions.Has(vmCtx.VSphereVM, infrav1.VMConfigurationInSyncCondition)).To(BeFalse())
+			return nil
+		}, model)
+	})
+
+	t.Run("when the VM matches its spec", func(t *testing.T) {
+		g = NewWithT(t)
+		model := simulator.VPX()
+		g.Expect(model.Create()).To(Succeed())
+
+		simulator.Run(func(ctx context.Context, c *vim25.Client) error {
+			before(ctx, c, model, "")
+			g.Expect(vms.reconcileDrift(ctx, vmCtx)).To(BeTrue())
+			g.Expect(conditions.IsTrue(vmCtx.VSphereVM, infrav1.VMConfigurationInSyncCondition)).To(BeTrue())
+			return nil
+		}, model)
+	})
+
+	t.Run("when the VM is an instant clone its CPUs and memory are not compared", func(t *testing.T) {
+		g = NewWithT(t)
+		model := simulator.VPX()
+		g.Expect(model.Create()).To(Succeed())
+
+		simulator.Run(func(ctx context.Context, c *vim25.Client) error {
+			before(ctx, c, model, infrav1.VirtualMachineDriftPolicyRemediate)
+			vmCtx.VSphereVM.Spec.CloneMode = infrav1.InstantClone
+			// The CPUs and memory are inherited from the source VM.
+			reconfigure(ctx, g, vmCtx.Obj, types.VirtualMachineConfigSpec{NumCPUs: 4, MemoryMB: 4096})
+
+			g.Expect(vms.reconcileDrift(ctx, vmCtx)).To(BeTrue())
+			g.Expect(vmCtx.VSphereVM.Status.FailureReason).To(BeNil())
+			g.Expect(conditions.IsTrue(vmCtx.VSphereVM, infrav1.VMConfigurationInSyncCondition)).To(BeTrue())
+			return nil
+		}, model)
+	})
+
+	t.Run("when the CPUs of the VM were changed the drift is reported", func(t *testing.T) {
+		g = NewWithT(t)
+		model := simulator.VPX()
+		g.Expect(model.Create()).To(Succeed())
+
+		simulator.Run(func(ctx context.Context, c *vim25.Client) error {
+			before(ctx, c, model, infrav1.VirtualMachineDriftPolicyReport)
+			reconfigure(ctx, g, vmCtx.Obj, types.VirtualMachineConfigSpec{NumCPUs: 4})
+
+			g.Expect(vms.reconcileDrift(ctx, vmCtx)).To(BeTrue())
+			g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+			condition := conditions.Get(vmCtx.VSphereVM, infrav1.VMConfigurationInSyncCondition)
+			g.Expect(condition).ToNot(BeNil())
+			g.Expect(condition.Reason).To(Equal(infrav1.DriftDetectedReason))
+			g.Expect(condition.Severity).To(Equal(clusterv1.ConditionSeverityWarning))
+			g.Expect(condition.Message).To(Equal("numCPUs (4 instead of 2)"))
+			return nil
+		}, model)
+	})
+
+	t.Run("when the network and folder of the VM were changed the drift is corrected", func(t *testing.T) {
+		g = NewWithT(t)
+		model := simulator.VPX()
+		g.Expect(model.Create()).To(Succeed())
+
+		simulator.Run(func(ctx context.Context, c *vim25.Client) error {
+			before(ctx, c, model, infrav1.VirtualMachineDriftPolicyCorrect)
+			vmFolder, err := vmCtx.Session.Finder.DefaultFolder(ctx)
+			g.Expect(err).ToNot(HaveOccurred())
+			otherFolder, err := vmFolder.CreateFolder(ctx, "other")
+			g.Expect(err).ToNot(HaveOccurred())
+			task, err := otherFolder.MoveInto(ctx, []types.ManagedObjectReference{vmCtx.Ref})
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(task.Wait(ctx)).To(Succeed())
+			vmCtx.VSphereVM.Spec.Network.Devices[0].NetworkName = "VM Network"
+
+			// The network is corrected first.
+			g.Expect(vms.reconcileDrift(ctx, vmCtx)).To(BeFalse())
+			g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.VMConfigurationInSyncCondition)).To(Equal(infrav1.CorrectingDriftReason))
+			g.Expect(conditions.GetMessage(vmCtx.VSphereVM, infrav1.VMConfigurationInSyncCondition)).To(Equal("network.devices[0].networkName, folder"))
+			waitForTaskRef(ctx, g, c, vmCtx)
+			vmCtx.properties = nil
+
+			g.Expect(vms.reconcileDrift(ctx, vmCtx)).To(BeFalse())
+			g.Expect(conditions.GetMessage(vmCtx.VSphereVM, infrav1.VMConfigurationInSyncCondition)).To(Equal("folder"))
+			waitForTaskRef(ctx, g, c, vmCtx)
+			vmCtx.properties = nil
+
+			g.Expect(vms.reconcileDrift(ctx, vmCtx)).To(BeTrue())
+			g.Expect(conditions.IsTrue(vmCtx.VSphereVM, infrav1.VMConfigurationInSyncCondition)).To(BeTrue())
+			return nil
+		}, model)
+	})
+
+	t.Run("when the memory of the VM was changed the machine is failed for remediation", func(t *testing.T) {
+		g = NewWithT(t)
+		model := simulator.VPX()
+		g.Expect(model.Create()).To(Succeed())
+
+		simulator.Run(func(ctx context.Context, c *vim25.Client) error {
+			before(ctx, c, model, infrav1.VirtualMachineDriftPolicyRemediate)
+			reconfigure(ctx, g, vmCtx.Obj, types.VirtualMachineConfigSpec{MemoryMB: 4096})
+
+			g.Expect(vms.reconcileDrift(ctx, vmCtx)).To(BeFalse())
+			g.Expect(vmCtx.VSphereVM.Status.FailureReason).ToNot(BeNil())
+			g.Expect(*vmCtx.VSphereVM.Status.FailureReason).To(Equal(capierrors.UpdateMachineError))
+			g.Expect(vmCtx.VSphereVM.Status.FailureMessage).To(Equal(pointer.String("VM configuration drifted from spec: memoryMiB (4096 instead of 2048)")))
+			condition := conditions.Get(vmCtx.VSphereVM, infrav1.VMConfigurationInSyncCondition)
+			g.Expect(condition).ToNot(BeNil())
+			g.Expect(condition.Severity).To(Equal(clusterv1.ConditionSeverityError))
+			return nil
+		}, model)
+	})
+}
+
+func reconfigure(ctx context.Context, g *WithT, vm *object.VirtualMachine, configSpec types.VirtualMachineConfigSpec) {
+	task, err := vm.Reconfigure(ctx, configSpec)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(task.Wait(ctx)).To(Succeed())
+}
+
+// waitForTaskRef waits for the task of the VSphereVM, which is then reset as
+// by reconcileInFlightTask.
+func waitForTaskRef(ctx context.Context, g *WithT, c *vim25.Client, vmCtx *virtualMachineContext) {
+	g.Expect(vmCtx.VSphereVM.Status.TaskRef).ToNot(BeEmpty())
+	task := object.NewTask(c, types.ManagedObjectReference{Type: "Task", Value: vmCtx.VSphereVM.Status.TaskRef})
+	g.Expect(task.Wait(ctx)).To(Succeed())
+	vmCtx.VSphereVM.Status.TaskRef = ""
+}
+
+func Test_reconcileInstanceUUID(t *testing.T) {
+	g := NewWithT(t)
+	model := simulator.VPX()
+	g.Expect(model.Create()).To(Succeed())
+
+	simulator.Run(func(ctx context.Context, c *vim25.Client) error {
+		vmCtx := emptyVirtualMachineContext()
+		vms := &VMService{}
+
+		authSession, err := getAuthSession(ctx, model.Service.Listen.Host)
+		g.Expect(err).ToNot(HaveOccurred())
+		vmCtx.Session = authSession
+
+		vm, err := find.NewFinder(c).VirtualMachine(ctx, "DC0_H0_VM0")
+		g.Expect(err).ToNot(HaveOccurred())
+		vmCtx.Obj = vm
+		vmCtx.Ref = vm.Reference()
+		vmCtx.VSphereVM = &infrav1.VSphereVM{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "vsphereVM1",
+				Namespace: "my-namespace",
+				UID:       "d3b07384-d113-4ec6-a3e8-4dd1a7c4c5b7",
+			},
+		}
+
+		// Only instant clones are reconfigured.
+		g.Expect(vms.reconcileInstanceUUID(ctx, vmCtx)).To(BeTrue())
+		g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+
+		vmCtx.VSphereVM.Status.CloneMode = infrav1.InstantClone
+		g.Expect(vms.reconcileInstanceUUID(ctx, vmCtx)).To(BeFalse())
+		waitForTaskRef(ctx, g, c, vmCtx)
+
+		vmCtx.properties = nil
+		g.Expect(vms.reconcileInstanceUUID(ctx, vmCtx)).To(BeTrue())
+		g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+		return nil
+	}, model)
+}
+
+func Test_reconcileBootstrapDataCleanup(t *testing.T) {
+	g := NewWithT(t)
+	model := simulator.VPX()
+	g.Expect(model.Create()).To(Succeed())
+
+	simulator.Run(func(ctx context.Context, c *vim25.Client) error {
+		vmCtx := emptyVirtualMachineContext()
+		vms := &VMService{}
+
+		authSession, err := getAuthSession(ctx, model.Service.Listen.Host)
+		g.Expect(err).ToNot(HaveOccurred())
+		vmCtx.Session = authSession
+
+		vm, err := find.NewFinder(c).VirtualMachine(ctx, "DC0_H0_VM0")
+		g.Expect(err).ToNot(HaveOccurred())
+		var extraConfig extra.Config
+		extraConfig.SetCloudInitUserData([]byte("#cloud-config"))
+		reconfigure(ctx, g, vm, types.VirtualMachineConfigSpec{ExtraConfig: extraConfig})
+		vmCtx.Obj = vm
+		vmCtx.Ref = vm.Reference()
+		vmCtx.VSphereVM = &infrav1.VSphereVM{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "vsphereVM1",
+				Namespace: "my-namespace",
+			},
+			Spec: infrav1.VSphereVMSpec{
+				VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+					BootstrapDataCleanupPolicy: infrav1.BootstrapDataCleanupPolicyBootstrapSentinel,
+				},
+			},
+		}
+
+		// The bootstrap data is kept until the guest reports the success
+		// of the bootstrap.
+		g.Expect(vms.reconcileBootstrapDataCleanup(ctx, vmCtx)).To(BeTrue())
+		g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+
+		reconfigure(ctx, g, vm, types.VirtualMachineConfigSpec{ExtraConfig: []types.BaseOptionValue{
+			&types.OptionValue{Key: "guestinfo.cluster-api.bootstrap-status", Value: "success"},
+		}})
+		vmCtx.properties = nil
+		g.Expect(vms.reconcileBootstrapDataCleanup(ctx, vmCtx)).To(BeFalse())
+		waitForTaskRef(ctx, g, c, vmCtx)
+
+		vmCtx.properties = nil
+		g.Expect(vms.reconcileBootstrapDataCleanup(ctx, vmCtx)).To(BeTrue())
+		g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+		return nil
+	}, model)
+}
+
+func Test_buildAdapterMappings(t *testing.T) {
+	g := NewWithT(t)
+
+	devices := []infrav1.NetworkDeviceSpec{
+		{NetworkName: "dhcp", DHCP4: true},
+		{
+			NetworkName: "static",
+			IPAddrs:     []string{"192.168.1.10/24", "fd00::10/64"},
+			Gateway4:    "192.168.1.1",
+			Nameservers: []string{"192.168.1.2"},
+		},
+		{NetworkName: "ipam"},
+	}
+	ipamState := map[string]infrav1.NetworkDeviceSpec{
+		"00:00:00:00:00:03": {IPAddrs: []string{"10.0.0.5/16"}, Gateway4: "10.0.0.1"},
+	}
+	networkStatus := []infrav1.NetworkStatus{
+		{MACAddr: "00:00:00:00:00:01"},
+		{MACAddr: "00:00:00:00:00:02"},
+		{MACAddr: "00:00:00:00:00:03"},
+	}
+
+	mappings, err := buildAdapterMappings(devices, ipamState, networkStatus)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(mappings).To(HaveLen(3))
+
+	g.Expect(mappings[0].MacAddress).To(Equal("00:00:00:00:00:01"))
+	g.Expect(mappings[0].Adapter.Ip).To(BeAssignableToTypeOf(&types.CustomizationDhcpIpGenerator{}))
+
+	g.Expect(mappings[1].Adapter.Ip).To(Equal(&types.CustomizationFixedIp{IpAddress: "192.168.1.10"}))
+	g.Expect(mappings[1].Adapter.SubnetMask).To(Equal("255.255.255.0"))
+	g.Expect(mappings[1].Adapter.Gateway).To(Equal([]string{"192.168.1.1"}))
+	g.Expect(mappings[1].Adapter.DnsServerList).To(Equal([]string{"192.168.1.2"}))
+	g.Expect(mappings[1].Adapter.IpV6Spec).ToNot(BeNil())
+	g.Expect(mappings[1].Adapter.IpV6Spec.Ip).To(Equal([]types.BaseCustomizationIpV6Generator{&types.CustomizationFixedIpV6{IpAddress: "fd00::10", SubnetMask: 64}}))
+
+	g.Expect(mappings[2].Adapter.Ip).To(Equal(&types.CustomizationFixedIp{IpAddress: "10.0.0.5"}))
+	g.Expect(mappings[2].Adapter.SubnetMask).To(Equal("255.255.0.0"))
+	g.Expect(mappings[2].Adapter.Gateway).To(Equal([]string{"10.0.0.1"}))
+
+	_, err = buildAdapterMappings(devices, ipamState, networkStatus[:1])
+	g.Expect(err).To(HaveOccurred())
+}
+
+func Test_renderSysprep(t *testing.T) {
+	g := NewWithT(t)
+
+	sysprep, err := renderSysprep("<ComputerName>{{ .Hostname }}</ComputerName>", "win-node-0", bootstrapRunOnceCommand)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(sysprep).To(Equal("<ComputerName>win-node-0</ComputerName>"))
+
+	sysprep, err = renderSysprep("<CommandLine>{{ .BootstrapCommand }}</CommandLine>", "win-node-0", `run "bootstrap" & exit`)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(sysprep).To(Equal("<CommandLine>run &#34;bootstrap&#34; &amp; exit</CommandLine>"))
+}
+
+func Test_updateCustomizationCondition(t *testing.T) {
+	g := NewWithT(t)
+	vmCtx := emptyVirtualMachineContext()
+	vmCtx.VSphereVM = &infrav1.VSphereVM{}
+	conditions.MarkFalse(vmCtx.VSphereVM, infrav1.GuestCustomizedCondition, infrav1.GuestCustomizationPendingReason, clusterv1.ConditionSeverityInfo, "")
+
+	// The customization stays pending until VMware Tools reports its status.
+	updateCustomizationCondition(vmCtx, &types.GuestInfo{})
+	g.Expect(conditions.GetReason(vmCtx.VSphereVM, infrav1.GuestCustomizedCondition)).To(Equal(infrav1.GuestCustomizationPendingReason))
+
+	updateCustomizationCondition(vmCtx, &types.GuestInfo{CustomizationInfo: &types.GuestInfoCustomizationInfo{CustomizationStatus: customizationStatusSucceeded}})
+	g.Expect(conditions.IsTrue(vmCtx.VSphereVM, infrav1.GuestCustomizedCondition)).To(BeTrue())
+}
+
 func Test_ReconcileStoragePolicy(t *testing.T) {
 	var vmCtx *virtualMachineContext
 	var g *WithT
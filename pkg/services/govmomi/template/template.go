@@ -14,7 +14,7 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// Package template has tools for finding VM templates.
+// Package template has tools for finding VM templates and Content Library items.
 package template
 
 import (
@@ -24,7 +24,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vapi/library"
 
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
 )
 
@@ -69,6 +71,78 @@ func findTemplateByName(ctx context.Context, tplctx tplContext, templateID strin
 	return tpl, nil
 }
 
+// FindLibraryItem finds a Content Library item based either on its ID or name.
+// Only OVF and VM template items are returned, as no other item type can be
+// used as the source of a virtual machine.
+func FindLibraryItem(ctx context.Context, tplctx tplContext, src infrav1.ContentLibraryItem) (*library.Item, error) {
+	manager := library.NewManager(tplctx.GetSession().TagManager.Client)
+
+	tplctx.GetLogger().V(6).Info("find content library by name", "library", src.Library)
+	lib, err := manager.GetLibraryByName(ctx, src.Library)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to find content library %q", src.Library)
+	}
+
+	item, err := findLibraryItemByID(ctx, tplctx, manager, lib, src.Item)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		item, err = findLibraryItemByName(ctx, tplctx, manager, lib, src.Item)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch item.Type {
+	case library.ItemTypeOVF, library.ItemTypeVMTX:
+		return item, nil
+	default:
+		return nil, errors.Errorf("content library item %q in library %q has unsupported type %q", src.Item, src.Library, item.Type)
+	}
+}
+
+func findLibraryItemByID(ctx context.Context, tplctx tplContext, manager *library.Manager, lib *library.Library, itemID string) (*library.Item, error) {
+	if !isValidUUID(itemID) {
+		return nil, nil
+	}
+	tplctx.GetLogger().V(6).Info("find content library item by id", "library", lib.Name, "item-id", itemID)
+	ids, err := manager.ListLibraryItems(ctx, lib.ID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing items of content library %q", lib.Name)
+	}
+	for _, id := range ids {
+		if id == itemID {
+			item, err := manager.GetLibraryItem(ctx, id)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error querying content library item %q", id)
+			}
+			return item, nil
+		}
+	}
+	return nil, nil
+}
+
+func findLibraryItemByName(ctx context.Context, tplctx tplContext, manager *library.Manager, lib *library.Library, itemName string) (*library.Item, error) {
+	tplctx.GetLogger().V(6).Info("find content library item by name", "library", lib.Name, "name", itemName)
+	ids, err := manager.FindLibraryItems(ctx, library.FindItem{LibraryID: lib.ID, Name: itemName})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying content library item by name %q", itemName)
+	}
+	switch len(ids) {
+	case 0:
+		return nil, errors.Errorf("unable to find content library item %q in library %q", itemName, lib.Name)
+	case 1:
+	default:
+		return nil, errors.Errorf("found %d content library items named %q in library %q", len(ids), itemName, lib.Name)
+	}
+	item, err := manager.GetLibraryItem(ctx, ids[0])
+	if err != nil {
+		return nil, errors.Wrapf(err, "error querying content library item %q", ids[0])
+	}
+	return item, nil
+}
+
 func isValidUUID(str string) bool {
 	_, err := uuid.Parse(str)
 	return err == nil
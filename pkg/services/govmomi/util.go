@@ -18,23 +18,27 @@ package govmomi
 
 import (
 	"context"
-	gonet "net"
+	"fmt"
 	"path"
 	"time"
 
 	"github.com/pkg/errors"
-	"github.com/vmware/govmomi/object"
-	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
 	"sigs.k8s.io/cluster-api/util/conditions"
-	"sigs.k8s.io/controller-runtime/pkg/event"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/metrics"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/faults"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/net"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/vcenter"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/watcher"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
 )
 
 func sanitizeIPAddrs(vmCtx *capvcontext.VMContext, ipAddrs []string) []string {
@@ -59,19 +63,34 @@ func sanitizeIPAddrs(vmCtx *capvcontext.VMContext, ipAddrs []string) []string {
 //  3. If it is not found by instance UUID, fallback to an inventory path search
 //     using the vm folder path and the VSphereVM name
 func findVM(ctx context.Context, vmCtx *capvcontext.VMContext) (types.ManagedObjectReference, error) {
+	ref, _, err := findVMWithProperties(ctx, vmCtx)
+	return ref, err
+}
+
+// findVMWithProperties searches for a VM like findVM. When the VM is found by
+// BIOS UUID, it also returns the snapshot of its properties, which is
+// retrieved in the same batch as the properties of other VMs, and nil
+// otherwise.
+func findVMWithProperties(ctx context.Context, vmCtx *capvcontext.VMContext) (types.ManagedObjectReference, *session.VirtualMachine, error) {
 	if biosUUID := vmCtx.VSphereVM.Spec.BiosUUID; biosUUID != "" {
-		objRef, err := vmCtx.Session.FindByBIOSUUID(ctx, biosUUID)
+		vm, err := vmCtx.Session.FindVirtualMachineByBIOSUUID(ctx, biosUUID)
 		if err != nil {
-			return types.ManagedObjectReference{}, err
+			return types.ManagedObjectReference{}, nil, err
 		}
-		if objRef == nil {
+		if vm == nil {
 			vmCtx.Logger.Info("vm not found by bios uuid", "biosuuid", biosUUID)
-			return types.ManagedObjectReference{}, errNotFound{uuid: biosUUID}
+			return types.ManagedObjectReference{}, nil, errNotFound{uuid: biosUUID}
 		}
-		vmCtx.Logger.Info("vm found by bios uuid", "vmref", objRef.Reference())
-		return objRef.Reference(), nil
+		vmCtx.Logger.Info("vm found by bios uuid", "vmref", vm.Reference())
+		return vm.Reference(), vm, nil
 	}
 
+	ref, err := findVMByInstanceUUIDOrPath(ctx, vmCtx)
+	return ref, nil, err
+}
+
+func findVMByInstanceUUIDOrPath(ctx context.Context, vmCtx *capvcontext.VMContext) (types.ManagedObjectReference, error) {
+
 	instanceUUID := string(vmCtx.VSphereVM.UID)
 	objRef, err := vmCtx.Session.FindByInstanceUUID(ctx, instanceUUID)
 	if err != nil {
@@ -92,6 +111,17 @@ func findVM(ctx context.Context, vmCtx *capvcontext.VMContext) (types.ManagedObj
 			}
 			return types.ManagedObjectReference{}, err
 		}
+		// A VM deployed from a Content Library item is reconfigured with
+		// the instance UUID after the deployment. Until then it is reported
+		// as not found, so Clone reuses it and retries the reconfiguration.
+		unconfigured, err := vcenter.IsUnconfiguredLibraryItem(ctx, vmCtx, vm)
+		if err != nil {
+			return types.ManagedObjectReference{}, err
+		}
+		if unconfigured {
+			vmCtx.Logger.Info("vm deployed from content library item is not configured yet", "vmref", vm.Reference())
+			return types.ManagedObjectReference{}, errNotFound{byInventoryPath: inventoryPath}
+		}
 		vmCtx.Logger.Info("vm found by name", "vmref", vm.Reference())
 		return vm.Reference(), nil
 	}
@@ -150,10 +180,13 @@ func checkAndRetryTask(vmCtx *capvcontext.VMContext, task *mo.Task) (bool, error
 		return true, nil
 	case types.TaskInfoStateSuccess:
 		logger.Info("task is a success", "description-id", task.Info.DescriptionId)
+		observeTaskDuration(vmCtx, task)
 		vmCtx.VSphereVM.Status.TaskRef = ""
+		vmCtx.VSphereVM.Status.TaskRetries = 0
 		return false, nil
 	case types.TaskInfoStateError:
-		logger.Info("task failed", "description-id", task.Info.DescriptionId)
+		class, fault := faults.Classify(task.Info.Error), faults.Name(task.Info.Error)
+		logger.Info("task failed", "description-id", task.Info.DescriptionId, "fault", fault, "class", class)
 
 		// NOTE: When a task fails there is not simple way to understand which operation is failing (e.g. cloning or powering on)
 		// so we are reporting failures using a dedicated reason until we find a better solution.
@@ -162,12 +195,32 @@ func checkAndRetryTask(vmCtx *capvcontext.VMContext, task *mo.Task) (bool, error
 		if task.Info.Error != nil {
 			errorMessage = task.Info.Error.LocalizedMessage
 		}
+
+		// A terminal fault fails the VSphereVM, and in turn its VSphereMachine,
+		// so that the Machine can be remediated instead of retrying the task
+		// forever. Tasks of deleted VSphereVMs are always retried.
+		if class == faults.Terminal && vmCtx.VSphereVM.DeletionTimestamp.IsZero() {
+			metrics.TaskFailuresTotal.WithLabelValues(vmCtx.VSphereVM.Spec.Server, fault, string(class)).Inc()
+			failureReason := capierrors.UpdateMachineError
+			if vmCtx.VSphereVM.Spec.BiosUUID == "" {
+				failureReason = capierrors.CreateMachineError
+			}
+			vmCtx.VSphereVM.Status.FailureReason = &failureReason
+			vmCtx.VSphereVM.Status.FailureMessage = pointer.String(fmt.Sprintf("task %s failed with fault %s: %s", task.Info.DescriptionId, fault, errorMessage))
+			conditions.MarkFalse(vmCtx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.TaskFailure, clusterv1.ConditionSeverityError, errorMessage)
+			vmCtx.VSphereVM.Status.TaskRef = ""
+			vmCtx.VSphereVM.Status.RetryAfter = metav1.Time{}
+			return true, nil
+		}
 		conditions.MarkFalse(vmCtx.VSphereVM, infrav1.VMProvisionedCondition, infrav1.TaskFailure, clusterv1.ConditionSeverityInfo, errorMessage)
 
 		// Instead of directly requeuing the failed task, wait for the RetryAfter duration to pass
-		// before resetting the taskRef from the VSphereVM status.
+		// before resetting the taskRef from the VSphereVM status. The duration doubles with each
+		// consecutive failure.
 		if vmCtx.VSphereVM.Status.RetryAfter.IsZero() {
-			vmCtx.VSphereVM.Status.RetryAfter = metav1.Time{Time: time.Now().Add(1 * time.Minute)}
+			metrics.TaskFailuresTotal.WithLabelValues(vmCtx.VSphereVM.Spec.Server, fault, string(class)).Inc()
+			vmCtx.VSphereVM.Status.RetryAfter = metav1.Time{Time: time.Now().Add(taskRetryDelay(vmCtx.VSphereVM.Status.TaskRetries))}
+			vmCtx.VSphereVM.Status.TaskRetries++
 		} else {
 			vmCtx.VSphereVM.Status.TaskRef = ""
 			vmCtx.VSphereVM.Status.RetryAfter = metav1.Time{}
@@ -178,57 +231,46 @@ func checkAndRetryTask(vmCtx *capvcontext.VMContext, task *mo.Task) (bool, error
 	}
 }
 
-func reconcileVSphereVMWhenNetworkIsReady(ctx context.Context, virtualMachineCtx *virtualMachineContext, powerOnTask *object.Task) {
-	reconcileVSphereVMOnChannel(
-		&virtualMachineCtx.VMContext,
-		func() (<-chan []interface{}, <-chan error, error) {
-			// Wait for the VM to be powered on.
-			powerOnTaskInfo, err := powerOnTask.WaitForResult(ctx)
-			if err != nil && powerOnTaskInfo == nil {
-				return nil, nil, errors.Wrapf(err, "failed to wait for power on op for vm %s", ctx)
-			}
-			powerState, err := virtualMachineCtx.Obj.PowerState(ctx)
-			if err != nil {
-				return nil, nil, errors.Wrapf(err, "failed to get power state for vm %s", ctx)
-			}
-			if powerState != types.VirtualMachinePowerStatePoweredOn {
-				return nil, nil, errors.Errorf(
-					"unexpected power state %v for vm %s",
-					powerState, ctx)
-			}
-
-			// Wait for all NICs to have valid MAC addresses.
-			if err := waitForMacAddresses(ctx, virtualMachineCtx); err != nil {
-				return nil, nil, errors.Wrapf(err, "failed to wait for mac addresses for vm %s", ctx)
-			}
-
-			// Get all the MAC addresses. This is done separately from waiting
-			// for all NICs to have MAC addresses in order to ensure the order
-			// of the retrieved MAC addresses matches the order of the device
-			// specs, and not the propery change order.
-			_, macToDeviceIndex, deviceToMacIndex, err := getMacAddresses(ctx, virtualMachineCtx)
-			if err != nil {
-				return nil, nil, errors.Wrapf(err, "failed to get mac addresses for vm %s", ctx)
-			}
+// taskOperations maps the description IDs of the tasks of VSphereVMs to the
+// operations whose durations are observed.
+var taskOperations = map[string]string{
+	"VirtualMachine.clone":        "clone",
+	"VirtualMachine.instantClone": "clone",
+	"VirtualMachine.powerOn":      "power_on",
+	"VirtualMachine.reconfigure":  "reconfigure",
+	"VirtualMachine.destroy":      "destroy",
+}
 
-			// Wait for the IP addresses to show up for the VM.
-			chanIPAddresses, chanErrs := waitForIPAddresses(ctx, virtualMachineCtx, macToDeviceIndex, deviceToMacIndex)
+// observeTaskDuration observes the duration of a successful task, from being
+// queued to being completed, which includes the time vCenter was busy with
+// other tasks.
+func observeTaskDuration(vmCtx *capvcontext.VMContext, task *mo.Task) {
+	operation, ok := taskOperations[task.Info.DescriptionId]
+	if !ok || task.Info.CompleteTime == nil || task.Info.QueueTime.IsZero() {
+		return
+	}
+	metrics.OperationDurationSeconds.
+		WithLabelValues(vmCtx.VSphereVM.Spec.Server, vmCtx.VSphereVM.Spec.Datacenter, operation).
+		Observe(task.Info.CompleteTime.Sub(task.Info.QueueTime).Seconds())
+}
 
-			// Trigger a reconcile every time a new IP is discovered.
-			chanOfLoggerKeysAndValues := make(chan []interface{})
-			go func() {
-				for ip := range chanIPAddresses {
-					chanOfLoggerKeysAndValues <- []interface{}{
-						"reason", "network",
-						"ipAddress", ip,
-					}
-				}
-			}()
-			return chanOfLoggerKeysAndValues, chanErrs, nil
-		})
+// taskRetryDelay returns the delay before retrying a task which failed with a
+// transient fault after the given number of consecutive failures.
+func taskRetryDelay(retries int32) time.Duration {
+	delay := taskRetryBaseDelay
+	for i := int32(0); i < retries && delay < taskRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > taskRetryMaxDelay {
+		return taskRetryMaxDelay
+	}
+	return delay
 }
 
-func reconcileVSphereVMOnTaskCompletion(ctx context.Context, vmCtx *capvcontext.VMContext) {
+// watchTask triggers a reconcile event for the VSphereVM resource once its
+// associated task completes successfully. If there is no task for the
+// VSphereVM resource then no reconcile event is triggered.
+func watchTask(ctx context.Context, vmCtx *capvcontext.VMContext) {
 	task := getTask(ctx, vmCtx)
 	if task == nil {
 		vmCtx.Logger.V(4).Info(
@@ -237,7 +279,6 @@ func reconcileVSphereVMOnTaskCompletion(ctx context.Context, vmCtx *capvcontext.
 		return
 	}
 	taskRef := task.Reference()
-	taskHelper := object.NewTask(vmCtx.Session.Client.Client, taskRef)
 
 	vmCtx.Logger.Info(
 		"enqueuing reconcile request on task completion",
@@ -246,334 +287,7 @@ func reconcileVSphereVMOnTaskCompletion(ctx context.Context, vmCtx *capvcontext.
 		"task-entity-name", task.Info.EntityName,
 		"task-description-id", task.Info.DescriptionId)
 
-	reconcileVSphereVMOnFuncCompletion(ctx, vmCtx, func() ([]interface{}, error) {
-		taskInfo, err := taskHelper.WaitForResult(ctx)
-
-		// An error is only returned if the process of waiting for the result
-		// failed, *not* if the task itself failed.
-		if err != nil && taskInfo == nil {
-			return nil, err
-		}
-		// do not queue in the event channel when task fails as we don't
-		// want to retry right away
-		if taskInfo.State == types.TaskInfoStateError {
-			vmCtx.Logger.Info("async task wait failed")
-			return nil, errors.Errorf("task failed")
-		}
-
-		return []interface{}{
-			"reason", "task",
-			"task-ref", taskRef,
-			"task-name", taskInfo.Name,
-			"task-entity-name", taskInfo.EntityName,
-			"task-state", taskInfo.State,
-			"task-description-id", taskInfo.DescriptionId,
-		}, nil
-	})
-}
-
-func reconcileVSphereVMOnFuncCompletion(_ context.Context, vmCtx *capvcontext.VMContext, waitFn func() (loggerKeysAndValues []interface{}, _ error)) {
-	obj := vmCtx.VSphereVM.DeepCopy()
-	gvk := obj.GetObjectKind().GroupVersionKind()
-
-	// Wait on the function to complete in a background goroutine.
-	go func() {
-		loggerKeysAndValues, err := waitFn()
-		if err != nil {
-			vmCtx.Logger.Error(err, "failed to wait on func")
-			return
-		}
-
-		// Once the task has completed (successfully or otherwise), trigger
-		// a reconcile event for the associated resource by sending a
-		// GenericEvent into the event channel for the resource type.
-		vmCtx.Logger.Info("triggering GenericEvent", loggerKeysAndValues...)
-		eventChannel := vmCtx.GetGenericEventChannelFor(gvk)
-		eventChannel <- event.GenericEvent{
-			Object: obj,
-		}
-	}()
-}
-
-func reconcileVSphereVMOnChannel(vmCtx *capvcontext.VMContext, waitFn func() (<-chan []interface{}, <-chan error, error)) {
-	obj := vmCtx.VSphereVM.DeepCopy()
-	gvk := obj.GetObjectKind().GroupVersionKind()
-
-	// Send a generic event for every set of logger keys/values received
-	// on the channel.
-	go func() {
-		chanOfLoggerKeysAndValues, chanErrs, err := waitFn()
-		if err != nil {
-			vmCtx.Logger.Error(err, "failed to wait on func")
-			return
-		}
-		for {
-			select {
-			case loggerKeysAndValues := <-chanOfLoggerKeysAndValues:
-				if loggerKeysAndValues == nil {
-					return
-				}
-				go func() {
-					// Trigger a reconcile event for the associated resource by
-					// sending a GenericEvent into the event channel for the resource
-					// type.
-					vmCtx.Logger.Info("triggering GenericEvent", loggerKeysAndValues...)
-					eventChannel := vmCtx.GetGenericEventChannelFor(gvk)
-					eventChannel <- event.GenericEvent{
-						Object: obj,
-					}
-				}()
-			case err := <-chanErrs:
-				if err != nil {
-					vmCtx.Logger.Error(err, "error occurred while waiting to trigger a generic event")
-				}
-				return
-			}
-		}
-	}()
-}
-
-// waitForMacAddresses waits for all configured network devices to have
-// valid MAC addresses.
-func waitForMacAddresses(ctx context.Context, virtualMachineCtx *virtualMachineContext) error {
-	return property.Wait(
-		ctx, property.DefaultCollector(virtualMachineCtx.Session.Client.Client),
-		virtualMachineCtx.Obj.Reference(), []string{"config.hardware.device"},
-		func(propertyChanges []types.PropertyChange) bool {
-			for _, propChange := range propertyChanges {
-				if propChange.Op != types.PropertyChangeOpAssign {
-					continue
-				}
-				deviceList := object.VirtualDeviceList(propChange.Val.(types.ArrayOfVirtualDevice).VirtualDevice)
-				for _, dev := range deviceList {
-					if nic, ok := dev.(types.BaseVirtualEthernetCard); ok {
-						mac := nic.GetVirtualEthernetCard().MacAddress
-						if mac == "" {
-							return false
-						}
-					}
-				}
-			}
-			return true
-		})
-}
-
-// getMacAddresses gets the MAC addresses for all network devices.
-// This happens separately from waitForMacAddresses to ensure returned order of
-// devices matches the spec and not order in which the property changes were
-// noticed.
-func getMacAddresses(ctx context.Context, virtualMachineCtx *virtualMachineContext) ([]string, map[string]int, map[int]string, error) {
-	var (
-		vm                   mo.VirtualMachine
-		macAddresses         = make([]string, 0)
-		macToDeviceSpecIndex = map[string]int{}
-		deviceSpecIndexToMac = map[int]string{}
-	)
-	if err := virtualMachineCtx.Obj.Properties(ctx, virtualMachineCtx.Obj.Reference(), []string{"config.hardware.device"}, &vm); err != nil {
-		return nil, nil, nil, err
-	}
-	i := 0
-	for _, device := range vm.Config.Hardware.Device {
-		nic, ok := device.(types.BaseVirtualEthernetCard)
-		if !ok {
-			continue
-		}
-		mac := nic.GetVirtualEthernetCard().MacAddress
-		macAddresses = append(macAddresses, mac)
-		macToDeviceSpecIndex[mac] = i
-		deviceSpecIndexToMac[i] = mac
-		i++
-	}
-	return macAddresses, macToDeviceSpecIndex, deviceSpecIndexToMac, nil
-}
-
-// waitForIPAddresses waits for all network devices that should be getting an
-// IP address to have an IP address. This is any network device that specifies a
-// network name and DHCP for v4 or v6 or one or more static IP addresses.
-func waitForIPAddresses(
-	ctx context.Context,
-	virtualMachineCtx *virtualMachineContext,
-	macToDeviceIndex map[string]int,
-	deviceToMacIndex map[int]string) (<-chan string, <-chan error) {
-	var (
-		chanErrs          = make(chan error)
-		chanIPAddresses   = make(chan string)
-		macToHasIPv4Lease = map[string]struct{}{}
-		macToHasIPv6Lease = map[string]struct{}{}
-		macToSkipped      = map[string]map[string]struct{}{}
-		macToHasStaticIP  = map[string]map[string]struct{}{}
-		propCollector     = property.DefaultCollector(virtualMachineCtx.Session.Client.Client)
-	)
-
-	// Initialize the nested maps early.
-	for mac := range macToDeviceIndex {
-		macToSkipped[mac] = map[string]struct{}{}
-		macToHasStaticIP[mac] = map[string]struct{}{}
+	if err := watcher.Watch(ctx, vmCtx, taskRef); err != nil {
+		vmCtx.Logger.Error(err, "failed to watch task", "task-ref", taskRef)
 	}
-
-	onPropertyChange := func(propertyChanges []types.PropertyChange) bool {
-		for _, propChange := range propertyChanges {
-			if propChange.Op != types.PropertyChangeOpAssign {
-				continue
-			}
-			nics := propChange.Val.(types.ArrayOfGuestNicInfo).GuestNicInfo
-			for _, nic := range nics {
-				mac := nic.MacAddress
-				if mac == "" || nic.IpConfig == nil {
-					continue
-				}
-				// Ignore any that don't correspond to a network
-				// device spec.
-				deviceSpecIndex, ok := macToDeviceIndex[mac]
-				if !ok {
-					chanErrs <- errors.Errorf("unknown device spec index for mac %s while waiting for ip addresses for vm %s", mac, virtualMachineCtx)
-					// Return true to stop the property collector from waiting
-					// on any more changes.
-					return true
-				}
-				if deviceSpecIndex < 0 || deviceSpecIndex >= len(virtualMachineCtx.VSphereVM.Spec.Network.Devices) {
-					chanErrs <- errors.Errorf("invalid device spec index %d for mac %s while waiting for ip addresses for vm %s", deviceSpecIndex, mac, virtualMachineCtx)
-					// Return true to stop the property collector from waiting
-					// on any more changes.
-					return true
-				}
-
-				// Get the network device spec that corresponds to the MAC.
-				deviceSpec := virtualMachineCtx.VSphereVM.Spec.Network.Devices[deviceSpecIndex]
-
-				// Look at each IP and determine whether a reconcile has
-				// been triggered for the IP.
-				for _, discoveredIPInfo := range nic.IpConfig.IpAddress {
-					discoveredIP := discoveredIPInfo.IpAddress
-
-					// Ignore link-local addresses.
-					if err := net.ErrOnLocalOnlyIPAddr(discoveredIP); err != nil {
-						if _, ok := macToSkipped[mac][discoveredIP]; !ok {
-							virtualMachineCtx.Logger.Info("ignoring IP address", "reason", err.Error())
-							macToSkipped[mac][discoveredIP] = struct{}{}
-						}
-						continue
-					}
-
-					// Check to see if the IP is in the list of the device
-					// spec's static IP addresses.
-					isStatic := false
-					for _, specIP := range deviceSpec.IPAddrs {
-						// The static IP assigned to the VM is required in the CIDR format
-						ip, _, _ := gonet.ParseCIDR(specIP)
-						if discoveredIP == ip.String() {
-							isStatic = true
-							break
-						}
-					}
-
-					// If it's a static IP then check to see if the IP has
-					// triggered a reconcile yet.
-					switch {
-					case isStatic:
-						if _, ok := macToHasStaticIP[mac][discoveredIP]; !ok {
-							// No reconcile yet. Record the IP send it to the
-							// channel.
-							virtualMachineCtx.Logger.Info(
-								"discovered IP address",
-								"addressType", "static",
-								"addressValue", discoveredIP)
-							macToHasStaticIP[mac][discoveredIP] = struct{}{}
-							chanIPAddresses <- discoveredIP
-						}
-					case gonet.ParseIP(discoveredIP).To4() != nil:
-						// An IPv4 address...
-						if deviceSpec.DHCP4 {
-							// Has an IPv4 lease been discovered yet?
-							if _, ok := macToHasIPv4Lease[mac]; !ok {
-								virtualMachineCtx.Logger.Info(
-									"discovered IP address",
-									"addressType", "dhcp4",
-									"addressValue", discoveredIP)
-								macToHasIPv4Lease[mac] = struct{}{}
-								chanIPAddresses <- discoveredIP
-							}
-						}
-					default:
-						// An IPv6 address..
-						if deviceSpec.DHCP6 {
-							// Has an IPv6 lease been discovered yet?
-							if _, ok := macToHasIPv6Lease[mac]; !ok {
-								virtualMachineCtx.Logger.Info(
-									"discovered IP address",
-									"addressType", "dhcp6",
-									"addressValue", discoveredIP)
-								macToHasIPv6Lease[mac] = struct{}{}
-								chanIPAddresses <- discoveredIP
-							}
-						}
-					}
-				}
-			}
-		}
-
-		// Determine whether or not the wait operation is over by whether
-		// or not the VM has all of the requested IP addresses.
-		for i, deviceSpec := range virtualMachineCtx.VSphereVM.Spec.Network.Devices {
-			mac, ok := deviceToMacIndex[i]
-			if !ok {
-				chanErrs <- errors.Errorf("invalid mac index %d waiting for ip addresses for vm %s", i, virtualMachineCtx)
-
-				// Return true to stop the property collector from waiting
-				// on any more changes.
-				return true
-			}
-			// If the device spec requires DHCP4 then the Wait is not
-			// over if there is no IPv4 lease.
-			if deviceSpec.DHCP4 {
-				if _, ok := macToHasIPv4Lease[mac]; !ok {
-					virtualMachineCtx.Logger.Info(
-						"the VM is missing the requested IP address",
-						"addressType", "dhcp4")
-					return false
-				}
-			}
-			// If the device spec requires DHCP6 then the Wait is not
-			// over if there is no IPv4 lease.
-			if deviceSpec.DHCP6 {
-				if _, ok := macToHasIPv6Lease[mac]; !ok {
-					virtualMachineCtx.Logger.Info(
-						"the VM is missing the requested IP address",
-						"addressType", "dhcp6")
-					return false
-				}
-			}
-			// If the device spec requires static IP addresses, the wait
-			// is not over if the device lacks one of those addresses.
-			for _, specIP := range deviceSpec.IPAddrs {
-				ip, _, _ := gonet.ParseCIDR(specIP)
-				if _, ok := macToHasStaticIP[mac][ip.String()]; !ok {
-					virtualMachineCtx.Logger.Info(
-						"the VM is missing the requested IP address",
-						"addressType", "static",
-						"addressValue", specIP)
-					return false
-				}
-			}
-		}
-
-		virtualMachineCtx.Logger.Info("the VM has all of the requested IP addresses")
-		return true
-	}
-
-	// The wait function will not return true until all the VM's
-	// network devices have IP assignments that match the requested
-	// network device specs. However, every time a new IP is discovered,
-	// a reconcile request will be triggered for the VSphereVM.
-	go func() {
-		if err := property.Wait(
-			ctx, propCollector, virtualMachineCtx.Obj.Reference(),
-			[]string{"guest.net"}, onPropertyChange); err != nil {
-			chanErrs <- errors.Wrapf(err, "failed to wait for ip addresses for vm %s", virtualMachineCtx)
-		}
-		close(chanIPAddresses)
-		close(chanErrs)
-	}()
-
-	return chanIPAddresses, chanErrs
 }
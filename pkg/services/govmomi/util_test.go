@@ -23,13 +23,18 @@ import (
 
 	"github.com/go-logr/logr"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
 	"sigs.k8s.io/cluster-api/util/conditions"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/metrics"
 )
 
 func Test_ShouldRetryTask(t *testing.T) {
@@ -127,6 +132,114 @@ func Test_ShouldRetryTask(t *testing.T) {
 	})
 }
 
+func Test_ShouldRetryTaskWithFault(t *testing.T) {
+	t.Run("when failed task has a terminal fault", func(t *testing.T) {
+		g := NewWithT(t)
+		vmCtx := &capvcontext.VMContext{
+			Logger: logr.Discard(),
+			VSphereVM: &infrav1.VSphereVM{Status: infrav1.VSphereVMStatus{
+				TaskRef: "task-123",
+			}},
+		}
+		task := baseTask(types.TaskInfoStateError, "")
+		task.Info.DescriptionId = "VirtualMachine.clone"
+		task.Info.Error = &types.LocalizedMethodFault{
+			Fault:            &types.InvalidDatastorePath{DatastorePath: "[ds0] template/template.vmx"},
+			LocalizedMessage: "Invalid datastore path '[ds0] template/template.vmx'.",
+		}
+
+		reconciled, err := checkAndRetryTask(vmCtx, &task)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(reconciled).To(BeTrue())
+		g.Expect(vmCtx.VSphereVM.Status.FailureReason).NotTo(BeNil())
+		g.Expect(*vmCtx.VSphereVM.Status.FailureReason).To(Equal(capierrors.CreateMachineError))
+		g.Expect(vmCtx.VSphereVM.Status.FailureMessage).NotTo(BeNil())
+		g.Expect(*vmCtx.VSphereVM.Status.FailureMessage).To(ContainSubstring("VirtualMachine.clone failed with fault InvalidDatastorePath"))
+		g.Expect(conditions.GetSeverity(vmCtx.VSphereVM, infrav1.VMProvisionedCondition)).To(HaveValue(Equal(clusterv1.ConditionSeverityError)))
+		g.Expect(vmCtx.VSphereVM.Status.TaskRef).To(BeEmpty())
+		g.Expect(vmCtx.VSphereVM.Status.RetryAfter.IsZero()).To(BeTrue())
+	})
+
+	t.Run("when failed task has a transient fault", func(t *testing.T) {
+		g := NewWithT(t)
+		vmCtx := &capvcontext.VMContext{
+			Logger: logr.Discard(),
+			VSphereVM: &infrav1.VSphereVM{Status: infrav1.VSphereVMStatus{
+				TaskRef:     "task-123",
+				TaskRetries: 2,
+			}},
+		}
+		task := baseTask(types.TaskInfoStateError, "")
+		task.Info.Error = &types.LocalizedMethodFault{Fault: &types.HostCommunication{}}
+
+		reconciled, err := checkAndRetryTask(vmCtx, &task)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(reconciled).To(BeTrue())
+		g.Expect(vmCtx.VSphereVM.Status.FailureReason).To(BeNil())
+		g.Expect(vmCtx.VSphereVM.Status.TaskRetries).To(BeEquivalentTo(3))
+		g.Expect(vmCtx.VSphereVM.Status.RetryAfter.Time).To(BeTemporally("~", time.Now().Add(4*time.Minute), 10*time.Second))
+	})
+
+	t.Run("when task succeeds after transient faults", func(t *testing.T) {
+		g := NewWithT(t)
+		vmCtx := &capvcontext.VMContext{
+			Logger: logr.Discard(),
+			VSphereVM: &infrav1.VSphereVM{Status: infrav1.VSphereVMStatus{
+				TaskRef:     "task-123",
+				TaskRetries: 2,
+			}},
+		}
+		task := baseTask(types.TaskInfoStateSuccess, "")
+
+		reconciled, err := checkAndRetryTask(vmCtx, &task)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(reconciled).To(BeFalse())
+		g.Expect(vmCtx.VSphereVM.Status.TaskRetries).To(BeZero())
+	})
+}
+
+func Test_observeTaskDuration(t *testing.T) {
+	g := NewWithT(t)
+	vmCtx := &capvcontext.VMContext{
+		VSphereVM: &infrav1.VSphereVM{Spec: infrav1.VSphereVMSpec{
+			VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+				Server:     "vcenter-metrics.test",
+				Datacenter: "dc0",
+			},
+		}},
+	}
+	sampleCount := func(operation string) uint64 {
+		var m dto.Metric
+		observer := metrics.OperationDurationSeconds.WithLabelValues("vcenter-metrics.test", "dc0", operation)
+		g.Expect(observer.(prometheus.Metric).Write(&m)).To(Succeed())
+		return m.GetHistogram().GetSampleCount()
+	}
+
+	queued := time.Now().Add(-time.Minute)
+	completed := time.Now()
+	task := baseTask(types.TaskInfoStateSuccess, "")
+	task.Info.QueueTime = queued
+	task.Info.CompleteTime = &completed
+
+	task.Info.DescriptionId = "VirtualMachine.clone"
+	observeTaskDuration(vmCtx, &task)
+	g.Expect(sampleCount("clone")).To(BeEquivalentTo(1))
+
+	// Tasks of other operations are not observed.
+	task.Info.DescriptionId = "VirtualMachine.rename"
+	observeTaskDuration(vmCtx, &task)
+	g.Expect(sampleCount("clone")).To(BeEquivalentTo(1))
+}
+
+func Test_taskRetryDelay(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(taskRetryDelay(0)).To(Equal(time.Minute))
+	g.Expect(taskRetryDelay(1)).To(Equal(2 * time.Minute))
+	g.Expect(taskRetryDelay(4)).To(Equal(16 * time.Minute))
+	g.Expect(taskRetryDelay(5)).To(Equal(30 * time.Minute))
+	g.Expect(taskRetryDelay(100)).To(Equal(30 * time.Minute))
+}
+
 func baseTask(state types.TaskInfoState, errorDescription string) mo.Task {
 	t := mo.Task{
 		ExtensibleManagedObject: mo.ExtensibleManagedObject{
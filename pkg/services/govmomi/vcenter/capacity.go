@@ -0,0 +1,249 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+const (
+	miB = int64(1024 * 1024)
+	kiB = int64(1024)
+)
+
+// InsufficientCapacityError is returned by Clone when the resource pool or the
+// datastore of a virtual machine cannot accommodate it. No clone task is
+// created in this case.
+type InsufficientCapacityError struct {
+	// Resource is the insufficient resource, i.e. CPU, memory or storage.
+	Resource string
+	// Object is the name of the resource pool or datastore.
+	Object string
+	// Required and Available are the required and available amounts of the
+	// resource, in Unit.
+	Required  int64
+	Available int64
+	Unit      string
+}
+
+func (e *InsufficientCapacityError) Error() string {
+	return fmt.Sprintf("insufficient %s in %s: %d%s required, %d%s available",
+		e.Resource, e.Object, e.Required, e.Unit, e.Available, e.Unit)
+}
+
+// IsInsufficientCapacity returns true if the error is or wraps an
+// InsufficientCapacityError.
+func IsInsufficientCapacity(err error) bool {
+	var capacityErr *InsufficientCapacityError
+	return errors.As(err, &capacityErr)
+}
+
+// capacityRequirements are the resources a new virtual machine requires from
+// its resource pool and its datastore.
+type capacityRequirements struct {
+	// CPUReservationMHz and MemoryReservationBytes are the reservations which
+	// must be unreserved in the resource pool. vCenter does not power on a
+	// virtual machine whose reservations cannot be guaranteed, while
+	// unreserved resources can be overcommitted.
+	CPUReservationMHz      int64
+	MemoryReservationBytes int64
+
+	// StorageBytes is the space allocated on the datastore by the clone: the
+	// thick provisioned disks and the swap file of the unreserved memory.
+	// Thin provisioned disks only allocate the space written by the guest.
+	StorageBytes int64
+}
+
+// getCapacityRequirements returns the capacity requirements of a clone of a
+// template with the given devices.
+func getCapacityRequirements(spec *types.VirtualMachineCloneSpec, devices object.VirtualDeviceList) capacityRequirements {
+	var req capacityRequirements
+	config := spec.Config
+
+	memoryBytes := int64(config.MemoryMB) * miB
+	switch {
+	case config.MemoryReservationLockedToMax != nil && *config.MemoryReservationLockedToMax:
+		req.MemoryReservationBytes = memoryBytes
+	case config.MemoryAllocation != nil && config.MemoryAllocation.Reservation != nil:
+		req.MemoryReservationBytes = *config.MemoryAllocation.Reservation * miB
+	}
+	if config.CpuAllocation != nil && config.CpuAllocation.Reservation != nil {
+		req.CPUReservationMHz = *config.CpuAllocation.Reservation
+	}
+	if swap := memoryBytes - req.MemoryReservationBytes; swap > 0 {
+		req.StorageBytes += swap
+	}
+
+	// The disks of a linked clone are delta disks, which only grow with the
+	// writes of the guest.
+	if spec.Location.DiskMoveType != string(linkCloneDiskMoveType) {
+		edited := map[int32]*types.VirtualDisk{}
+		for _, change := range config.DeviceChange {
+			if s := change.GetVirtualDeviceConfigSpec(); s.Operation == types.VirtualDeviceConfigSpecOperationEdit {
+				if disk, ok := s.Device.(*types.VirtualDisk); ok {
+					edited[disk.Key] = disk
+				}
+			}
+		}
+		// Disks placed on another datastore are not checked.
+		placed := map[int32]bool{}
+		for _, locator := range spec.Location.Disk {
+			if spec.Location.Datastore != nil && locator.Datastore != *spec.Location.Datastore {
+				placed[locator.DiskId] = true
+			}
+		}
+		for _, device := range devices.SelectByType((*types.VirtualDisk)(nil)) {
+			disk := device.(*types.VirtualDisk)
+			if placed[disk.Key] {
+				continue
+			}
+			if e, ok := edited[disk.Key]; ok {
+				disk = e
+			}
+			req.StorageBytes += thickCapacityBytes(disk)
+		}
+	}
+	for _, change := range config.DeviceChange {
+		s := change.GetVirtualDeviceConfigSpec()
+		if s.Operation != types.VirtualDeviceConfigSpecOperationAdd || s.FileOperation != types.VirtualDeviceConfigSpecFileOperationCreate {
+			continue
+		}
+		if disk, ok := s.Device.(*types.VirtualDisk); ok && isOnDatastore(disk, spec.Location.Datastore) {
+			req.StorageBytes += thickCapacityBytes(disk)
+		}
+	}
+	return req
+}
+
+// thickCapacityBytes returns the capacity of a disk if it is thick
+// provisioned.
+func thickCapacityBytes(disk *types.VirtualDisk) int64 {
+	if backing, ok := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo); ok && backing.ThinProvisioned != nil && *backing.ThinProvisioned {
+		return 0
+	}
+	return disk.CapacityInKB * kiB
+}
+
+// isOnDatastore returns true if a new disk is created on the datastore of the
+// virtual machine, i.e. its backing names no other datastore.
+func isOnDatastore(disk *types.VirtualDisk, datastoreRef *types.ManagedObjectReference) bool {
+	backing, ok := disk.Backing.(types.BaseVirtualDeviceFileBackingInfo)
+	if !ok || datastoreRef == nil {
+		return true
+	}
+	info := backing.GetVirtualDeviceFileBackingInfo()
+	return info.Datastore == nil || *info.Datastore == *datastoreRef
+}
+
+// check returns an InsufficientCapacityError if the resource pool or the
+// datastore cannot accommodate the requirements.
+func (req capacityRequirements) check(pool mo.ResourcePool, datastore mo.Datastore) error {
+	if req.CPUReservationMHz > pool.Runtime.Cpu.UnreservedForVm {
+		return &InsufficientCapacityError{
+			Resource:  "CPU",
+			Object:    pool.Name,
+			Required:  req.CPUReservationMHz,
+			Available: pool.Runtime.Cpu.UnreservedForVm,
+			Unit:      "MHz",
+		}
+	}
+	if req.MemoryReservationBytes > pool.Runtime.Memory.UnreservedForVm {
+		return &InsufficientCapacityError{
+			Resource:  "memory",
+			Object:    pool.Name,
+			Required:  req.MemoryReservationBytes / miB,
+			Available: pool.Runtime.Memory.UnreservedForVm / miB,
+			Unit:      "MiB",
+		}
+	}
+	if req.StorageBytes > datastore.Summary.FreeSpace {
+		return &InsufficientCapacityError{
+			Resource:  "storage",
+			Object:    datastore.Summary.Name,
+			Required:  req.StorageBytes / miB,
+			Available: datastore.Summary.FreeSpace / miB,
+			Unit:      "MiB",
+		}
+	}
+	return nil
+}
+
+// checkCapacity verifies that the resource pool and the datastore of a clone
+// can accommodate it before the clone task is created, since a clone failing
+// for insufficient resources leaves a partial virtual machine behind. It is
+// called before every provisioning path: template clones, instant clones and
+// Content Library deployments.
+func checkCapacity(ctx context.Context, vmCtx *capvcontext.VMContext, pool *object.ResourcePool, spec *types.VirtualMachineCloneSpec, devices object.VirtualDeviceList) error {
+	req := getCapacityRequirements(spec, devices)
+
+	var poolMo mo.ResourcePool
+	if err := pool.Properties(ctx, pool.Reference(), []string{"name", "runtime"}, &poolMo); err != nil {
+		return errors.Wrapf(err, "unable to get runtime of resource pool for VM %s", vmCtx.VSphereVM.Name)
+	}
+	var datastoreMo mo.Datastore
+	datastore := object.NewDatastore(vmCtx.Session.Client.Client, *spec.Location.Datastore)
+	if err := datastore.Properties(ctx, datastore.Reference(), []string{"summary"}, &datastoreMo); err != nil {
+		return errors.Wrapf(err, "unable to get summary of datastore for VM %s", vmCtx.VSphereVM.Name)
+	}
+
+	vmCtx.Logger.V(4).Info("checking capacity",
+		"cpuReservationMHz", req.CPUReservationMHz,
+		"memoryReservationMiB", req.MemoryReservationBytes/miB,
+		"storageMiB", req.StorageBytes/miB)
+	return req.check(poolMo, datastoreMo)
+}
+
+// getInstantCloneCapacitySpec returns the spec the capacity requirements of an
+// instant clone of the given source are computed from. An instant clone
+// inherits the memory and the reservations of its source, and its disks are
+// delta disks of the disks of the source.
+func getInstantCloneCapacitySpec(src *types.VirtualMachineConfigInfo, datastoreRef *types.ManagedObjectReference) *types.VirtualMachineCloneSpec {
+	config := &types.VirtualMachineConfigSpec{
+		MemoryMB:                     int64(src.Hardware.MemoryMB),
+		MemoryReservationLockedToMax: src.MemoryReservationLockedToMax,
+		CpuAllocation:                src.CpuAllocation,
+		MemoryAllocation:             src.MemoryAllocation,
+	}
+	return &types.VirtualMachineCloneSpec{
+		Config: config,
+		Location: types.VirtualMachineRelocateSpec{
+			DiskMoveType: string(linkCloneDiskMoveType),
+			Datastore:    datastoreRef,
+		},
+	}
+}
+
+// getLibraryItemCapacitySpec returns the spec the capacity requirements of a
+// deployment of a Content Library item are computed from. The disks of the
+// item are only known once it is deployed, so only the reservations and the
+// swap file of the virtual hardware of the VSphereVM are checked.
+func getLibraryItemCapacitySpec(vmCtx *capvcontext.VMContext, datastoreRef *types.ManagedObjectReference) *types.VirtualMachineCloneSpec {
+	return &types.VirtualMachineCloneSpec{
+		Config: newHardwareConfigSpec(vmCtx),
+		Location: types.VirtualMachineRelocateSpec{
+			Datastore: datastoreRef,
+		},
+	}
+}
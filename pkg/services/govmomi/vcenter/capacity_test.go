@@ -0,0 +1,229 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/utils/pointer"
+)
+
+func newCapacityDisk(key int32, capacityGiB int64, thin bool) *types.VirtualDisk {
+	return &types.VirtualDisk{
+		VirtualDevice: types.VirtualDevice{
+			Key: key,
+			Backing: &types.VirtualDiskFlatVer2BackingInfo{
+				ThinProvisioned: pointer.Bool(thin),
+			},
+		},
+		CapacityInKB: capacityGiB * 1024 * 1024,
+	}
+}
+
+func TestGetCapacityRequirements(t *testing.T) {
+	datastoreRef := types.ManagedObjectReference{Type: "Datastore", Value: "datastore-1"}
+	otherDatastoreRef := types.ManagedObjectReference{Type: "Datastore", Value: "datastore-2"}
+
+	testCases := []struct {
+		name     string
+		spec     types.VirtualMachineCloneSpec
+		devices  object.VirtualDeviceList
+		expected capacityRequirements
+	}{
+		{
+			name: "full clone with thick disks",
+			spec: types.VirtualMachineCloneSpec{
+				Config: &types.VirtualMachineConfigSpec{
+					MemoryMB: 2048,
+					DeviceChange: []types.BaseVirtualDeviceConfigSpec{
+						&types.VirtualDeviceConfigSpec{
+							Operation: types.VirtualDeviceConfigSpecOperationEdit,
+							Device:    newCapacityDisk(1, 40, false),
+						},
+					},
+				},
+				Location: types.VirtualMachineRelocateSpec{
+					DiskMoveType: string(fullCloneDiskMoveType),
+					Datastore:    &datastoreRef,
+				},
+			},
+			devices: object.VirtualDeviceList{newCapacityDisk(1, 20, false), newCapacityDisk(2, 10, false)},
+			expected: capacityRequirements{
+				StorageBytes: 2*giB + 40*giB + 10*giB,
+			},
+		},
+		{
+			name: "full clone with thin disks and reservations",
+			spec: types.VirtualMachineCloneSpec{
+				Config: &types.VirtualMachineConfigSpec{
+					MemoryMB:         4096,
+					CpuAllocation:    &types.ResourceAllocationInfo{Reservation: pointer.Int64(1000)},
+					MemoryAllocation: &types.ResourceAllocationInfo{Reservation: pointer.Int64(1024)},
+				},
+				Location: types.VirtualMachineRelocateSpec{
+					DiskMoveType: string(fullCloneDiskMoveType),
+					Datastore:    &datastoreRef,
+				},
+			},
+			devices: object.VirtualDeviceList{newCapacityDisk(1, 20, true)},
+			expected: capacityRequirements{
+				CPUReservationMHz:      1000,
+				MemoryReservationBytes: giB,
+				StorageBytes:           3 * giB,
+			},
+		},
+		{
+			name: "linked clone with memory reservation locked to max",
+			spec: types.VirtualMachineCloneSpec{
+				Config: &types.VirtualMachineConfigSpec{
+					MemoryMB:                     2048,
+					MemoryReservationLockedToMax: pointer.Bool(true),
+				},
+				Location: types.VirtualMachineRelocateSpec{
+					DiskMoveType: string(linkCloneDiskMoveType),
+					Datastore:    &datastoreRef,
+				},
+			},
+			devices: object.VirtualDeviceList{newCapacityDisk(1, 20, false)},
+			expected: capacityRequirements{
+				MemoryReservationBytes: 2 * giB,
+			},
+		},
+		{
+			name: "new disks and disks placed on another datastore",
+			spec: types.VirtualMachineCloneSpec{
+				Config: &types.VirtualMachineConfigSpec{
+					DeviceChange: []types.BaseVirtualDeviceConfigSpec{
+						&types.VirtualDeviceConfigSpec{
+							Operation:     types.VirtualDeviceConfigSpecOperationAdd,
+							FileOperation: types.VirtualDeviceConfigSpecFileOperationCreate,
+							Device:        newCapacityDisk(-1, 5, false),
+						},
+						&types.VirtualDeviceConfigSpec{
+							Operation:     types.VirtualDeviceConfigSpecOperationAdd,
+							FileOperation: types.VirtualDeviceConfigSpecFileOperationCreate,
+							Device: &types.VirtualDisk{
+								VirtualDevice: types.VirtualDevice{
+									Key: -2,
+									Backing: &types.VirtualDiskFlatVer2BackingInfo{
+										VirtualDeviceFileBackingInfo: types.VirtualDeviceFileBackingInfo{Datastore: &otherDatastoreRef},
+									},
+								},
+								CapacityInKB: 50 * 1024 * 1024,
+							},
+						},
+					},
+				},
+				Location: types.VirtualMachineRelocateSpec{
+					DiskMoveType: string(fullCloneDiskMoveType),
+					Datastore:    &datastoreRef,
+					Disk: []types.VirtualMachineRelocateSpecDiskLocator{
+						{DiskId: 1, Datastore: datastoreRef},
+						{DiskId: 2, Datastore: otherDatastoreRef},
+					},
+				},
+			},
+			devices: object.VirtualDeviceList{newCapacityDisk(1, 20, false), newCapacityDisk(2, 100, false)},
+			expected: capacityRequirements{
+				StorageBytes: 20*giB + 5*giB,
+			},
+		},
+		{
+			name: "instant clone",
+			spec: *getInstantCloneCapacitySpec(&types.VirtualMachineConfigInfo{
+				Hardware:         types.VirtualHardware{MemoryMB: 4096},
+				CpuAllocation:    &types.ResourceAllocationInfo{Reservation: pointer.Int64(1000)},
+				MemoryAllocation: &types.ResourceAllocationInfo{Reservation: pointer.Int64(1024)},
+			}, &datastoreRef),
+			devices: object.VirtualDeviceList{newCapacityDisk(1, 20, false)},
+			expected: capacityRequirements{
+				CPUReservationMHz:      1000,
+				MemoryReservationBytes: 1 * giB,
+				StorageBytes:           3 * giB,
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			if req := getCapacityRequirements(&tc.spec, tc.devices); req != tc.expected {
+				t.Errorf("Expected requirements %+v, got %+v", tc.expected, req)
+			}
+		})
+	}
+}
+
+func TestCapacityRequirementsCheck(t *testing.T) {
+	pool := mo.ResourcePool{
+		ManagedEntity: mo.ManagedEntity{Name: "pool"},
+		Runtime: types.ResourcePoolRuntimeInfo{
+			Cpu:    types.ResourcePoolResourceUsage{UnreservedForVm: 2000},
+			Memory: types.ResourcePoolResourceUsage{UnreservedForVm: 4 * giB},
+		},
+	}
+	datastore := newDatastore("ds", 100, 50, 0)
+
+	testCases := []struct {
+		name     string
+		req      capacityRequirements
+		resource string
+	}{
+		{
+			name: "when the capacity is sufficient",
+			req:  capacityRequirements{CPUReservationMHz: 2000, MemoryReservationBytes: 4 * giB, StorageBytes: 50 * giB},
+		},
+		{
+			name:     "when the CPU is insufficient",
+			req:      capacityRequirements{CPUReservationMHz: 2001},
+			resource: "CPU",
+		},
+		{
+			name:     "when the memory is insufficient",
+			req:      capacityRequirements{MemoryReservationBytes: 5 * giB},
+			resource: "memory",
+		},
+		{
+			name:     "when the storage is insufficient",
+			req:      capacityRequirements{StorageBytes: 51 * giB},
+			resource: "storage",
+		},
+	}
+
+	for _, test := range testCases {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.req.check(pool, datastore)
+			if tc.resource == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if !IsInsufficientCapacity(errors.Wrap(err, "clone failed")) {
+				t.Fatalf("Expected an InsufficientCapacityError, got: %v", err)
+			}
+			if resource := err.(*InsufficientCapacityError).Resource; resource != tc.resource {
+				t.Errorf("Expected insufficient %s, got insufficient %s", tc.resource, resource)
+			}
+		})
+	}
+}
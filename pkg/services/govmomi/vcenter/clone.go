@@ -20,8 +20,6 @@ package vcenter
 import (
 	"context"
 	"fmt"
-	"math/rand"
-	"time"
 
 	"github.com/pkg/errors"
 	"github.com/vmware/govmomi/object"
@@ -34,8 +32,10 @@ import (
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/allocation"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/extra"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/template"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 )
 
 const (
@@ -47,6 +47,9 @@ const (
 // the virtual machine to be created on the vCenter, which can be resolved by waiting on the task reference stored
 // in VMContext.VSphereVM.Status.TaskRef.
 func Clone(ctx context.Context, vmCtx *capvcontext.VMContext, bootstrapData []byte, format bootstrapv1.Format) error {
+	ctx, span := tracing.Start(ctx, "vcenter.Clone")
+	defer span.End()
+
 	vmCtx = &capvcontext.VMContext{
 		ControllerContext: vmCtx.ControllerContext,
 		VSphereVM:         vmCtx.VSphereVM,
@@ -57,7 +60,9 @@ func Clone(ctx context.Context, vmCtx *capvcontext.VMContext, bootstrapData []by
 	vmCtx.Logger.Info("starting clone process")
 
 	var extraConfig extra.Config
-	if len(bootstrapData) > 0 {
+	// The NoCloud ISO holding the bootstrap data is attached once the VM is
+	// created.
+	if len(bootstrapData) > 0 && vmCtx.VSphereVM.Spec.BootstrapDataTransport != infrav1.BootstrapDataTransportNoCloud {
 		vmCtx.Logger.Info("applied bootstrap data to VM clone spec")
 		switch format {
 		case bootstrapv1.CloudConfig:
@@ -72,16 +77,72 @@ func Clone(ctx context.Context, vmCtx *capvcontext.VMContext, bootstrapData []by
 			return err
 		}
 	}
-	tpl, err := template.FindTemplate(ctx, vmCtx, vmCtx.VSphereVM.Spec.Template)
+	folder, err := vmCtx.Session.Finder.FolderOrDefault(ctx, vmCtx.VSphereVM.Spec.Folder)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get folder for %q", ctx)
+	}
+
+	pool, err := vmCtx.Session.Finder.ResourcePoolOrDefault(ctx, vmCtx.VSphereVM.Spec.ResourcePool)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get resource pool for %q", ctx)
+	}
+
+	// A Content Library item is deployed as the new virtual machine and
+	// reconfigured afterwards, while a template is cloned.
+	var tpl *object.VirtualMachine
+	fromLibrary := vmCtx.VSphereVM.Spec.ContentLibrary != nil
+	if !fromLibrary {
+		tpl, err = template.FindTemplate(ctx, vmCtx, vmCtx.VSphereVM.Spec.Template)
+		if err != nil {
+			return err
+		}
+	}
+
+	datastoreRef, placement, err := selectDatastore(ctx, vmCtx, folder, pool, tpl)
 	if err != nil {
 		return err
 	}
+	datastoreName, err := object.NewDatastore(vmCtx.Session.Client.Client, *datastoreRef).ObjectName(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get name of datastore %s for %q", datastoreRef.Value, ctx)
+	}
+	vmCtx.VSphereVM.Status.Datastore = datastoreName
+
+	if fromLibrary {
+		item, err := template.FindLibraryItem(ctx, vmCtx, *vmCtx.VSphereVM.Spec.ContentLibrary)
+		if err != nil {
+			return err
+		}
+		tpl, err = deployLibraryItem(ctx, vmCtx, item, folder, pool, datastoreRef)
+		if err != nil {
+			return err
+		}
+		if tpl == nil {
+			// The VSphereVM is reconciled again once the deployment is
+			// complete.
+			return nil
+		}
+	}
+
+	// An instant clone forks the running source VM, which is why neither
+	// disks nor virtual hardware are customized.
+	if vmCtx.VSphereVM.Spec.CloneMode == infrav1.InstantClone {
+		if fromLibrary {
+			return errors.New("instant clones cannot be created from a content library item")
+		}
+		task, err := instantClone(ctx, vmCtx, tpl, folder, pool, datastoreRef, extraConfig)
+		if err != nil {
+			return err
+		}
+		setTaskRef(ctx, vmCtx, task)
+		return nil
+	}
 
 	// If a linked clone is requested then a MoRef for a snapshot must be
 	// found with which to perform the linked clone.
 	var snapshotRef *types.ManagedObjectReference
 
-	if vmCtx.VSphereVM.Spec.CloneMode == "" || vmCtx.VSphereVM.Spec.CloneMode == infrav1.LinkedClone {
+	if !fromLibrary && (vmCtx.VSphereVM.Spec.CloneMode == "" || vmCtx.VSphereVM.Spec.CloneMode == infrav1.LinkedClone) {
 		vmCtx.Logger.Info("linked clone requested")
 		// If the name of a snapshot was not provided then find the template's
 		// current snapshot.
@@ -116,16 +177,6 @@ func Clone(ctx context.Context, vmCtx *capvcontext.VMContext, bootstrapData []by
 		diskMoveType = linkCloneDiskMoveType
 	}
 
-	folder, err := vmCtx.Session.Finder.FolderOrDefault(ctx, vmCtx.VSphereVM.Spec.Folder)
-	if err != nil {
-		return errors.Wrapf(err, "unable to get folder for %q", ctx)
-	}
-
-	pool, err := vmCtx.Session.Finder.ResourcePoolOrDefault(ctx, vmCtx.VSphereVM.Spec.ResourcePool)
-	if err != nil {
-		return errors.Wrapf(err, "unable to get resource pool for %q", ctx)
-	}
-
 	devices, err := tpl.Device(ctx)
 	if err != nil {
 		return errors.Wrapf(err, "error getting devices for %q", ctx)
@@ -141,8 +192,16 @@ func Clone(ctx context.Context, vmCtx *capvcontext.VMContext, bootstrapData []by
 			return errors.Wrapf(err, "error getting disk spec for %q", ctx)
 		}
 		deviceSpecs = append(deviceSpecs, diskSpecs...)
+	} else if err := validateLinkedCloneDisks(vmCtx, devices); err != nil {
+		return err
 	}
 
+	newDiskSpecs, err := getNewDiskSpecs(ctx, vmCtx, devices, *datastoreRef)
+	if err != nil {
+		return errors.Wrapf(err, "error getting new disk specs for %q", ctx)
+	}
+	deviceSpecs = append(deviceSpecs, newDiskSpecs...)
+
 	networkSpecs, err := getNetworkSpecs(ctx, vmCtx, devices)
 	if err != nil {
 		return errors.Wrapf(err, "error getting network specs for %q", ctx)
@@ -154,37 +213,22 @@ func Clone(ctx context.Context, vmCtx *capvcontext.VMContext, bootstrapData []by
 		return errors.Wrapf(err, "error getting network specs for %q", ctx)
 	}
 
-	numCPUs := vmCtx.VSphereVM.Spec.NumCPUs
-	if numCPUs < 2 {
-		numCPUs = 2
-	}
-	numCoresPerSocket := vmCtx.VSphereVM.Spec.NumCoresPerSocket
-	if numCoresPerSocket == 0 {
-		numCoresPerSocket = numCPUs
-	}
-	memMiB := vmCtx.VSphereVM.Spec.MemoryMiB
-	if memMiB == 0 {
-		memMiB = 2048
-	}
-
 	// Disable the vAppConfig during VM creation to ensure Cloud-Init inside of the guest does not
 	// activate and prefer the OVF datasource over the VMware datasource.
 	vappConfigRemoved := true
 
+	config := newHardwareConfigSpec(vmCtx)
+	// Assign the clone's InstanceUUID the value of the Kubernetes Machine
+	// object's UID. This allows lookup of the cloned VM prior to knowing
+	// the VM's UUID.
+	config.InstanceUuid = string(vmCtx.VSphereVM.UID)
+	config.Flags = newVMFlagInfo()
+	config.DeviceChange = deviceSpecs
+	config.ExtraConfig = extraConfig
+	config.VAppConfigRemoved = &vappConfigRemoved
+
 	spec := types.VirtualMachineCloneSpec{
-		Config: &types.VirtualMachineConfigSpec{
-			// Assign the clone's InstanceUUID the value of the Kubernetes Machine
-			// object's UID. This allows lookup of the cloned VM prior to knowing
-			// the VM's UUID.
-			InstanceUuid:      string(vmCtx.VSphereVM.UID),
-			Flags:             newVMFlagInfo(),
-			DeviceChange:      deviceSpecs,
-			ExtraConfig:       extraConfig,
-			NumCPUs:           numCPUs,
-			NumCoresPerSocket: numCoresPerSocket,
-			MemoryMB:          memMiB,
-			VAppConfigRemoved: &vappConfigRemoved,
-		},
+		Config: config,
 		Location: types.VirtualMachineRelocateSpec{
 			DiskMoveType: string(diskMoveType),
 			Folder:       types.NewReference(folder.Reference()),
@@ -198,33 +242,129 @@ func Clone(ctx context.Context, vmCtx *capvcontext.VMContext, bootstrapData []by
 		Snapshot: snapshotRef,
 	}
 
-	// For PCI devices, the memory for the VM needs to be reserved
+	if err := applySecuritySpec(ctx, vmCtx, devices, spec.Config); err != nil {
+		return err
+	}
+
+	disks := devices.SelectByType((*types.VirtualDisk)(nil))
+	isLinkedClone := snapshotRef != nil
+	spec.Location.Disk = getDiskLocators(disks, *datastoreRef, isLinkedClone)
+	spec.Location.Datastore = datastoreRef
+	applyStoragePlacement(&spec.Location, placement)
+	if err := setDiskLocatorPlacement(ctx, vmCtx, spec.Location.Disk); err != nil {
+		return errors.Wrapf(err, "error getting disk placement for %q", ctx)
+	}
+
+	var task *object.Task
+	if fromLibrary {
+		vmCtx.Logger.Info("reconfiguring machine deployed from content library", "namespace", vmCtx.VSphereVM.Namespace, "name", vmCtx.VSphereVM.Name)
+		task, err = tpl.Reconfigure(ctx, *spec.Config)
+		if err != nil {
+			return errors.Wrapf(err, "error trigging reconfigure op for machine %s", ctx)
+		}
+	} else {
+		// The capacity of Content Library deployments and instant clones
+		// is checked before they are started.
+		if err := checkCapacity(ctx, vmCtx, pool, &spec, devices); err != nil {
+			return err
+		}
+		vmCtx.Logger.Info("cloning machine", "namespace", vmCtx.VSphereVM.Namespace, "name", vmCtx.VSphereVM.Name, "cloneType", vmCtx.VSphereVM.Status.CloneMode)
+		task, err = tpl.Clone(ctx, folder, vmCtx.VSphereVM.Name, spec)
+		if err != nil {
+			return errors.Wrapf(err, "error trigging clone op for machine %s", ctx)
+		}
+	}
+
+	setTaskRef(ctx, vmCtx, task)
+	return nil
+}
+
+// newHardwareConfigSpec returns the configuration of the virtual hardware of
+// the virtual machine of the VSphereVM, i.e. its CPUs, memory and resource
+// allocation.
+func newHardwareConfigSpec(vmCtx *capvcontext.VMContext) *types.VirtualMachineConfigSpec {
+	numCPUs := vmCtx.VSphereVM.Spec.NumCPUs
+	if numCPUs < 2 {
+		numCPUs = 2
+	}
+	numCoresPerSocket := vmCtx.VSphereVM.Spec.NumCoresPerSocket
+	if numCoresPerSocket == 0 {
+		numCoresPerSocket = numCPUs
+	}
+	memMiB := vmCtx.VSphereVM.Spec.MemoryMiB
+	if memMiB == 0 {
+		memMiB = 2048
+	}
+
+	config := &types.VirtualMachineConfigSpec{
+		NumCPUs:           numCPUs,
+		NumCoresPerSocket: numCoresPerSocket,
+		MemoryMB:          memMiB,
+	}
+
+	// For PCI devices and SR-IOV network devices, the memory for the VM needs
+	// to be reserved.
 	// We can replace this once we have another way of reserving memory option
 	// exposed via the API types.
-	if len(vmCtx.VSphereVM.Spec.PciDevices) > 0 {
-		spec.Config.MemoryReservationLockedToMax = pointer.Bool(true)
+	if len(vmCtx.VSphereVM.Spec.PciDevices) > 0 || hasSRIOVNetworkDevice(vmCtx.VSphereVM.Spec.Network.Devices) {
+		config.MemoryReservationLockedToMax = pointer.Bool(true)
 	}
+	allocation.Apply(vmCtx.VSphereVM.Spec.ResourceAllocation, config)
+	return config
+}
+
+// setTaskRef records the task creating the virtual machine in the status of
+// the VSphereVM.
+func setTaskRef(ctx context.Context, vmCtx *capvcontext.VMContext, task *object.Task) {
+	vmCtx.VSphereVM.Status.TaskRef = task.Reference().Value
 
+	// patch the vsphereVM early to ensure that the task is
+	// reflected in the status right away, this avoid situations
+	// of concurrent clones
+	if err := vmCtx.Patch(ctx); err != nil {
+		vmCtx.Logger.Error(err, "patch failed", "vspherevm", vmCtx.VSphereVM)
+	}
+}
+
+// selectDatastore returns the datastore on which the virtual machine is placed.
+// It is either the configured datastore, the datastore recommended by Storage
+// DRS for the configured datastore cluster, a datastore compatible with the
+// configured storage policy or the default datastore.
+// The placement recommended by Storage DRS is returned as well, it is nil if
+// no datastore cluster is configured.
+// The template is nil when the virtual machine is deployed from a Content
+// Library item.
+func selectDatastore(ctx context.Context, vmCtx *capvcontext.VMContext, folder *object.Folder, pool *object.ResourcePool, tpl *object.VirtualMachine) (*types.ManagedObjectReference, *types.StoragePlacementAction, error) {
 	var datastoreRef *types.ManagedObjectReference
-	if vmCtx.VSphereVM.Spec.Datastore != "" {
+	var placement *types.StoragePlacementAction
+	switch {
+	case vmCtx.VSphereVM.Spec.Datastore != "":
 		datastore, err := vmCtx.Session.Finder.Datastore(ctx, vmCtx.VSphereVM.Spec.Datastore)
 		if err != nil {
-			return errors.Wrapf(err, "unable to get datastore %s for %q", vmCtx.VSphereVM.Spec.Datastore, ctx)
+			return nil, nil, errors.Wrapf(err, "unable to get datastore %s for %q", vmCtx.VSphereVM.Spec.Datastore, ctx)
 		}
 		datastoreRef = types.NewReference(datastore.Reference())
-		spec.Location.Datastore = datastoreRef
+	case vmCtx.VSphereVM.Spec.DatastoreCluster != "":
+		if tpl == nil {
+			return nil, nil, errors.New("datastore cluster placement is not supported for content library items")
+		}
+		var err error
+		placement, err = recommendDatastore(ctx, vmCtx, folder, pool, tpl)
+		if err != nil {
+			return nil, nil, err
+		}
+		datastoreRef = types.NewReference(placement.Destination)
 	}
 
-	var storageProfileID string
 	if vmCtx.VSphereVM.Spec.StoragePolicyName != "" {
 		pbmClient, err := pbm.NewClient(ctx, vmCtx.Session.Client.Client)
 		if err != nil {
-			return errors.Wrapf(err, "unable to create pbm client for %q", ctx)
+			return nil, nil, errors.Wrapf(err, "unable to create pbm client for %q", ctx)
 		}
 
-		storageProfileID, err = pbmClient.ProfileIDByName(ctx, vmCtx.VSphereVM.Spec.StoragePolicyName)
+		storageProfileID, err := pbmClient.ProfileIDByName(ctx, vmCtx.VSphereVM.Spec.StoragePolicyName)
 		if err != nil {
-			return errors.Wrapf(err, "unable to get storageProfileID from name %s for %q", vmCtx.VSphereVM.Spec.StoragePolicyName, ctx)
+			return nil, nil, errors.Wrapf(err, "unable to get storageProfileID from name %s for %q", vmCtx.VSphereVM.Spec.StoragePolicyName, ctx)
 		}
 
 		var hubs []pbmTypes.PbmPlacementHub
@@ -239,12 +379,12 @@ func Clone(ctx context.Context, vmCtx *capvcontext.VMContext, bootstrapData []by
 			// Otherwise we should get just the Datastores connected to our pool
 			cluster, err := pool.Owner(ctx)
 			if err != nil {
-				return errors.Wrapf(err, "failed to get owning cluster of resourcepool %q to calculate datastore based on storage policy", pool)
+				return nil, nil, errors.Wrapf(err, "failed to get owning cluster of resourcepool %q to calculate datastore based on storage policy", pool)
 			}
 			dsGetter := object.NewComputeResource(vmCtx.Session.Client.Client, cluster.Reference())
 			datastores, err := dsGetter.Datastores(ctx)
 			if err != nil {
-				return errors.Wrapf(err, "unable to list datastores from owning cluster of requested resourcepool")
+				return nil, nil, errors.Wrapf(err, "unable to list datastores from owning cluster of requested resourcepool")
 			}
 			for _, ds := range datastores {
 				hubs = append(hubs, pbmTypes.PbmPlacementHub{
@@ -258,20 +398,25 @@ func Clone(ctx context.Context, vmCtx *capvcontext.VMContext, bootstrapData []by
 		constraints = append(constraints, &pbmTypes.PbmPlacementCapabilityProfileRequirement{ProfileId: pbmTypes.PbmProfileId{UniqueId: storageProfileID}})
 		result, err := pbmClient.CheckRequirements(ctx, hubs, nil, constraints)
 		if err != nil {
-			return errors.Wrapf(err, "unable to check requirements for storage policy")
+			return nil, nil, errors.Wrapf(err, "unable to check requirements for storage policy")
 		}
 
 		if len(result.CompatibleDatastores()) == 0 {
-			return fmt.Errorf("no compatible datastores found for storage policy: %s", vmCtx.VSphereVM.Spec.StoragePolicyName)
+			return nil, nil, fmt.Errorf("no compatible datastores found for storage policy: %s", vmCtx.VSphereVM.Spec.StoragePolicyName)
 		}
 
 		// If datastoreRef is nil here it means that the user didn't specify a Datastore. So we should
 		// select one of the datastores of the owning cluster of the resource pool that matched the
-		// requirements of the storage policy.
+		// requirements of the storage policy, according to the datastore selection policy.
 		if datastoreRef == nil {
-			r := rand.New(rand.NewSource(time.Now().UnixNano())) //nolint:gosec // We won't need cryptographically secure randomness here.
-			ds := result.CompatibleDatastores()[r.Intn(len(result.CompatibleDatastores()))]
-			datastoreRef = &types.ManagedObjectReference{Type: ds.HubType, Value: ds.HubId}
+			refs := make([]types.ManagedObjectReference, 0, len(result.CompatibleDatastores()))
+			for _, ds := range result.CompatibleDatastores() {
+				refs = append(refs, types.ManagedObjectReference{Type: ds.HubType, Value: ds.HubId})
+			}
+			datastoreRef, err = selectCompatibleDatastore(ctx, vmCtx, refs)
+			if err != nil {
+				return nil, nil, err
+			}
 		}
 	}
 
@@ -281,31 +426,12 @@ func Clone(ctx context.Context, vmCtx *capvcontext.VMContext, bootstrapData []by
 		// if no datastore defined through VM spec or storage policy, use default
 		datastore, err := vmCtx.Session.Finder.DefaultDatastore(ctx)
 		if err != nil {
-			return errors.Wrapf(err, "unable to get default datastore for %q", ctx)
+			return nil, nil, errors.Wrapf(err, "unable to get default datastore for %q", ctx)
 		}
 		datastoreRef = types.NewReference(datastore.Reference())
 	}
 
-	disks := devices.SelectByType((*types.VirtualDisk)(nil))
-	isLinkedClone := snapshotRef != nil
-	spec.Location.Disk = getDiskLocators(disks, *datastoreRef, isLinkedClone)
-	spec.Location.Datastore = datastoreRef
-
-	vmCtx.Logger.Info("cloning machine", "namespace", vmCtx.VSphereVM.Namespace, "name", vmCtx.VSphereVM.Name, "cloneType", vmCtx.VSphereVM.Status.CloneMode)
-	task, err := tpl.Clone(ctx, folder, vmCtx.VSphereVM.Name, spec)
-	if err != nil {
-		return errors.Wrapf(err, "error trigging clone op for machine %s", ctx)
-	}
-
-	vmCtx.VSphereVM.Status.TaskRef = task.Reference().Value
-
-	// patch the vsphereVM early to ensure that the task is
-	// reflected in the status right away, this avoid situations
-	// of concurrent clones
-	if err := vmCtx.Patch(ctx); err != nil {
-		vmCtx.Logger.Error(err, "patch failed", "vspherevm", vmCtx.VSphereVM)
-	}
-	return nil
+	return datastoreRef, placement, nil
 }
 
 func newVMFlagInfo() *types.VirtualMachineFlagInfo {
@@ -359,15 +485,23 @@ func getDiskSpec(vmCtx *capvcontext.VMContext, devices object.VirtualDeviceList)
 		for i, disk := range disks[1:] {
 			var diskCloneCapacityKB int64
 			// Check if additional Disks have been provided
-			if len(vmCtx.VSphereVM.Spec.AdditionalDisksGiB) > i {
+			switch {
+			case len(vmCtx.VSphereVM.Spec.Disks) > i:
+				diskCloneCapacityKB = int64(vmCtx.VSphereVM.Spec.Disks[i].SizeGiB) * 1024 * 1024
+			case len(vmCtx.VSphereVM.Spec.AdditionalDisksGiB) > i:
 				diskCloneCapacityKB = int64(vmCtx.VSphereVM.Spec.AdditionalDisksGiB[i]) * 1024 * 1024
-			} else {
+			default:
 				diskCloneCapacityKB = disk.(*types.VirtualDisk).CapacityInKB
 			}
 			additionalDiskConfigSpec, err := getDiskConfigSpec(disk.(*types.VirtualDisk), diskCloneCapacityKB)
 			if err != nil {
 				return nil, errors.Wrap(err, "Error getting disk config spec for additional disk")
 			}
+			if len(vmCtx.VSphereVM.Spec.Disks) > i {
+				if err := setTemplateDiskMode(disk.(*types.VirtualDisk), vmCtx.VSphereVM.Spec.Disks[i]); err != nil {
+					return nil, err
+				}
+			}
 			diskSpecs = append(diskSpecs, additionalDiskConfigSpec)
 		}
 	}
@@ -392,7 +526,211 @@ func getDiskConfigSpec(disk *types.VirtualDisk, diskCloneCapacityKB int64) (type
 	}, nil
 }
 
-const ethCardType = "vmxnet3"
+// setTemplateDiskMode applies the disk mode of the given disk spec to a disk
+// provided by the template. Properties which only apply to new disks must not
+// be set for disks provided by the template.
+func setTemplateDiskMode(disk *types.VirtualDisk, diskSpec infrav1.VirtualDiskSpec) error {
+	if err := validateTemplateDiskSpec(diskSpec); err != nil {
+		return err
+	}
+	if diskSpec.Mode == "" {
+		return nil
+	}
+	backing, ok := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo)
+	if !ok {
+		return errors.Errorf("mode of disk %q cannot be set, the disk has a %T backing", diskSpec.Name, disk.Backing)
+	}
+	backing.DiskMode = string(getDiskMode(diskSpec.Mode))
+	return nil
+}
+
+func validateTemplateDiskSpec(diskSpec infrav1.VirtualDiskSpec) error {
+	if diskSpec.ProvisioningType != "" {
+		return errors.Errorf("provisioning type of disk %q cannot be set, the disk is provided by the template", diskSpec.Name)
+	}
+	if diskSpec.Controller != "" {
+		return errors.Errorf("controller of disk %q cannot be set, the disk is provided by the template", diskSpec.Name)
+	}
+	return nil
+}
+
+// validateLinkedCloneDisks verifies the disk specs of the disks provided by the
+// template of a linked clone. These disks are backed by the snapshot of the
+// template, so only their placement, i.e. the datastore and storage policy, is
+// applied by the disk locators while their size and mode cannot be changed.
+func validateLinkedCloneDisks(vmCtx *capvcontext.VMContext, devices object.VirtualDeviceList) error {
+	disks := devices.SelectByType((*types.VirtualDisk)(nil))
+	for i := 1; i < len(disks) && i <= len(vmCtx.VSphereVM.Spec.Disks); i++ {
+		diskSpec := vmCtx.VSphereVM.Spec.Disks[i-1]
+		if err := validateTemplateDiskSpec(diskSpec); err != nil {
+			return err
+		}
+		if diskSpec.Mode != "" {
+			return errors.Errorf("mode of disk %q cannot be set, the disk of a linked clone is backed by the snapshot of the template", diskSpec.Name)
+		}
+		if capacityKB := int64(diskSpec.SizeGiB) * 1024 * 1024; capacityKB != 0 && capacityKB != disks[i].(*types.VirtualDisk).CapacityInKB {
+			return errors.Errorf("size of disk %q cannot be changed, the disk of a linked clone is backed by the snapshot of the template", diskSpec.Name)
+		}
+	}
+	return nil
+}
+
+// getNewDiskSpecs returns the device specs for the disks of the machine config
+// which are not provided by the template. The new disks are placed on the given
+// datastore unless a different one is configured for a disk.
+func getNewDiskSpecs(ctx context.Context, vmCtx *capvcontext.VMContext, devices object.VirtualDeviceList, datastoreRef types.ManagedObjectReference) ([]types.BaseVirtualDeviceConfigSpec, error) {
+	// The primary disk of the template is not part of the list of disks.
+	templateDisks := len(devices.SelectByType((*types.VirtualDisk)(nil))) - 1
+	if templateDisks < 0 {
+		templateDisks = 0
+	}
+	if len(vmCtx.VSphereVM.Spec.Disks) <= templateDisks {
+		return nil, nil
+	}
+
+	// New disks are added to a copy of the device list, so every disk is
+	// assigned a free unit number on its controller.
+	list := append(object.VirtualDeviceList{}, devices...)
+
+	var diskSpecs []types.BaseVirtualDeviceConfigSpec
+	for _, diskSpec := range vmCtx.VSphereVM.Spec.Disks[templateDisks:] {
+		if diskSpec.SizeGiB == 0 {
+			return nil, errors.Errorf("size of disk %q must be set, the disk is not provided by the template", diskSpec.Name)
+		}
+
+		controller, err := list.FindDiskController(getDiskControllerName(diskSpec.Controller))
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to find controller for disk %q", diskSpec.Name)
+		}
+
+		diskDatastoreRef := datastoreRef
+		if diskSpec.Datastore != "" {
+			datastore, err := vmCtx.Session.Finder.Datastore(ctx, diskSpec.Datastore)
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to get datastore %s for disk %q", diskSpec.Datastore, diskSpec.Name)
+			}
+			diskDatastoreRef = datastore.Reference()
+		}
+		datastoreName, err := object.NewDatastore(vmCtx.Session.Client.Client, diskDatastoreRef).ObjectName(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get name of datastore for disk %q", diskSpec.Name)
+		}
+
+		disk := list.CreateDisk(controller, diskDatastoreRef, "")
+		disk.CapacityInKB = int64(diskSpec.SizeGiB) * 1024 * 1024
+		backing := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo)
+		// Only naming the datastore creates the disk in the directory of the
+		// virtual machine on this datastore.
+		backing.FileName = (&object.DatastorePath{Datastore: datastoreName}).String()
+		switch diskSpec.ProvisioningType {
+		case infrav1.DiskProvisioningTypeThick:
+			backing.ThinProvisioned = pointer.Bool(false)
+		case infrav1.DiskProvisioningTypeEagerlyZeroedThick:
+			backing.ThinProvisioned = pointer.Bool(false)
+			backing.EagerlyScrub = pointer.Bool(true)
+		default:
+			backing.ThinProvisioned = pointer.Bool(true)
+		}
+		if diskSpec.Mode != "" {
+			backing.DiskMode = string(getDiskMode(diskSpec.Mode))
+		}
+		list = append(list, disk)
+
+		deviceSpec := &types.VirtualDeviceConfigSpec{
+			Operation:     types.VirtualDeviceConfigSpecOperationAdd,
+			FileOperation: types.VirtualDeviceConfigSpecFileOperationCreate,
+			Device:        disk,
+		}
+		if diskSpec.StoragePolicyName != "" {
+			deviceSpec.Profile, err = getStorageProfileSpec(ctx, vmCtx, diskSpec.StoragePolicyName)
+			if err != nil {
+				return nil, err
+			}
+		}
+		diskSpecs = append(diskSpecs, deviceSpec)
+		vmCtx.Logger.V(4).Info("created disk", "disk-spec", diskSpec)
+	}
+	return diskSpecs, nil
+}
+
+// setDiskLocatorPlacement places the disks provided by the template on the
+// datastore and with the storage policy configured for them. The first disk
+// locator belongs to the primary disk, which is not part of the list of disks.
+func setDiskLocatorPlacement(ctx context.Context, vmCtx *capvcontext.VMContext, diskLocators []types.VirtualMachineRelocateSpecDiskLocator) error {
+	for i := 1; i < len(diskLocators) && i <= len(vmCtx.VSphereVM.Spec.Disks); i++ {
+		diskSpec := vmCtx.VSphereVM.Spec.Disks[i-1]
+		if diskSpec.Datastore != "" {
+			datastore, err := vmCtx.Session.Finder.Datastore(ctx, diskSpec.Datastore)
+			if err != nil {
+				return errors.Wrapf(err, "unable to get datastore %s for disk %q", diskSpec.Datastore, diskSpec.Name)
+			}
+			diskLocators[i].Datastore = datastore.Reference()
+		}
+		if diskSpec.StoragePolicyName != "" {
+			profile, err := getStorageProfileSpec(ctx, vmCtx, diskSpec.StoragePolicyName)
+			if err != nil {
+				return err
+			}
+			diskLocators[i].Profile = profile
+		}
+	}
+	return nil
+}
+
+func getStorageProfileSpec(ctx context.Context, vmCtx *capvcontext.VMContext, storagePolicyName string) ([]types.BaseVirtualMachineProfileSpec, error) {
+	storageProfileID, err := getStorageProfileID(ctx, vmCtx, storagePolicyName)
+	if err != nil {
+		return nil, err
+	}
+	return []types.BaseVirtualMachineProfileSpec{
+		&types.VirtualMachineDefinedProfileSpec{ProfileId: storageProfileID},
+	}, nil
+}
+
+func getStorageProfileID(ctx context.Context, vmCtx *capvcontext.VMContext, storagePolicyName string) (string, error) {
+	pbmClient, err := pbm.NewClient(ctx, vmCtx.Session.Client.Client)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to create pbm client for %q", ctx)
+	}
+	storageProfileID, err := pbmClient.ProfileIDByName(ctx, storagePolicyName)
+	if err != nil {
+		return "", errors.Wrapf(err, "unable to get storageProfileID from name %s for %q", storagePolicyName, ctx)
+	}
+	return storageProfileID, nil
+}
+
+func getDiskMode(mode infrav1.DiskMode) types.VirtualDiskMode {
+	switch mode {
+	case infrav1.DiskModeIndependentPersistent:
+		return types.VirtualDiskModeIndependent_persistent
+	case infrav1.DiskModeIndependentNonPersistent:
+		return types.VirtualDiskModeIndependent_nonpersistent
+	default:
+		return types.VirtualDiskModePersistent
+	}
+}
+
+func getDiskControllerName(controller infrav1.DiskControllerType) string {
+	switch controller {
+	case infrav1.DiskControllerTypeNVME:
+		return "nvme"
+	case infrav1.DiskControllerTypeSATA:
+		return "sata"
+	default:
+		return "scsi"
+	}
+}
+
+const defaultEthCardType = infrav1.NetworkAdapterTypeVmxnet3
+
+func hasSRIOVNetworkDevice(devices []infrav1.NetworkDeviceSpec) bool {
+	for i := range devices {
+		if devices[i].AdapterType == infrav1.NetworkAdapterTypeSRIOV {
+			return true
+		}
+	}
+	return false
+}
 
 func getNetworkSpecs(ctx context.Context, vmCtx *capvcontext.VMContext, devices object.VirtualDeviceList) ([]types.BaseVirtualDeviceConfigSpec, error) {
 	deviceSpecs := []types.BaseVirtualDeviceConfigSpec{}
@@ -417,11 +755,28 @@ func getNetworkSpecs(ctx context.Context, vmCtx *capvcontext.VMContext, devices
 		if err != nil {
 			return nil, errors.Wrapf(err, "unable to create new ethernet card backing info for network %q on %q", netSpec.NetworkName, ctx)
 		}
-		dev, err := object.EthernetCardTypes().CreateEthernetCard(ethCardType, backing)
+		ethCardType := netSpec.AdapterType
+		if ethCardType == "" {
+			ethCardType = defaultEthCardType
+		}
+		dev, err := object.EthernetCardTypes().CreateEthernetCard(string(ethCardType), backing)
 		if err != nil {
 			return nil, errors.Wrapf(err, "unable to create new ethernet card %q for network %q on %q", ethCardType, netSpec.NetworkName, ctx)
 		}
 
+		// SR-IOV network devices are backed by a virtual function of the
+		// configured physical function.
+		if sriov, ok := dev.(*types.VirtualSriovEthernetCard); ok {
+			if netSpec.PhysicalFunction == "" {
+				return nil, errors.Errorf("physical function must be set for sriov ethernet card for network %q on %q", netSpec.NetworkName, ctx)
+			}
+			sriov.SriovBacking = &types.VirtualSriovEthernetCardSriovBackingInfo{
+				PhysicalFunctionBacking: &types.VirtualPCIPassthroughDeviceBackingInfo{
+					Id: netSpec.PhysicalFunction,
+				},
+			}
+		}
+
 		// Get the actual NIC object. This is safe to assert without a check
 		// because "object.EthernetCardTypes().CreateEthernetCard" returns a
 		// "types.BaseVirtualEthernetCard" as a "types.BaseVirtualDevice".
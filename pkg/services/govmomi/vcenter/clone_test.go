@@ -19,8 +19,10 @@ package vcenter
 import (
 	ctx "context"
 	"crypto/tls"
+	"fmt"
 	"testing"
 
+	"github.com/go-logr/logr"
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/simulator"
 	_ "github.com/vmware/govmomi/vapi/simulator" // run init func to register the tagging API endpoints.
@@ -58,6 +60,7 @@ func TestGetDiskSpec(t *testing.T) {
 		expectDevice             bool
 		cloneDiskSize            int32
 		additionalCloneDiskSizes []int32
+		additionalDisks          []infrav1.VirtualDiskSpec
 		name                     string
 		disks                    object.VirtualDeviceList
 		err                      string
@@ -106,6 +109,20 @@ func TestGetDiskSpec(t *testing.T) {
 			additionalCloneDiskSizes: []int32{defaultSizeGiB},
 			err:                      "Error getting disk config spec for additional disk: can't resize template disk down, initial capacity is larger: 23068672KiB > 20971520KiB",
 		},
+		{
+			name:            "Successfully clone template and increase second disk size using disks",
+			disks:           append(defaultDisks, defaultDisks...),
+			cloneDiskSize:   defaultSizeGiB + 2,
+			additionalDisks: []infrav1.VirtualDiskSpec{{Name: "data", SizeGiB: defaultSizeGiB + 2, Mode: infrav1.DiskModeIndependentPersistent}},
+			expectDevice:    true,
+		},
+		{
+			name:            "Fails to clone template and change provisioning type of second disk",
+			disks:           append(defaultDisks, defaultDisks...),
+			cloneDiskSize:   defaultSizeGiB + 3,
+			additionalDisks: []infrav1.VirtualDiskSpec{{Name: "data", ProvisioningType: infrav1.DiskProvisioningTypeThick}},
+			err:             "provisioning type of disk \"data\" cannot be set, the disk is provided by the template",
+		},
 	}
 
 	for _, test := range testCases {
@@ -114,6 +131,7 @@ func TestGetDiskSpec(t *testing.T) {
 			cloneSpec := infrav1.VirtualMachineCloneSpec{
 				DiskGiB:            tc.cloneDiskSize,
 				AdditionalDisksGiB: tc.additionalCloneDiskSizes,
+				Disks:              tc.additionalDisks,
 			}
 			vsphereVM := &infrav1.VSphereVM{
 				Spec: infrav1.VSphereVMSpec{
@@ -135,6 +153,231 @@ func TestGetDiskSpec(t *testing.T) {
 					secondaryDevice := devices[1]
 					validateDiskSpec(t, secondaryDevice, tc.additionalCloneDiskSizes[0])
 				}
+				if len(tc.additionalDisks) != 0 {
+					secondaryDevice := devices[1]
+					validateDiskSpec(t, secondaryDevice, tc.additionalDisks[0].SizeGiB)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateLinkedCloneDisks(t *testing.T) {
+	newDisk := func(capacityGiB int64) *types.VirtualDisk {
+		return &types.VirtualDisk{CapacityInKB: capacityGiB * 1024 * 1024}
+	}
+	devices := object.VirtualDeviceList{newDisk(20), newDisk(10)}
+
+	testCases := []struct {
+		name  string
+		disks []infrav1.VirtualDiskSpec
+		err   string
+	}{
+		{
+			name:  "placement of template disk and new disk",
+			disks: []infrav1.VirtualDiskSpec{{Name: "data", Datastore: "fast", StoragePolicyName: "gold"}, {Name: "etcd", SizeGiB: 5, Mode: infrav1.DiskModeIndependentPersistent}},
+		},
+		{
+			name:  "size of template disk",
+			disks: []infrav1.VirtualDiskSpec{{Name: "data", SizeGiB: 10}},
+		},
+		{
+			name:  "resize of template disk",
+			disks: []infrav1.VirtualDiskSpec{{Name: "data", SizeGiB: 11}},
+			err:   "size of disk \"data\" cannot be changed, the disk of a linked clone is backed by the snapshot of the template",
+		},
+		{
+			name:  "mode of template disk",
+			disks: []infrav1.VirtualDiskSpec{{Name: "data", Mode: infrav1.DiskModeIndependentPersistent}},
+			err:   "mode of disk \"data\" cannot be set, the disk of a linked clone is backed by the snapshot of the template",
+		},
+		{
+			name:  "controller of template disk",
+			disks: []infrav1.VirtualDiskSpec{{Name: "data", Controller: infrav1.DiskControllerTypeNVME}},
+			err:   "controller of disk \"data\" cannot be set, the disk is provided by the template",
+		},
+	}
+
+	for _, test := range testCases {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			vmContext := &capvcontext.VMContext{VSphereVM: &infrav1.VSphereVM{
+				Spec: infrav1.VSphereVMSpec{
+					VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{Disks: tc.disks},
+				},
+			}}
+			err := validateLinkedCloneDisks(vmContext, devices)
+			if (tc.err != "" && err == nil) || (tc.err == "" && err != nil) || (err != nil && tc.err != err.Error()) {
+				t.Fatalf("Expected to get '%v' error from validateLinkedCloneDisks, got: '%v'", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestGetNewDiskSpecs(t *testing.T) {
+	model, session, server := initSimulator(t)
+	t.Cleanup(model.Remove)
+	t.Cleanup(server.Close)
+	vm := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine)
+	machine := object.NewVirtualMachine(session.Client.Client, vm.Reference())
+
+	devices, err := machine.Device(ctx.TODO())
+	if err != nil {
+		t.Fatalf("Failed to obtain vm devices: %v", err)
+	}
+	if templateDisks := len(devices.SelectByType((*types.VirtualDisk)(nil))); templateDisks != 1 {
+		t.Fatalf("Expected the template to have a single disk, got: %d", templateDisks)
+	}
+	datastore, err := session.Finder.DefaultDatastore(ctx.TODO())
+	if err != nil {
+		t.Fatalf("Failed to obtain default datastore: %v", err)
+	}
+
+	testCases := []struct {
+		name          string
+		disks         []infrav1.VirtualDiskSpec
+		expectedDisks int
+		err           string
+	}{
+		{
+			name: "No new disks without disks",
+		},
+		{
+			name: "Successfully add new disks",
+			disks: []infrav1.VirtualDiskSpec{
+				{Name: "etcd", SizeGiB: 10, ProvisioningType: infrav1.DiskProvisioningTypeEagerlyZeroedThick},
+				{Name: "containerd", SizeGiB: 50, Mode: infrav1.DiskModeIndependentPersistent},
+			},
+			expectedDisks: 2,
+		},
+		{
+			name:  "Fail to add new disk without size",
+			disks: []infrav1.VirtualDiskSpec{{Name: "etcd"}},
+			err:   "size of disk \"etcd\" must be set, the disk is not provided by the template",
+		},
+	}
+
+	for _, test := range testCases {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			vsphereVM := &infrav1.VSphereVM{
+				Spec: infrav1.VSphereVMSpec{
+					VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+						Disks: tc.disks,
+					},
+				},
+			}
+			vmContext := &capvcontext.VMContext{VSphereVM: vsphereVM, Session: session, Logger: logr.Discard()}
+			diskSpecs, err := getNewDiskSpecs(ctx.TODO(), vmContext, devices, datastore.Reference())
+			if (tc.err != "" && err == nil) || (tc.err == "" && err != nil) || (err != nil && tc.err != err.Error()) {
+				t.Fatalf("Expected to get '%v' error from getNewDiskSpecs, got: '%v'", tc.err, err)
+			}
+			if len(diskSpecs) != tc.expectedDisks {
+				t.Fatalf("Expected to get %d disk specs, got: %d", tc.expectedDisks, len(diskSpecs))
+			}
+
+			unitNumbers := map[int32]bool{}
+			for i, diskSpec := range diskSpecs {
+				spec := diskSpec.GetVirtualDeviceConfigSpec()
+				if spec.Operation != types.VirtualDeviceConfigSpecOperationAdd || spec.FileOperation != types.VirtualDeviceConfigSpecFileOperationCreate {
+					t.Errorf("Disk operation does not match add/create, got: %s/%s", spec.Operation, spec.FileOperation)
+				}
+				disk := spec.Device.(*types.VirtualDisk)
+				if expectedSizeKB := int64(tc.disks[i].SizeGiB) * 1024 * 1024; disk.CapacityInKB != expectedSizeKB {
+					t.Errorf("Disk size does not match: expected %d, got %d", expectedSizeKB, disk.CapacityInKB)
+				}
+				if unitNumbers[*disk.UnitNumber] {
+					t.Errorf("Disk unit number %d is assigned twice", *disk.UnitNumber)
+				}
+				unitNumbers[*disk.UnitNumber] = true
+			}
+			if tc.expectedDisks == 2 {
+				backing := diskSpecs[0].GetVirtualDeviceConfigSpec().Device.(*types.VirtualDisk).Backing.(*types.VirtualDiskFlatVer2BackingInfo)
+				if *backing.ThinProvisioned || !*backing.EagerlyScrub {
+					t.Errorf("Expected first disk to be eagerly zeroed thick provisioned")
+				}
+				backing = diskSpecs[1].GetVirtualDeviceConfigSpec().Device.(*types.VirtualDisk).Backing.(*types.VirtualDiskFlatVer2BackingInfo)
+				if backing.DiskMode != string(types.VirtualDiskModeIndependent_persistent) {
+					t.Errorf("Expected second disk to be independent persistent, got: %s", backing.DiskMode)
+				}
+			}
+		})
+	}
+}
+
+func TestGetNetworkSpecs(t *testing.T) {
+	model, session, server := initSimulator(t)
+	t.Cleanup(model.Remove)
+	t.Cleanup(server.Close)
+	vm := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine)
+	machine := object.NewVirtualMachine(session.Client.Client, vm.Reference())
+
+	devices, err := machine.Device(ctx.TODO())
+	if err != nil {
+		t.Fatalf("Failed to obtain vm devices: %v", err)
+	}
+	existingNICs := len(devices.SelectByType((*types.VirtualEthernetCard)(nil)))
+
+	testCases := []struct {
+		name             string
+		device           infrav1.NetworkDeviceSpec
+		expectedCardType types.BaseVirtualDevice
+		err              string
+	}{
+		{
+			name:             "Default to vmxnet3",
+			device:           infrav1.NetworkDeviceSpec{NetworkName: "VM Network"},
+			expectedCardType: &types.VirtualVmxnet3{},
+		},
+		{
+			name:             "Successfully create e1000e ethernet card",
+			device:           infrav1.NetworkDeviceSpec{NetworkName: "VM Network", AdapterType: infrav1.NetworkAdapterTypeE1000e},
+			expectedCardType: &types.VirtualE1000e{},
+		},
+		{
+			name:             "Successfully create sriov ethernet card",
+			device:           infrav1.NetworkDeviceSpec{NetworkName: "VM Network", AdapterType: infrav1.NetworkAdapterTypeSRIOV, PhysicalFunction: "0000:3b:00.0"},
+			expectedCardType: &types.VirtualSriovEthernetCard{},
+		},
+		{
+			name:   "Fail to create sriov ethernet card without physical function",
+			device: infrav1.NetworkDeviceSpec{NetworkName: "VM Network", AdapterType: infrav1.NetworkAdapterTypeSRIOV},
+			err:    "physical function must be set for sriov ethernet card for network \"VM Network\" on \"context.TODO\"",
+		},
+	}
+
+	for _, test := range testCases {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			vsphereVM := &infrav1.VSphereVM{
+				Spec: infrav1.VSphereVMSpec{
+					VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+						Network: infrav1.NetworkSpec{Devices: []infrav1.NetworkDeviceSpec{tc.device}},
+					},
+				},
+			}
+			vmContext := &capvcontext.VMContext{VSphereVM: vsphereVM, Session: session, Logger: logr.Discard()}
+			deviceSpecs, err := getNetworkSpecs(ctx.TODO(), vmContext, devices)
+			if (tc.err != "" && err == nil) || (tc.err == "" && err != nil) || (err != nil && tc.err != err.Error()) {
+				t.Fatalf("Expected to get '%v' error from getNetworkSpecs, got: '%v'", tc.err, err)
+			}
+			if tc.err != "" {
+				return
+			}
+			if len(deviceSpecs) != existingNICs+1 {
+				t.Fatalf("Expected to get %d device specs, got: %d", existingNICs+1, len(deviceSpecs))
+			}
+			added := deviceSpecs[existingNICs].GetVirtualDeviceConfigSpec()
+			if added.Operation != types.VirtualDeviceConfigSpecOperationAdd {
+				t.Errorf("Network device operation does not match '%s', got: %s", types.VirtualDeviceConfigSpecOperationAdd, added.Operation)
+			}
+			if actual, expected := fmt.Sprintf("%T", added.Device), fmt.Sprintf("%T", tc.expectedCardType); actual != expected {
+				t.Errorf("Ethernet card type does not match: expected %s, got %s", expected, actual)
+			}
+			if sriov, ok := added.Device.(*types.VirtualSriovEthernetCard); ok {
+				if sriov.SriovBacking == nil || sriov.SriovBacking.PhysicalFunctionBacking.Id != tc.device.PhysicalFunction {
+					t.Errorf("SR-IOV physical function does not match %s", tc.device.PhysicalFunction)
+				}
 			}
 		})
 	}
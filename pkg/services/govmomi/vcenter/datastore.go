@@ -0,0 +1,165 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"context"
+	"math"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+// datastoreSelector chooses one of the given accessible datastores.
+type datastoreSelector func(ctx context.Context, vmCtx *capvcontext.VMContext, datastores []mo.Datastore) (*mo.Datastore, error)
+
+// datastoreSelectors maps each DatastoreSelectionPolicy to its implementation.
+var datastoreSelectors = map[infrav1.DatastoreSelectionPolicy]datastoreSelector{
+	infrav1.DatastoreSelectionPolicyMostFreeSpace: func(_ context.Context, _ *capvcontext.VMContext, datastores []mo.Datastore) (*mo.Datastore, error) {
+		return mostFreeSpace(datastores), nil
+	},
+	infrav1.DatastoreSelectionPolicyLeastProvisioned: func(_ context.Context, _ *capvcontext.VMContext, datastores []mo.Datastore) (*mo.Datastore, error) {
+		return leastProvisioned(datastores), nil
+	},
+	infrav1.DatastoreSelectionPolicyRoundRobin: selectRoundRobin,
+}
+
+// selectCompatibleDatastore chooses one of the datastores compatible with the
+// storage policy of the VSphereVM according to its DatastoreSelectionPolicy.
+// Datastores which are not accessible are never chosen.
+func selectCompatibleDatastore(ctx context.Context, vmCtx *capvcontext.VMContext, refs []types.ManagedObjectReference) (*types.ManagedObjectReference, error) {
+	policy := vmCtx.VSphereVM.Spec.DatastoreSelectionPolicy
+	if policy == "" {
+		policy = infrav1.DatastoreSelectionPolicyMostFreeSpace
+	}
+	selector, ok := datastoreSelectors[policy]
+	if !ok {
+		return nil, errors.Errorf("unsupported datastore selection policy %q", policy)
+	}
+
+	var datastores []mo.Datastore
+	pc := property.DefaultCollector(vmCtx.Session.Client.Client)
+	if err := pc.Retrieve(ctx, refs, []string{"summary"}, &datastores); err != nil {
+		return nil, errors.Wrapf(err, "unable to get summary of datastores compatible with storage policy %s", vmCtx.VSphereVM.Spec.StoragePolicyName)
+	}
+
+	accessible := make([]mo.Datastore, 0, len(datastores))
+	for _, ds := range datastores {
+		if ds.Summary.Accessible {
+			accessible = append(accessible, ds)
+		}
+	}
+	if len(accessible) == 0 {
+		return nil, errors.Errorf("no accessible datastores found for storage policy: %s", vmCtx.VSphereVM.Spec.StoragePolicyName)
+	}
+
+	ds, err := selector(ctx, vmCtx, accessible)
+	if err != nil {
+		return nil, err
+	}
+	vmCtx.Logger.Info("selected datastore compatible with storage policy", "policy", policy, "datastore", ds.Summary.Name, "freeSpace", ds.Summary.FreeSpace)
+	return types.NewReference(ds.Reference()), nil
+}
+
+// mostFreeSpace returns the datastore with the most free space.
+func mostFreeSpace(datastores []mo.Datastore) *mo.Datastore {
+	var selected *mo.Datastore
+	for i := range datastores {
+		if selected == nil || datastores[i].Summary.FreeSpace > selected.Summary.FreeSpace {
+			selected = &datastores[i]
+		}
+	}
+	return selected
+}
+
+// leastProvisioned returns the datastore with the lowest ratio of provisioned
+// space to capacity. Provisioned space includes the space not yet committed
+// by thin provisioned disks. Ties are broken by free space.
+func leastProvisioned(datastores []mo.Datastore) *mo.Datastore {
+	var (
+		selected      *mo.Datastore
+		selectedRatio float64
+	)
+	for i := range datastores {
+		ratio := provisionedRatio(datastores[i].Summary)
+		if selected == nil || ratio < selectedRatio ||
+			(ratio == selectedRatio && datastores[i].Summary.FreeSpace > selected.Summary.FreeSpace) {
+			selected, selectedRatio = &datastores[i], ratio
+		}
+	}
+	return selected
+}
+
+func provisionedRatio(summary types.DatastoreSummary) float64 {
+	if summary.Capacity <= 0 {
+		// A datastore without a known capacity is never preferred.
+		return math.MaxFloat64
+	}
+	provisioned := summary.Capacity - summary.FreeSpace + summary.Uncommitted
+	return float64(provisioned) / float64(summary.Capacity)
+}
+
+// selectRoundRobin returns the datastore hosting the fewest VSphereVMs of the
+// cluster of the VSphereVM. Ties are broken by free space.
+func selectRoundRobin(ctx context.Context, vmCtx *capvcontext.VMContext, datastores []mo.Datastore) (*mo.Datastore, error) {
+	clusterName, ok := vmCtx.VSphereVM.Labels[clusterv1.ClusterNameLabel]
+	if !ok {
+		return nil, errors.Errorf("missing CAPI cluster label on %s", vmCtx)
+	}
+
+	vmList := &infrav1.VSphereVMList{}
+	if err := vmCtx.Client.List(
+		ctx, vmList,
+		client.InNamespace(vmCtx.VSphereVM.Namespace),
+		client.MatchingLabels{clusterv1.ClusterNameLabel: clusterName}); err != nil {
+		return nil, errors.Wrapf(err, "failed to list VSphereVMs of cluster %s", clusterName)
+	}
+
+	return leastUsed(datastores, vmList.Items), nil
+}
+
+// leastUsed returns the datastore on which the fewest of the given VSphereVMs
+// were placed. Ties are broken by free space.
+func leastUsed(datastores []mo.Datastore, vms []infrav1.VSphereVM) *mo.Datastore {
+	placed := map[string]int{}
+	for _, vm := range vms {
+		if vm.Status.Datastore != "" {
+			placed[vm.Status.Datastore]++
+		}
+	}
+
+	var selected *mo.Datastore
+	for i := range datastores {
+		if selected == nil {
+			selected = &datastores[i]
+			continue
+		}
+		count, selectedCount := placed[datastores[i].Summary.Name], placed[selected.Summary.Name]
+		if count < selectedCount ||
+			(count == selectedCount && datastores[i].Summary.FreeSpace > selected.Summary.FreeSpace) {
+			selected = &datastores[i]
+		}
+	}
+	return selected
+}
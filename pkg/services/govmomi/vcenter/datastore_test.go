@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+const giB = int64(1024 * 1024 * 1024)
+
+func newDatastore(name string, capacity, freeSpace, uncommitted int64) mo.Datastore {
+	return mo.Datastore{
+		Summary: types.DatastoreSummary{
+			Name:        name,
+			Capacity:    capacity * giB,
+			FreeSpace:   freeSpace * giB,
+			Uncommitted: uncommitted * giB,
+			Accessible:  true,
+		},
+	}
+}
+
+func newPlacedVM(datastore string) infrav1.VSphereVM {
+	return infrav1.VSphereVM{Status: infrav1.VSphereVMStatus{Datastore: datastore}}
+}
+
+func TestSelectDatastore(t *testing.T) {
+	datastores := []mo.Datastore{
+		// 80% provisioned, 400GiB free.
+		newDatastore("ds-large", 2000, 400, 0),
+		// 60% provisioned, 300GiB free.
+		newDatastore("ds-thin", 1000, 700, 300),
+		// 50% provisioned, 250GiB free.
+		newDatastore("ds-small", 500, 250, 0),
+	}
+
+	testCases := []struct {
+		name     string
+		selector func([]mo.Datastore) *mo.Datastore
+		expected string
+	}{
+		{
+			name:     "most free space",
+			selector: mostFreeSpace,
+			expected: "ds-thin",
+		},
+		{
+			name:     "least provisioned ignores free space",
+			selector: leastProvisioned,
+			expected: "ds-small",
+		},
+		{
+			name: "least provisioned breaks ties by free space",
+			selector: func(datastores []mo.Datastore) *mo.Datastore {
+				return leastProvisioned(append(datastores, newDatastore("ds-small-twin", 1000, 500, 0)))
+			},
+			expected: "ds-small-twin",
+		},
+		{
+			name: "least provisioned never prefers datastores without capacity",
+			selector: func(datastores []mo.Datastore) *mo.Datastore {
+				return leastProvisioned([]mo.Datastore{newDatastore("ds-unknown", 0, 0, 0), datastores[0]})
+			},
+			expected: "ds-large",
+		},
+		{
+			name: "round robin without placed machines breaks ties by free space",
+			selector: func(datastores []mo.Datastore) *mo.Datastore {
+				return leastUsed(datastores, []infrav1.VSphereVM{newPlacedVM("")})
+			},
+			expected: "ds-thin",
+		},
+		{
+			name: "round robin prefers the datastore with the fewest machines",
+			selector: func(datastores []mo.Datastore) *mo.Datastore {
+				return leastUsed(datastores, []infrav1.VSphereVM{
+					newPlacedVM("ds-thin"),
+					newPlacedVM("ds-thin"),
+					newPlacedVM("ds-large"),
+					newPlacedVM("ds-small"),
+				})
+			},
+			expected: "ds-large",
+		},
+	}
+
+	for _, test := range testCases {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			selected := tc.selector(datastores)
+			if selected == nil {
+				t.Fatal("Expected a datastore to be selected")
+			}
+			if selected.Summary.Name != tc.expected {
+				t.Errorf("Expected datastore %q, got %q", tc.expected, selected.Summary.Name)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/services/govmomi/extra"
+)
+
+// instantClone kicks off an instant clone operation which forks the running
+// source VM into a new virtual machine. The guestinfo keys in extraConfig are
+// injected into the new virtual machine, the guest of the frozen source VM is
+// expected to pick them up once it resumes.
+func instantClone(ctx context.Context, vmCtx *capvcontext.VMContext, src *object.VirtualMachine, folder *object.Folder, pool *object.ResourcePool, datastoreRef *types.ManagedObjectReference, extraConfig extra.Config) (*object.Task, error) {
+	var obj mo.VirtualMachine
+	if err := src.Properties(ctx, src.Reference(), []string{"runtime.powerState", "config.hardware.memoryMB", "config.memoryReservationLockedToMax", "config.cpuAllocation", "config.memoryAllocation"}, &obj); err != nil {
+		return nil, errors.Wrapf(err, "error getting power state and configuration of instant clone source %s", vmCtx.VSphereVM.Spec.Template)
+	}
+	if obj.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOn {
+		return nil, errors.Errorf("instant clone source %s must be powered on, but is %s", vmCtx.VSphereVM.Spec.Template, obj.Runtime.PowerState)
+	}
+	if obj.Config == nil {
+		return nil, errors.Errorf("error getting configuration of instant clone source %s", vmCtx.VSphereVM.Spec.Template)
+	}
+	if err := checkCapacity(ctx, vmCtx, pool, getInstantCloneCapacitySpec(obj.Config, datastoreRef), nil); err != nil {
+		return nil, err
+	}
+
+	devices, err := src.Device(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting devices for %q", ctx)
+	}
+
+	networkSpecs, err := getInstantCloneNetworkSpecs(ctx, vmCtx, devices)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting network specs for %q", ctx)
+	}
+
+	spec := types.VirtualMachineInstantCloneSpec{
+		Name: vmCtx.VSphereVM.Name,
+		Location: types.VirtualMachineRelocateSpec{
+			Folder:       types.NewReference(folder.Reference()),
+			Pool:         types.NewReference(pool.Reference()),
+			Datastore:    datastoreRef,
+			DeviceChange: networkSpecs,
+		},
+		Config: extraConfig,
+	}
+
+	vmCtx.VSphereVM.Status.CloneMode = infrav1.InstantClone
+
+	vmCtx.Logger.Info("instant cloning machine", "namespace", vmCtx.VSphereVM.Namespace, "name", vmCtx.VSphereVM.Name, "source", vmCtx.VSphereVM.Spec.Template)
+	task, err := src.InstantClone(ctx, spec)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error trigging instant clone op for machine %s", ctx)
+	}
+	return task, nil
+}
+
+// getInstantCloneNetworkSpecs connects the network devices of the instant clone
+// source to the networks of the machine config. Network devices cannot be added
+// or removed by an instant clone, so the source must have exactly one network
+// device for every device of the machine config.
+func getInstantCloneNetworkSpecs(ctx context.Context, vmCtx *capvcontext.VMContext, devices object.VirtualDeviceList) ([]types.BaseVirtualDeviceConfigSpec, error) {
+	nics := devices.SelectByType((*types.VirtualEthernetCard)(nil))
+	if len(nics) != len(vmCtx.VSphereVM.Spec.Network.Devices) {
+		return nil, errors.Errorf("instant clone source has %d network devices, but %d are configured", len(nics), len(vmCtx.VSphereVM.Spec.Network.Devices))
+	}
+
+	deviceSpecs := make([]types.BaseVirtualDeviceConfigSpec, 0, len(nics))
+	for i, dev := range nics {
+		netSpec := &vmCtx.VSphereVM.Spec.Network.Devices[i]
+		ref, err := vmCtx.Session.Finder.Network(ctx, netSpec.NetworkName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to find network %q", netSpec.NetworkName)
+		}
+		backing, err := ref.EthernetCardBackingInfo(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to create new ethernet card backing info for network %q on %q", netSpec.NetworkName, ctx)
+		}
+
+		nic := dev.(types.BaseVirtualEthernetCard).GetVirtualEthernetCard()
+		nic.Backing = backing
+
+		// The MAC address of the source must not be inherited by the clone,
+		// so either assign the configured one or let vSphere generate one.
+		if netSpec.MACAddr != "" {
+			nic.MacAddress = netSpec.MACAddr
+			nic.AddressType = string(types.VirtualEthernetCardMacTypeManual)
+			vmCtx.Logger.V(4).Info("configured manual mac address", "mac-addr", nic.MacAddress)
+		} else {
+			nic.MacAddress = ""
+			nic.AddressType = string(types.VirtualEthernetCardMacTypeGenerated)
+		}
+
+		deviceSpecs = append(deviceSpecs, &types.VirtualDeviceConfigSpec{
+			Device:    dev,
+			Operation: types.VirtualDeviceConfigSpecOperationEdit,
+		})
+		vmCtx.Logger.V(4).Info("reconnected network device", "network-spec", netSpec)
+	}
+
+	return deviceSpecs, nil
+}
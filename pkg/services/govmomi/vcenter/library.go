@@ -0,0 +1,202 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vapi/library"
+	vapivcenter "github.com/vmware/govmomi/vapi/vcenter"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+// libraryDeployments holds the deployments of Content Library items that are
+// in progress, keyed by the UID of the VSphereVM.
+var libraryDeployments sync.Map
+
+// libraryDeployment is a deployment of a Content Library item running in the
+// background. The vSphere API deploys items synchronously, which can take
+// minutes for large OVF templates.
+type libraryDeployment struct {
+	done chan struct{}
+	ref  *types.ManagedObjectReference
+	err  error
+}
+
+// deployLibraryItem deploys the given Content Library item as a new virtual
+// machine named after the VSphereVM. The deployment runs in the background
+// and the VSphereVM is reconciled again once it is complete, nil is returned
+// while the deployment is in progress. The returned virtual machine still has
+// to be reconfigured with the clone spec built for the VSphereVM.
+//
+// A virtual machine left behind by a previous deployment that was interrupted
+// before it could be reconfigured is reused instead of deploying the item again.
+func deployLibraryItem(ctx context.Context, vmCtx *capvcontext.VMContext, item *library.Item, folder *object.Folder, pool *object.ResourcePool, datastoreRef *types.ManagedObjectReference) (*object.VirtualMachine, error) {
+	if obj, ok := libraryDeployments.Load(vmCtx.VSphereVM.UID); ok {
+		deployment := obj.(*libraryDeployment)
+		select {
+		case <-deployment.done:
+			libraryDeployments.Delete(vmCtx.VSphereVM.UID)
+			if deployment.err != nil {
+				return nil, errors.Wrapf(deployment.err, "error deploying content library item %q for %s", item.Name, vmCtx)
+			}
+			return object.NewVirtualMachine(vmCtx.Session.Client.Client, *deployment.ref), nil
+		default:
+			vmCtx.Logger.Info("content library item is being deployed", "item", item.Name)
+			return nil, nil
+		}
+	}
+
+	annotation := libraryItemAnnotation(vmCtx)
+
+	vm, err := findDeployedLibraryItem(ctx, vmCtx, folder, annotation)
+	if err != nil {
+		return nil, err
+	}
+	if vm != nil {
+		vmCtx.Logger.Info("reusing previously deployed content library item", "item", item.Name, "vm", vm.Reference().Value)
+		return vm, nil
+	}
+
+	// The disks of the deployed virtual machine are placed with the storage
+	// policy of the VSphereVM, as they are not relocated afterwards.
+	var storageProfileID string
+	if vmCtx.VSphereVM.Spec.StoragePolicyName != "" {
+		storageProfileID, err = getStorageProfileID(ctx, vmCtx, vmCtx.VSphereVM.Spec.StoragePolicyName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var deploy func(context.Context, *vapivcenter.Manager) (*types.ManagedObjectReference, error)
+	switch item.Type {
+	case library.ItemTypeOVF:
+		spec := vapivcenter.Deploy{
+			DeploymentSpec: vapivcenter.DeploymentSpec{
+				Name:               vmCtx.VSphereVM.Name,
+				Annotation:         annotation,
+				DefaultDatastoreID: datastoreRef.Value,
+				StorageProfileID:   storageProfileID,
+				AcceptAllEULA:      true,
+			},
+			Target: vapivcenter.Target{
+				ResourcePoolID: pool.Reference().Value,
+				FolderID:       folder.Reference().Value,
+			},
+		}
+		deploy = func(ctx context.Context, manager *vapivcenter.Manager) (*types.ManagedObjectReference, error) {
+			return manager.DeployLibraryItem(ctx, item.ID, spec)
+		}
+	case library.ItemTypeVMTX:
+		storage := &vapivcenter.DiskStorage{Datastore: datastoreRef.Value}
+		if storageProfileID != "" {
+			storage.StoragePolicy = &vapivcenter.StoragePolicy{Policy: storageProfileID, Type: "USE_SPECIFIED_POLICY"}
+		}
+		spec := vapivcenter.DeployTemplate{
+			Name:          vmCtx.VSphereVM.Name,
+			Description:   annotation,
+			VMHomeStorage: storage,
+			DiskStorage:   storage,
+			Placement: &vapivcenter.Placement{
+				ResourcePool: pool.Reference().Value,
+				Folder:       folder.Reference().Value,
+			},
+		}
+		deploy = func(ctx context.Context, manager *vapivcenter.Manager) (*types.ManagedObjectReference, error) {
+			return manager.DeployTemplateLibraryItem(ctx, item.ID, spec)
+		}
+	default:
+		return nil, errors.Errorf("unsupported content library item type %q", item.Type)
+	}
+
+	if err := checkCapacity(ctx, vmCtx, pool, getLibraryItemCapacitySpec(vmCtx, datastoreRef), nil); err != nil {
+		return nil, err
+	}
+
+	vmCtx.Logger.Info("deploying content library item", "item", item.Name, "type", item.Type)
+	deployment := &libraryDeployment{done: make(chan struct{})}
+	libraryDeployments.Store(vmCtx.VSphereVM.UID, deployment)
+
+	manager := vapivcenter.NewManager(vmCtx.Session.TagManager.Client)
+	obj := vmCtx.VSphereVM.DeepCopy()
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	go func() {
+		// The deployment outlives the reconcile which started it.
+		deployment.ref, deployment.err = deploy(context.Background(), manager)
+		close(deployment.done)
+
+		// Trigger a reconcile of the VSphereVM to reconfigure the deployed
+		// virtual machine.
+		vmCtx.Logger.Info("content library item deployment completed", "item", item.Name, "error", deployment.err)
+		vmCtx.GetGenericEventChannelFor(gvk) <- event.GenericEvent{Object: obj}
+	}()
+	return nil, nil
+}
+
+// IsUnconfiguredLibraryItem returns true if the given virtual machine was
+// deployed from a Content Library item for the VSphereVM but not reconfigured
+// yet, i.e. it does not have the instance UUID of the VSphereVM.
+func IsUnconfiguredLibraryItem(ctx context.Context, vmCtx *capvcontext.VMContext, vm *object.VirtualMachine) (bool, error) {
+	if vmCtx.VSphereVM.Spec.ContentLibrary == nil {
+		return false, nil
+	}
+	var obj mo.VirtualMachine
+	if err := vm.Properties(ctx, vm.Reference(), []string{"config.annotation", "config.instanceUuid"}, &obj); err != nil {
+		return false, errors.Wrapf(err, "error getting configuration of virtual machine %q", vmCtx.VSphereVM.Name)
+	}
+	if obj.Config == nil {
+		return false, nil
+	}
+	return strings.Contains(obj.Config.Annotation, libraryItemAnnotation(vmCtx)) && obj.Config.InstanceUuid != string(vmCtx.VSphereVM.UID), nil
+}
+
+// findDeployedLibraryItem returns the virtual machine named after the VSphereVM
+// in the given folder if it was deployed from a Content Library item for this
+// very VSphereVM.
+func findDeployedLibraryItem(ctx context.Context, vmCtx *capvcontext.VMContext, folder *object.Folder, annotation string) (*object.VirtualMachine, error) {
+	vm, err := vmCtx.Session.Finder.VirtualMachine(ctx, path.Join(folder.InventoryPath, vmCtx.VSphereVM.Name))
+	if err != nil {
+		if _, ok := err.(*find.NotFoundError); ok {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "error searching for virtual machine %q", vmCtx.VSphereVM.Name)
+	}
+
+	var obj mo.VirtualMachine
+	if err := vm.Properties(ctx, vm.Reference(), []string{"config.annotation"}, &obj); err != nil {
+		return nil, errors.Wrapf(err, "error getting annotation of virtual machine %q", vmCtx.VSphereVM.Name)
+	}
+	if obj.Config == nil || !strings.Contains(obj.Config.Annotation, annotation) {
+		return nil, errors.Errorf("virtual machine %q already exists and was not deployed for %s", vmCtx.VSphereVM.Name, vmCtx)
+	}
+	return vm, nil
+}
+
+func libraryItemAnnotation(vmCtx *capvcontext.VMContext) string {
+	return fmt.Sprintf("Deployed by Cluster API Provider vSphere for VSphereVM %s", vmCtx.VSphereVM.UID)
+}
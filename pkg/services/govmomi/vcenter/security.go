@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/types"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+// vtpmDeviceKey is the temporary device key of the virtual TPM added to the
+// clone spec.
+const vtpmDeviceKey = int32(-300)
+
+// applySecuritySpec sets the firmware, Secure Boot and virtual TPM requested
+// in the security section of the VSphereVM on the given config spec. The
+// virtual TPM is only added if the template does not provide one already.
+func applySecuritySpec(ctx context.Context, vmCtx *capvcontext.VMContext, devices object.VirtualDeviceList, configSpec *types.VirtualMachineConfigSpec) error {
+	security := vmCtx.VSphereVM.Spec.Security
+	if security == nil {
+		return nil
+	}
+
+	if security.Firmware != "" {
+		configSpec.Firmware = string(security.Firmware)
+	}
+	if security.SecureBoot {
+		configSpec.BootOptions = &types.VirtualMachineBootOptions{EfiSecureBootEnabled: pointer.Bool(true)}
+	}
+
+	if !security.VTPM || len(devices.SelectByType((*types.VirtualTPM)(nil))) > 0 {
+		return nil
+	}
+	if err := checkKeyProvider(ctx, vmCtx, security.KeyProvider); err != nil {
+		conditions.MarkFalse(vmCtx.VSphereVM, infrav1.SecurityConfiguredCondition, infrav1.KeyProviderNotFoundReason, clusterv1.ConditionSeverityError, err.Error())
+		return errors.Wrapf(err, "unable to add virtual TPM for %q", ctx)
+	}
+	if security.KeyProvider != "" {
+		configSpec.Crypto = &types.CryptoSpecEncrypt{
+			CryptoKeyId: types.CryptoKeyId{ProviderId: &types.KeyProviderId{Id: security.KeyProvider}},
+		}
+	}
+	configSpec.DeviceChange = append(configSpec.DeviceChange, &types.VirtualDeviceConfigSpec{
+		Operation: types.VirtualDeviceConfigSpecOperationAdd,
+		Device:    &types.VirtualTPM{VirtualDevice: types.VirtualDevice{Key: vtpmDeviceKey}},
+	})
+	return nil
+}
+
+// checkKeyProvider returns an error if the key provider with the given name,
+// or the default key provider if the name is empty, is not configured in
+// vCenter.
+func checkKeyProvider(ctx context.Context, vmCtx *capvcontext.VMContext, name string) error {
+	client := vmCtx.Session.Client.Client
+	if client.ServiceContent.CryptoManager == nil {
+		return errors.New("vCenter does not support key providers")
+	}
+
+	res, err := methods.ListKmsClusters(ctx, client, &types.ListKmsClusters{This: *client.ServiceContent.CryptoManager})
+	if err != nil {
+		return errors.Wrap(err, "unable to list key providers")
+	}
+	for _, provider := range res.Returnval {
+		if (name == "" && provider.UseAsDefault) || (name != "" && provider.ClusterId.Id == name) {
+			return nil
+		}
+	}
+
+	if name == "" {
+		return errors.New("no default key provider is configured in vCenter")
+	}
+	return errors.Errorf("key provider %q is not configured in vCenter", name)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+
+	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+)
+
+// recommendDatastore asks Storage DRS for the placement of the clone of the
+// template on the datastores of the configured datastore cluster.
+func recommendDatastore(ctx context.Context, vmCtx *capvcontext.VMContext, folder *object.Folder, pool *object.ResourcePool, tpl *object.VirtualMachine) (*types.StoragePlacementAction, error) {
+	storagePod, err := vmCtx.Session.Finder.DatastoreCluster(ctx, vmCtx.VSphereVM.Spec.DatastoreCluster)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get datastore cluster %s for %q", vmCtx.VSphereVM.Spec.DatastoreCluster, ctx)
+	}
+
+	storagePodRef := storagePod.Reference()
+	folderRef := folder.Reference()
+	tplRef := tpl.Reference()
+	placementSpec := types.StoragePlacementSpec{
+		Type:      string(types.StoragePlacementSpecPlacementTypeClone),
+		CloneName: vmCtx.VSphereVM.Name,
+		CloneSpec: &types.VirtualMachineCloneSpec{
+			Location: types.VirtualMachineRelocateSpec{
+				Folder: &folderRef,
+				Pool:   types.NewReference(pool.Reference()),
+			},
+		},
+		Folder: &folderRef,
+		Vm:     &tplRef,
+		PodSelectionSpec: types.StorageDrsPodSelectionSpec{
+			StoragePod: &storagePodRef,
+		},
+	}
+
+	vmCtx.Logger.V(4).Info("requesting storage drs recommendation", "datastore-cluster", vmCtx.VSphereVM.Spec.DatastoreCluster)
+	result, err := object.NewStorageResourceManager(vmCtx.Session.Client.Client).RecommendDatastores(ctx, placementSpec)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get storage drs recommendation for datastore cluster %s", vmCtx.VSphereVM.Spec.DatastoreCluster)
+	}
+
+	// Recommendations are sorted by their rating, so the first placement
+	// action with a destination is the best choice.
+	for _, recommendation := range result.Recommendations {
+		for _, action := range recommendation.Action {
+			if placement, ok := action.(*types.StoragePlacementAction); ok {
+				vmCtx.Logger.Info("storage drs recommended datastore", "datastore-cluster", vmCtx.VSphereVM.Spec.DatastoreCluster, "datastore", placement.Destination.Value, "reason", recommendation.Reason)
+				return placement, nil
+			}
+		}
+	}
+
+	var faults string
+	if result.DrsFault != nil {
+		for _, faultsByVM := range result.DrsFault.FaultsByVm {
+			for _, fault := range faultsByVM.GetClusterDrsFaultsFaultsByVm().Fault {
+				faults += " " + fault.LocalizedMessage
+			}
+		}
+	}
+	return nil, errors.Errorf("storage drs did not recommend a datastore of datastore cluster %s:%s", vmCtx.VSphereVM.Spec.DatastoreCluster, faults)
+}
+
+// applyStoragePlacement applies the placement recommended by Storage DRS to the
+// relocate spec of the clone. The virtual machine and its disks are placed on
+// the recommended datastore, unless Storage DRS recommended a datastore of its
+// own for a disk.
+func applyStoragePlacement(location *types.VirtualMachineRelocateSpec, placement *types.StoragePlacementAction) {
+	if placement == nil {
+		return
+	}
+	location.Datastore = types.NewReference(placement.Destination)
+	for i := range location.Disk {
+		location.Disk[i].Datastore = placement.Destination
+		for _, disk := range placement.RelocateSpec.Disk {
+			if disk.DiskId == location.Disk[i].DiskId {
+				location.Disk[i].Datastore = disk.Datastore
+			}
+		}
+	}
+}
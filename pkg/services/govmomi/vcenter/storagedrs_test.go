@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vcenter
+
+import (
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestApplyStoragePlacement(t *testing.T) {
+	ds := func(value string) types.ManagedObjectReference {
+		return types.ManagedObjectReference{Type: "Datastore", Value: value}
+	}
+
+	location := types.VirtualMachineRelocateSpec{
+		Datastore: types.NewReference(ds("default")),
+		Disk: []types.VirtualMachineRelocateSpecDiskLocator{
+			{DiskId: 2000, Datastore: ds("default")},
+			{DiskId: 2001, Datastore: ds("default")},
+		},
+	}
+	applyStoragePlacement(&location, &types.StoragePlacementAction{
+		Destination: ds("ds-1"),
+		RelocateSpec: types.VirtualMachineRelocateSpec{
+			Datastore: types.NewReference(ds("ds-1")),
+			Disk:      []types.VirtualMachineRelocateSpecDiskLocator{{DiskId: 2001, Datastore: ds("ds-2")}},
+		},
+	})
+
+	if *location.Datastore != ds("ds-1") {
+		t.Errorf("Expected the VM to be placed on ds-1, got %s", location.Datastore.Value)
+	}
+	if location.Disk[0].Datastore != ds("ds-1") {
+		t.Errorf("Expected the first disk to be placed on ds-1, got %s", location.Disk[0].Datastore.Value)
+	}
+	if location.Disk[1].Datastore != ds("ds-2") {
+		t.Errorf("Expected the second disk to be placed on ds-2, got %s", location.Disk[1].Datastore.Value)
+	}
+
+	// Without a recommendation the placement is not changed.
+	applyStoragePlacement(&location, nil)
+	if *location.Datastore != ds("ds-1") {
+		t.Errorf("Expected the VM to stay on ds-1, got %s", location.Datastore.Value)
+	}
+}
@@ -0,0 +1,303 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package watcher enqueues VSphereVMs when the vCenter objects they own
+// change. Each vCenter is watched by a single PropertyCollector, which
+// replaces waiting on each task and polling each virtual machine.
+package watcher
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/types"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	capvcontext "sigs.k8s.io/cluster-api-provider-vsphere/pkg/context"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
+)
+
+var (
+	// VMProperties are the properties of a virtual machine whose changes
+	// enqueue the owning VSphereVM.
+	VMProperties = []string{
+		"config.changeVersion",
+		"guest.net",
+		"runtime.host",
+		"runtime.powerState",
+	}
+
+	// TaskProperties are the properties of a task whose changes enqueue the
+	// owning VSphereVM. The VSphereVM is enqueued when the task completes, so
+	// that the fault of a failed task is classified without delay.
+	TaskProperties = []string{
+		"info.state",
+	}
+
+	// watchers are the watchers of each vCenter server and user.
+	watchers sync.Map
+
+	// maxWaitSeconds is the maximum duration of a WaitForUpdatesEx call.
+	maxWaitSeconds int32 = 60
+)
+
+// Watch enqueues the VSphereVM of the context when the virtual machine or
+// task with the given reference changes. The vCenter of the VSphereVM is
+// watched with the session of the context until ctx is done or the session
+// becomes invalid, and watched again with the session of the next call. The
+// objects visible to a user depend on its privileges, so each user of a
+// vCenter has its own watcher.
+func Watch(ctx context.Context, vmCtx *capvcontext.VMContext, ref types.ManagedObjectReference) error {
+	return getOrCreate(vmCtx).watch(ctx, vmCtx.Session, ref, vmCtx.VSphereVM.DeepCopy())
+}
+
+// Unwatch stops watching the objects owned by the VSphereVM of the context.
+// The objects are owned by a single watcher, whose user may differ from the
+// user of the session of the context, e.g. after the credentials of the
+// VSphereCluster changed.
+func Unwatch(ctx context.Context, vmCtx *capvcontext.VMContext) error {
+	var errs []error
+	watchers.Range(func(_, val interface{}) bool {
+		if err := val.(*watcher).unwatch(ctx, vmCtx.VSphereVM); err != nil {
+			errs = append(errs, err)
+		}
+		return true
+	})
+	return kerrors.NewAggregate(errs)
+}
+
+func getOrCreate(vmCtx *capvcontext.VMContext) *watcher {
+	server := vmCtx.VSphereVM.Spec.Server
+	user := ""
+	if u := vmCtx.Session.Client.URL().User; u != nil {
+		user = u.Username()
+	}
+	key := server + "#" + user
+	if val, ok := watchers.Load(key); ok {
+		return val.(*watcher)
+	}
+	val, _ := watchers.LoadOrStore(key, &watcher{
+		events: vmCtx.GetGenericEventChannelFor(infrav1.GroupVersion.WithKind("VSphereVM")),
+		logger: vmCtx.ControllerContext.Logger.WithName("watcher").WithValues("server", server, "user", user),
+		owners: map[types.ManagedObjectReference]*infrav1.VSphereVM{},
+	})
+	return val.(*watcher)
+}
+
+// watcher watches the objects of a vCenter with a PropertyCollector filter
+// on a ListView of the objects.
+type watcher struct {
+	events chan event.GenericEvent
+	logger logr.Logger
+
+	mu     sync.Mutex
+	owners map[types.ManagedObjectReference]*infrav1.VSphereVM
+	// listView is the ListView of the watched objects, nil when the vCenter
+	// is not watched.
+	listView *view.ListView
+}
+
+func (w *watcher) watch(ctx context.Context, s *session.Session, ref types.ManagedObjectReference, owner *infrav1.VSphereVM) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, watched := w.owners[ref]
+	// The owner is replaced to enqueue its latest version.
+	w.owners[ref] = owner
+	if w.listView == nil {
+		return w.start(ctx, s.Client.Client)
+	}
+	if watched {
+		return nil
+	}
+	unresolved, err := w.listView.Add(ctx, []types.ManagedObjectReference{ref})
+	if err != nil {
+		delete(w.owners, ref)
+		return errors.Wrapf(err, "unable to watch %s", ref)
+	}
+	// The object no longer exists or is not visible to the user of the
+	// watcher.
+	if len(unresolved) > 0 {
+		delete(w.owners, ref)
+		return errors.Errorf("unable to watch %s: object not found", ref)
+	}
+	return nil
+}
+
+func (w *watcher) unwatch(ctx context.Context, owner *infrav1.VSphereVM) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var refs []types.ManagedObjectReference
+	for ref, o := range w.owners {
+		if o.Namespace == owner.Namespace && o.Name == owner.Name {
+			refs = append(refs, ref)
+			delete(w.owners, ref)
+		}
+	}
+	if len(refs) == 0 || w.listView == nil {
+		return nil
+	}
+	_, err := w.listView.Remove(ctx, refs)
+	return errors.Wrapf(err, "unable to stop watching objects of %s/%s", owner.Namespace, owner.Name)
+}
+
+// start creates the ListView of the watched objects and waits for their
+// updates in the background. It must be called with the lock held.
+func (w *watcher) start(ctx context.Context, c *vim25.Client) error {
+	refs := make([]types.ManagedObjectReference, 0, len(w.owners))
+	for ref := range w.owners {
+		refs = append(refs, ref)
+	}
+	listView, err := view.NewManager(c).CreateListView(ctx, refs)
+	if err != nil {
+		return errors.Wrap(err, "unable to create list view")
+	}
+	w.listView = listView
+	w.logger.Info("watching vCenter objects", "count", len(refs))
+
+	// The updates are not part of the reconcile which started the watch.
+	go w.run(tracing.Detach(ctx), c, listView)
+	return nil
+}
+
+// run waits for the updates of the objects of the ListView until ctx is done
+// or the session becomes invalid.
+func (w *watcher) run(ctx context.Context, c *vim25.Client, listView *view.ListView) {
+	err := w.waitForUpdates(ctx, c, listView)
+	if err != nil {
+		w.logger.Error(err, "stopped watching vCenter objects")
+	} else {
+		w.logger.Info("stopped watching vCenter objects")
+	}
+
+	w.mu.Lock()
+	if w.listView == listView {
+		w.listView = nil
+	}
+	w.mu.Unlock()
+	// Use the background context, as ctx may be done.
+	_ = listView.Destroy(context.Background())
+}
+
+func (w *watcher) waitForUpdates(ctx context.Context, c *vim25.Client, listView *view.ListView) error {
+	// A new property collector is required, as filters can only be removed
+	// by destroying their collector.
+	pc, err := property.DefaultCollector(c).Create(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to create property collector")
+	}
+	defer func() {
+		_ = pc.Destroy(context.Background())
+	}()
+
+	filter := new(property.WaitFilter)
+	filter.Add(listView.Reference(), "VirtualMachine", VMProperties, &types.TraversalSpec{
+		Type: "ListView",
+		Path: "view",
+	})
+	filter.Spec.ObjectSet[0].Skip = types.NewBool(true)
+	filter.Spec.PropSet = append(filter.Spec.PropSet, types.PropertySpec{
+		Type:    "Task",
+		PathSet: TaskProperties,
+	})
+	if err := pc.CreateFilter(ctx, filter.CreateFilter); err != nil {
+		return errors.Wrap(err, "unable to create property filter")
+	}
+
+	// The wait is bounded so that it ends shortly after ctx is done, instead
+	// of being canceled with CancelWaitForUpdates.
+	opts := &types.WaitOptions{MaxWaitSeconds: &maxWaitSeconds}
+	version := ""
+	for ctx.Err() == nil {
+		set, err := pc.WaitForUpdates(ctx, version, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "unable to wait for updates")
+		}
+		// MaxWaitSeconds was exceeded without updates.
+		if set == nil {
+			continue
+		}
+		version = set.Version
+		for _, filterSet := range set.FilterSet {
+			for _, update := range filterSet.ObjectSet {
+				w.dispatch(ctx, update)
+			}
+		}
+	}
+	return nil
+}
+
+// dispatch enqueues the owner of the object of the update if needed.
+func (w *watcher) dispatch(ctx context.Context, update types.ObjectUpdate) {
+	ref := update.Obj
+	w.mu.Lock()
+	owner, ok := w.owners[ref]
+	w.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	enqueue, done := false, false
+	switch ref.Type {
+	case "Task":
+		for _, change := range update.ChangeSet {
+			if change.Name != "info.state" {
+				continue
+			}
+			switch change.Val {
+			case types.TaskInfoStateSuccess, types.TaskInfoStateError:
+				enqueue, done = true, true
+			}
+		}
+	default:
+		// The initial values of a virtual machine are received when it is
+		// added to the ListView, after the VSphereVM was reconciled.
+		enqueue = update.Kind != types.ObjectUpdateKindEnter
+		done = update.Kind == types.ObjectUpdateKindLeave
+	}
+
+	if done {
+		w.mu.Lock()
+		delete(w.owners, ref)
+		listView := w.listView
+		w.mu.Unlock()
+		if listView != nil && update.Kind != types.ObjectUpdateKindLeave {
+			if _, err := listView.Remove(ctx, []types.ManagedObjectReference{ref}); err != nil {
+				w.logger.Error(err, "unable to stop watching object", "ref", ref)
+			}
+		}
+	}
+	if enqueue {
+		w.logger.V(4).Info("triggering GenericEvent", "ref", ref, "kind", update.Kind,
+			"VSphereVM", owner.Namespace+"/"+owner.Name)
+		select {
+		case w.events <- event.GenericEvent{Object: owner}:
+		case <-ctx.Done():
+		}
+	}
+}
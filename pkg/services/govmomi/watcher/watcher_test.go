@@ -0,0 +1,131 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watcher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/context/fake"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/session"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestWatch(t *testing.T) {
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("unable to create simulator: %s", err)
+	}
+	defer simr.Destroy()
+
+	// The watcher is stopped before the simulator, which waits for the
+	// pending WaitForUpdatesEx call.
+	defer func(seconds int32) { maxWaitSeconds = seconds }(maxWaitSeconds)
+	maxWaitSeconds = 1
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	vmCtx := fake.NewVMContext(ctx, fake.NewControllerContext(fake.NewControllerManagerContext()))
+	vmCtx.VSphereVM.Spec.Server = simr.ServerURL().Host
+
+	vmCtx.Session, err = session.GetOrCreate(ctx,
+		session.NewParams().
+			WithServer(vmCtx.VSphereVM.Spec.Server).
+			WithUserInfo(simr.Username(), simr.Password()).
+			WithDatacenter("*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := vmCtx.GetGenericEventChannelFor(infrav1.GroupVersion.WithKind("VSphereVM"))
+
+	simVM := simulator.Map.Any("VirtualMachine").(*simulator.VirtualMachine)
+	vm := object.NewVirtualMachine(vmCtx.Session.Client.Client, simVM.Reference())
+
+	if err := Watch(ctx, vmCtx, vm.Reference()); err != nil {
+		t.Fatalf("unable to watch VM: %s", err)
+	}
+	// The initial values of the VM do not trigger an event.
+	expectNoEvent(t, events)
+
+	// Objects which are not visible to the user of the watcher are reported.
+	if err := Watch(ctx, vmCtx, types.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-unknown"}); err == nil {
+		t.Fatal("Expected an error watching an unknown VM")
+	}
+
+	task, err := vm.PowerOff(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Watch(ctx, vmCtx, task.Reference()); err != nil {
+		t.Fatalf("unable to watch task: %s", err)
+	}
+	expectEvent(t, events, vmCtx.VSphereVM.Name)
+	if err := task.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// The power state and the task trigger an event each.
+	drainEvents(events)
+
+	if err := Unwatch(ctx, vmCtx); err != nil {
+		t.Fatalf("unable to stop watching: %s", err)
+	}
+	task, err = vm.PowerOn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := task.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expectNoEvent(t, events)
+}
+
+func expectEvent(t *testing.T, events <-chan event.GenericEvent, name string) {
+	t.Helper()
+	select {
+	case e := <-events:
+		if e.Object.GetName() != name {
+			t.Errorf("Expected an event for %s, got an event for %s", name, e.Object.GetName())
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Expected an event for %s", name)
+	}
+}
+
+func expectNoEvent(t *testing.T, events <-chan event.GenericEvent) {
+	t.Helper()
+	select {
+	case e := <-events:
+		t.Fatalf("Expected no event, got an event for %s", e.Object.GetName())
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func drainEvents(events <-chan event.GenericEvent) {
+	for {
+		select {
+		case <-events:
+		case <-time.After(500 * time.Millisecond):
+			return
+		}
+	}
+}
@@ -44,7 +44,7 @@ type VSphereMachineService interface {
 // machines on vSphere.
 type VirtualMachineService interface {
 	// ReconcileVM reconciles a VM with the intended state.
-	ReconcileVM(ctx context.Context, vmCtx *capvcontext.VMContext) (infrav1.VirtualMachine, error)
+	ReconcileVM(ctx context.Context, vmCtx *capvcontext.VMContext) (reconcile.Result, infrav1.VirtualMachine, error)
 
 	// DestroyVM powers off and removes a VM from the inventory.
 	DestroyVM(ctx context.Context, vmCtx *capvcontext.VMContext) (reconcile.Result, infrav1.VirtualMachine, error)
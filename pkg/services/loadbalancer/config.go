@@ -0,0 +1,274 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package loadbalancer renders the configuration of the HAProxy and keepalived
+// load balancer VMs of the control plane endpoint of a VSphereCluster.
+package loadbalancer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"net/netip"
+	"sort"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+const (
+	// HAProxyConfigGuestInfoKey is the guestinfo variable holding the base64
+	// encoded HAProxy configuration of the load balancer VMs.
+	HAProxyConfigGuestInfoKey = "haproxy.cfg"
+
+	// DefaultReplicas is the default number of load balancer VMs.
+	DefaultReplicas = 2
+
+	// DefaultInterface is the default network interface of the load balancer
+	// VMs the control plane endpoint host is assigned to.
+	DefaultInterface = "eth0"
+
+	// VRRPPasswordSecretKey is the key of the bootstrap data secret of the
+	// load balancer VMs holding the VRRP password, which is generated once
+	// and kept for the lifetime of the load balancer.
+	VRRPPasswordSecretKey = "vrrpPassword"
+
+	vrrpPasswordLength = 8
+	vrrpPasswordChars  = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// Backend is a control plane machine the load balancer forwards to.
+type Backend struct {
+	Name    string
+	Address string
+	Port    int32
+}
+
+// VMName returns the name of the load balancer VSphereVM with the given
+// index of a VSphereCluster.
+func VMName(clusterName string, index int) string {
+	return fmt.Sprintf("%s-lb-%d", clusterName, index)
+}
+
+// BootstrapSecretName returns the name of the secret holding the bootstrap
+// data of the load balancer VMs of a VSphereCluster.
+func BootstrapSecretName(clusterName string) string {
+	return fmt.Sprintf("%s-lb", clusterName)
+}
+
+// Replicas returns the number of load balancer VMs.
+func Replicas(spec *infrav1.VSphereLoadBalancerSpec) int {
+	if spec.Replicas == nil {
+		return DefaultReplicas
+	}
+	return int(*spec.Replicas)
+}
+
+// VirtualRouterID returns the VRRP virtual router ID of the load balancer of
+// a VSphereCluster, derived from its UID if not set.
+func VirtualRouterID(spec *infrav1.VSphereLoadBalancerSpec, clusterUID string) int32 {
+	if spec.VirtualRouterID != nil {
+		return *spec.VirtualRouterID
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(clusterUID))
+	return int32(h.Sum32()%255) + 1
+}
+
+var haproxyConfigTemplate = template.Must(template.New("haproxy.cfg").Parse(`global
+  log stdout format raw local0
+  maxconn 4096
+
+defaults
+  mode tcp
+  log global
+  option tcplog
+  option dontlognull
+  timeout connect 10s
+  timeout client 1h
+  timeout server 1h
+
+frontend kube-apiserver
+  bind {{ .Bind }}
+  default_backend kube-apiserver
+
+backend kube-apiserver
+  option httpchk GET /healthz
+  http-check expect status 200
+  default-server inter 5s fall 3 rise 2 check check-ssl verify none
+{{- range .Backends }}
+  server {{ .Name }} {{ .Address }}:{{ .Port }}
+{{- end }}
+`))
+
+// HAProxyConfig returns the HAProxy configuration forwarding connections to
+// the port of the control plane endpoint to the backends.
+func HAProxyConfig(endpoint netip.Addr, port int32, backends []Backend) ([]byte, error) {
+	backends = append([]Backend(nil), backends...)
+	sort.Slice(backends, func(i, j int) bool { return backends[i].Name < backends[j].Name })
+
+	bind := fmt.Sprintf("*:%d", port)
+	if endpoint.Is6() {
+		bind = fmt.Sprintf(":::%d v6only", port)
+	}
+	buf := &bytes.Buffer{}
+	if err := haproxyConfigTemplate.Execute(buf, struct {
+		Bind     string
+		Backends []Backend
+	}{bind, backends}); err != nil {
+		return nil, errors.Wrap(err, "failed to render HAProxy configuration")
+	}
+	return buf.Bytes(), nil
+}
+
+var keepalivedConfigTemplate = template.Must(template.New("keepalived.conf").Parse(`global_defs {
+  enable_script_security
+  script_user root
+}
+
+vrrp_script chk_haproxy {
+  script "/usr/bin/env pidof haproxy"
+  interval 2
+  fall 2
+  rise 2
+}
+
+vrrp_instance kube_apiserver {
+  state BACKUP
+  interface {{ .Interface }}
+  virtual_router_id {{ .VirtualRouterID }}
+  priority 100
+  advert_int 1
+  nopreempt
+{{- if .Password }}
+  authentication {
+    auth_type PASS
+    auth_pass {{ .Password }}
+  }
+{{- end }}
+  virtual_ipaddress {
+    {{ .Address }}
+  }
+  track_script {
+    chk_haproxy
+  }
+}
+`))
+
+// KeepalivedConfig returns the keepalived configuration assigning the host of
+// the control plane endpoint to one of the load balancer VMs running HAProxy.
+// VRRP authentication is only supported with IPv4.
+func KeepalivedConfig(endpoint netip.Addr, iface string, virtualRouterID int32, password string) ([]byte, error) {
+	if endpoint.Is6() {
+		password = ""
+	}
+	buf := &bytes.Buffer{}
+	if err := keepalivedConfigTemplate.Execute(buf, struct {
+		Interface       string
+		VirtualRouterID int32
+		Password        string
+		Address         string
+	}{iface, virtualRouterID, password, netip.PrefixFrom(endpoint, endpoint.BitLen()).String()}); err != nil {
+		return nil, errors.Wrap(err, "failed to render keepalived configuration")
+	}
+	return buf.Bytes(), nil
+}
+
+// haproxyConfigScript applies the HAProxy configuration passed in the guestinfo
+// of the VM, which is updated when the control plane machines change.
+const haproxyConfigScript = `#!/bin/sh
+set -eu
+config=$(mktemp)
+trap 'rm -f "$config"' EXIT
+vmware-rpctool "info-get guestinfo.` + HAProxyConfigGuestInfoKey + `" | base64 -d > "$config"
+if cmp -s "$config" /etc/haproxy/haproxy.cfg; then
+  exit 0
+fi
+haproxy -c -f "$config"
+install -m 0644 "$config" /etc/haproxy/haproxy.cfg
+systemctl reload-or-restart haproxy
+`
+
+const haproxyConfigService = `[Unit]
+Description=Apply the HAProxy configuration passed in the guestinfo
+After=vmtoolsd.service
+
+[Service]
+Type=oneshot
+ExecStart=/usr/local/bin/capv-haproxy-config
+`
+
+const haproxyConfigTimer = `[Unit]
+Description=Apply the HAProxy configuration passed in the guestinfo periodically
+
+[Timer]
+OnBootSec=5s
+OnUnitActiveSec=10s
+
+[Install]
+WantedBy=timers.target
+`
+
+type cloudConfigFile struct {
+	Path        string `json:"path"`
+	Permissions string `json:"permissions"`
+	Content     string `json:"content"`
+}
+
+// BootstrapData returns the cloud-config of the load balancer VMs, which
+// configures keepalived and periodically applies the HAProxy configuration
+// passed in the guestinfo.
+func BootstrapData(keepalivedConfig []byte) ([]byte, error) {
+	cloudConfig := struct {
+		WriteFiles []cloudConfigFile `json:"write_files"`
+		RunCmd     []string          `json:"runcmd"`
+	}{
+		WriteFiles: []cloudConfigFile{
+			{Path: "/etc/keepalived/keepalived.conf", Permissions: "0600", Content: string(keepalivedConfig)},
+			{Path: "/usr/local/bin/capv-haproxy-config", Permissions: "0755", Content: haproxyConfigScript},
+			{Path: "/etc/systemd/system/capv-haproxy-config.service", Permissions: "0644", Content: haproxyConfigService},
+			{Path: "/etc/systemd/system/capv-haproxy-config.timer", Permissions: "0644", Content: haproxyConfigTimer},
+		},
+		RunCmd: []string{
+			"systemctl daemon-reload",
+			"systemctl enable --now haproxy keepalived capv-haproxy-config.timer",
+		},
+	}
+	data, err := yaml.Marshal(cloudConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal load balancer cloud-config")
+	}
+	return append([]byte("#cloud-config\n"), data...), nil
+}
+
+// GenerateVRRPPassword returns a random VRRP password for the load balancer
+// of a VSphereCluster, which is limited to 8 characters by keepalived.
+func GenerateVRRPPassword() (string, error) {
+	password := make([]byte, vrrpPasswordLength)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(vrrpPasswordChars))))
+		if err != nil {
+			return "", errors.Wrap(err, "failed to generate VRRP password")
+		}
+		password[i] = vrrpPasswordChars[n.Int64()]
+	}
+	return string(password), nil
+}
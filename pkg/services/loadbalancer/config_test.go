@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loadbalancer
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/yaml"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+func TestHAProxyConfig(t *testing.T) {
+	backends := []Backend{
+		{Name: "cp-2", Address: "10.0.0.12", Port: 6443},
+		{Name: "cp-1", Address: "10.0.0.11", Port: 6443},
+	}
+
+	t.Run("with an IPv4 endpoint", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		config, err := HAProxyConfig(netip.MustParseAddr("10.0.0.10"), 6443, backends)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(string(config)).To(gomega.ContainSubstring("  bind *:6443\n"))
+		g.Expect(string(config)).To(gomega.HaveSuffix(`
+  server cp-1 10.0.0.11:6443
+  server cp-2 10.0.0.12:6443
+`))
+		g.Expect(backends[0].Name).To(gomega.Equal("cp-2"))
+	})
+
+	t.Run("with an IPv6 endpoint", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		config, err := HAProxyConfig(netip.MustParseAddr("fd00::10"), 443, []Backend{{Name: "cp-1", Address: "fd00::11", Port: 6443}})
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(string(config)).To(gomega.ContainSubstring("  bind :::443 v6only\n"))
+		g.Expect(string(config)).To(gomega.ContainSubstring("  server cp-1 fd00::11:6443\n"))
+	})
+
+	t.Run("without backends", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+
+		config, err := HAProxyConfig(netip.MustParseAddr("10.0.0.10"), 6443, nil)
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(string(config)).ToNot(gomega.ContainSubstring("\n  server "))
+	})
+}
+
+func TestKeepalivedConfig(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	config, err := KeepalivedConfig(netip.MustParseAddr("10.0.0.10"), "eth0", 42, "secret")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(string(config)).To(gomega.ContainSubstring("  interface eth0\n"))
+	g.Expect(string(config)).To(gomega.ContainSubstring("  virtual_router_id 42\n"))
+	g.Expect(string(config)).To(gomega.ContainSubstring("    auth_pass secret\n"))
+	g.Expect(string(config)).To(gomega.ContainSubstring("    10.0.0.10/32\n"))
+
+	config, err = KeepalivedConfig(netip.MustParseAddr("fd00::10"), "ens192", 42, "secret")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(string(config)).ToNot(gomega.ContainSubstring("authentication"))
+	g.Expect(string(config)).To(gomega.ContainSubstring("    fd00::10/128\n"))
+}
+
+func TestBootstrapData(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	data, err := BootstrapData([]byte("global_defs {}\n"))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(string(data)).To(gomega.HavePrefix("#cloud-config\n"))
+
+	var cloudConfig struct {
+		WriteFiles []cloudConfigFile `json:"write_files"`
+		RunCmd     []string          `json:"runcmd"`
+	}
+	g.Expect(yaml.Unmarshal(data, &cloudConfig)).To(gomega.Succeed())
+	g.Expect(cloudConfig.WriteFiles).To(gomega.ContainElement(cloudConfigFile{
+		Path:        "/etc/keepalived/keepalived.conf",
+		Permissions: "0600",
+		Content:     "global_defs {}\n",
+	}))
+	g.Expect(cloudConfig.RunCmd).ToNot(gomega.BeEmpty())
+}
+
+func TestVirtualRouterID(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	spec := &infrav1.VSphereLoadBalancerSpec{}
+	id := VirtualRouterID(spec, "7c7e2a6e-0bd4-4c5b-9c8f-1a2b3c4d5e6f")
+	g.Expect(id).To(gomega.BeNumerically(">=", 1))
+	g.Expect(id).To(gomega.BeNumerically("<=", 255))
+	g.Expect(VirtualRouterID(spec, "7c7e2a6e-0bd4-4c5b-9c8f-1a2b3c4d5e6f")).To(gomega.Equal(id))
+
+	spec.VirtualRouterID = pointer.Int32(7)
+	g.Expect(VirtualRouterID(spec, "7c7e2a6e-0bd4-4c5b-9c8f-1a2b3c4d5e6f")).To(gomega.Equal(int32(7)))
+}
+
+func TestGenerateVRRPPassword(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	password, err := GenerateVRRPPassword()
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(password).To(gomega.MatchRegexp("^[a-zA-Z0-9]{8}$"))
+
+	other, err := GenerateVRRPPassword()
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(other).ToNot(gomega.Equal(password))
+}
@@ -256,6 +256,14 @@ func (v *VimMachineService) reconcileNetwork(ctx context.Context, vimMachineCtx
 			}
 		}
 	}
+	// The status of the bonds and VLANs is appended to the one of the network
+	// devices once the MAC address of their device is known.
+	for _, networkStatus := range vm.Status.NetworkInterfaces {
+		if networkStatus.MACAddr == "" {
+			continue
+		}
+		networkStatusList = append(networkStatusList, networkStatus)
+	}
 	vimMachineCtx.VSphereMachine.Status.Network = networkStatusList
 
 	addresses := vm.Status.Addresses
@@ -341,11 +349,19 @@ func (v *VimMachineService) createOrPatchVSphereVM(ctx context.Context, vimMachi
 		if vm.Spec.Thumbprint == "" {
 			vm.Spec.Thumbprint = vimMachineCtx.VSphereCluster.Spec.Thumbprint
 		}
+		if vm.Spec.DatastoreSelectionPolicy == "" {
+			vm.Spec.DatastoreSelectionPolicy = vimMachineCtx.VSphereCluster.Spec.DatastoreSelectionPolicy
+		}
+		if vm.Spec.MetadataTemplate == nil && vimMachineCtx.VSphereCluster.Spec.MetadataTemplate != nil {
+			vm.Spec.MetadataTemplate = vimMachineCtx.VSphereCluster.Spec.MetadataTemplate.DeepCopy()
+		}
 		if vsphereVM != nil {
 			vm.Spec.BiosUUID = vsphereVM.Spec.BiosUUID
 		}
 		vm.Spec.PowerOffMode = vimMachineCtx.VSphereMachine.Spec.PowerOffMode
 		vm.Spec.GuestSoftPowerOffTimeout = vimMachineCtx.VSphereMachine.Spec.GuestSoftPowerOffTimeout
+		vm.Spec.ResizePolicy = vimMachineCtx.VSphereMachine.Spec.ResizePolicy
+		vm.Spec.DriftPolicy = vimMachineCtx.VSphereMachine.Spec.DriftPolicy
 		return nil
 	}
 
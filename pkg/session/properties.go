@@ -0,0 +1,321 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/metrics"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
+)
+
+// VirtualMachineProperties are the properties of the virtual machines
+// retrieved by RetrieveVirtualMachine.
+var VirtualMachineProperties = []string{
+	"config.cpuAllocation",
+	"config.cpuHotAddEnabled",
+	"config.extraConfig",
+	"config.files.vmPathName",
+	"config.hardware",
+	"config.hotPlugMemoryLimit",
+	"config.instanceUuid",
+	"config.memoryAllocation",
+	"config.memoryHotAddEnabled",
+	"config.memoryReservationLockedToMax",
+	"config.uuid",
+	"config.version",
+	"guest.net",
+	"parent",
+	"resourcePool",
+	"runtime.host",
+	"runtime.powerState",
+}
+
+const (
+	// propertyBatchWindow is the time during which the retrievals of
+	// virtual machines are gathered into a single request.
+	propertyBatchWindow = 50 * time.Millisecond
+
+	// maxPropertyBatchSize is the maximum number of virtual machines whose
+	// properties are retrieved with a single request.
+	maxPropertyBatchSize = 500
+
+	// propertyBatchTimeout is the timeout of a request.
+	propertyBatchTimeout = time.Minute
+)
+
+// VirtualMachine is a snapshot of the VirtualMachineProperties of a virtual
+// machine.
+type VirtualMachine struct {
+	mo.VirtualMachine
+
+	// HostName is the name of the host of the virtual machine.
+	HostName string
+}
+
+// RetrieveVirtualMachine retrieves the VirtualMachineProperties of the
+// virtual machine with the given reference, or returns nil if it does not
+// exist. Concurrent retrievals are batched into a single RetrieveProperties
+// request, which also retrieves the names of the hosts.
+func (s *Session) RetrieveVirtualMachine(ctx context.Context, ref types.ManagedObjectReference) (*VirtualMachine, error) {
+	if s.properties == nil {
+		vms, errs := retrieveVirtualMachines(ctx, s.Client.Client, []types.ManagedObjectReference{ref})
+		return vms[ref], errs[ref]
+	}
+	return s.properties.retrieve(ctx, ref)
+}
+
+// FindVirtualMachineByBIOSUUID finds a virtual machine by its BIOS UUID and
+// retrieves its VirtualMachineProperties, or returns nil if it does not
+// exist. The reference of the virtual machine is cached, so that it is not
+// searched again while it exists.
+func (s *Session) FindVirtualMachineByBIOSUUID(ctx context.Context, uuid string) (*VirtualMachine, error) {
+	if s.properties != nil {
+		if ref, ok := s.properties.refs.Load(uuid); ok {
+			vm, err := s.RetrieveVirtualMachine(ctx, ref.(types.ManagedObjectReference))
+			if err != nil {
+				return nil, err
+			}
+			if vm != nil && vm.Config != nil && vm.Config.Uuid == uuid {
+				return vm, nil
+			}
+			s.properties.refs.Delete(uuid)
+		}
+	}
+
+	ref, err := s.FindByBIOSUUID(ctx, uuid)
+	if err != nil || ref == nil {
+		return nil, err
+	}
+	vm, err := s.RetrieveVirtualMachine(ctx, ref.Reference())
+	if err != nil || vm == nil {
+		return nil, err
+	}
+	if s.properties != nil {
+		s.properties.refs.Store(uuid, ref.Reference())
+	}
+	return vm, nil
+}
+
+// propertyBatcher gathers the retrievals of virtual machines during
+// propertyBatchWindow into batches.
+type propertyBatcher struct {
+	client  *vim25.Client
+	vcenter string
+
+	mu      sync.Mutex
+	pending *propertyBatch
+
+	// refs are the references of the virtual machines found by BIOS UUID.
+	refs sync.Map
+}
+
+type propertyBatch struct {
+	once sync.Once
+	done chan struct{}
+	refs []types.ManagedObjectReference
+
+	vms  map[types.ManagedObjectReference]*VirtualMachine
+	errs map[types.ManagedObjectReference]error
+}
+
+func newPropertyBatcher(client *vim25.Client, vcenter string) *propertyBatcher {
+	return &propertyBatcher{client: client, vcenter: vcenter}
+}
+
+func (b *propertyBatcher) retrieve(ctx context.Context, ref types.ManagedObjectReference) (_ *VirtualMachine, reterr error) {
+	// The request of the batch is not traced, as it is not sent in the
+	// context of a single reconcile, so the wait for the batch is traced
+	// instead.
+	ctx, span := tracing.Start(ctx, "RetrieveVirtualMachine")
+	defer func() { tracing.End(span, reterr) }()
+
+	b.mu.Lock()
+	batch := b.pending
+	if batch == nil {
+		batch = &propertyBatch{done: make(chan struct{})}
+		b.pending = batch
+		time.AfterFunc(propertyBatchWindow, func() { b.flush(batch) })
+	}
+	batch.refs = append(batch.refs, ref)
+	full := len(batch.refs) >= maxPropertyBatchSize
+	b.mu.Unlock()
+	if full {
+		go b.flush(batch)
+	}
+
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return batch.vms[ref], batch.errs[ref]
+}
+
+func (b *propertyBatcher) flush(batch *propertyBatch) {
+	batch.once.Do(func() {
+		b.mu.Lock()
+		if b.pending == batch {
+			b.pending = nil
+		}
+		refs := uniqueRefs(batch.refs)
+		b.mu.Unlock()
+
+		// The batch is not bound to the context of any of its callers.
+		ctx, cancel := context.WithTimeout(context.Background(), propertyBatchTimeout)
+		defer cancel()
+
+		metrics.PropertyBatchSize.WithLabelValues(b.vcenter).Observe(float64(len(refs)))
+		batch.vms, batch.errs = retrieveVirtualMachines(ctx, b.client, refs)
+		close(batch.done)
+	})
+}
+
+// retrieveVirtualMachines retrieves the VirtualMachineProperties of the
+// virtual machines. If the request fails because one of the virtual machines
+// was deleted, that virtual machine is dropped and the remaining ones are
+// retrieved again, once.
+func retrieveVirtualMachines(ctx context.Context, c *vim25.Client, refs []types.ManagedObjectReference) (map[types.ManagedObjectReference]*VirtualMachine, map[types.ManagedObjectReference]error) {
+	vms := map[types.ManagedObjectReference]*VirtualMachine{}
+	errs := map[types.ManagedObjectReference]error{}
+
+	contents, err := retrieveProperties(ctx, c, refs)
+	if deleted, ok := managedObjectNotFound(err); ok {
+		if refs = removeRef(refs, deleted); len(refs) == 0 {
+			return vms, errs
+		}
+		contents, err = retrieveProperties(ctx, c, refs)
+	}
+	if err != nil {
+		deleted, _ := managedObjectNotFound(err)
+		for _, ref := range refs {
+			if ref != deleted {
+				errs[ref] = errors.Wrapf(err, "unable to retrieve properties of %s", ref)
+			}
+		}
+		return vms, errs
+	}
+
+	hostNames := map[types.ManagedObjectReference]string{}
+	for _, content := range contents {
+		if content.Obj.Type == "HostSystem" {
+			for _, prop := range content.PropSet {
+				if name, ok := prop.Val.(string); ok && prop.Name == "name" {
+					hostNames[content.Obj] = name
+				}
+			}
+		}
+	}
+	for _, content := range contents {
+		if content.Obj.Type != "VirtualMachine" {
+			continue
+		}
+		obj, err := mo.ObjectContentToType(content)
+		if err != nil {
+			errs[content.Obj] = errors.Wrapf(err, "unable to retrieve properties of %s", content.Obj)
+			continue
+		}
+		vm := &VirtualMachine{VirtualMachine: obj.(mo.VirtualMachine)}
+		if host := vm.Runtime.Host; host != nil {
+			vm.HostName = hostNames[*host]
+		}
+		vms[content.Obj] = vm
+	}
+	return vms, errs
+}
+
+// retrieveProperties retrieves the VirtualMachineProperties of the virtual
+// machines and the names of their hosts with a single request.
+func retrieveProperties(ctx context.Context, c *vim25.Client, refs []types.ManagedObjectReference) ([]types.ObjectContent, error) {
+	objectSet := make([]types.ObjectSpec, 0, len(refs))
+	for _, ref := range refs {
+		objectSet = append(objectSet, types.ObjectSpec{
+			Obj: ref,
+			SelectSet: []types.BaseSelectionSpec{
+				&types.TraversalSpec{
+					Type: "VirtualMachine",
+					Path: "runtime.host",
+				},
+			},
+		})
+	}
+	req := types.RetrieveProperties{
+		SpecSet: []types.PropertyFilterSpec{{
+			ObjectSet: objectSet,
+			PropSet: []types.PropertySpec{
+				{Type: "VirtualMachine", PathSet: VirtualMachineProperties},
+				{Type: "HostSystem", PathSet: []string{"name"}},
+			},
+		}},
+	}
+	res, err := property.DefaultCollector(c).RetrieveProperties(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res.Returnval, nil
+}
+
+func uniqueRefs(refs []types.ManagedObjectReference) []types.ManagedObjectReference {
+	seen := map[types.ManagedObjectReference]bool{}
+	unique := make([]types.ManagedObjectReference, 0, len(refs))
+	for _, ref := range refs {
+		if !seen[ref] {
+			seen[ref] = true
+			unique = append(unique, ref)
+		}
+	}
+	return unique
+}
+
+// removeRef returns the references without the given one.
+func removeRef(refs []types.ManagedObjectReference, ref types.ManagedObjectReference) []types.ManagedObjectReference {
+	remaining := make([]types.ManagedObjectReference, 0, len(refs))
+	for _, r := range refs {
+		if r != ref {
+			remaining = append(remaining, r)
+		}
+	}
+	return remaining
+}
+
+// managedObjectNotFound returns the object that was not found if the error
+// is a ManagedObjectNotFound fault.
+func managedObjectNotFound(err error) (types.ManagedObjectReference, bool) {
+	if err == nil {
+		return types.ManagedObjectReference{}, false
+	}
+	if soap.IsSoapFault(err) {
+		fault, ok := soap.ToSoapFault(err).VimFault().(types.ManagedObjectNotFound)
+		return fault.Obj, ok
+	}
+	if soap.IsVimFault(err) {
+		fault, ok := soap.ToVimFault(err).(*types.ManagedObjectNotFound)
+		if ok {
+			return fault.Obj, true
+		}
+	}
+	return types.ManagedObjectReference{}, false
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package session
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/metrics"
+	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
+)
+
+func TestRetrieveVirtualMachine(t *testing.T) {
+	g := NewWithT(t)
+
+	simr, err := vcsim.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("failed to create VC simulator")
+	}
+	defer simr.Destroy()
+
+	ctx := context.Background()
+	s, err := GetOrCreate(ctx, NewParams().
+		WithServer(simr.ServerURL().Host).
+		WithUserInfo(simr.Username(), simr.Password()).WithDatacenter("*"))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	var refs []types.ManagedObjectReference
+	for _, obj := range simulator.Map.All("VirtualMachine") {
+		refs = append(refs, obj.Reference())
+	}
+	// The VM does not exist.
+	missing := types.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-missing"}
+	refs = append(refs, missing)
+
+	requests := retrievePropertiesCount(g, simr.ServerURL().Host)
+	var wg sync.WaitGroup
+	vms := make([]*VirtualMachine, len(refs))
+	errs := make([]error, len(refs))
+	for i := range refs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			vms[i], errs[i] = s.RetrieveVirtualMachine(ctx, refs[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, ref := range refs {
+		g.Expect(errs[i]).ToNot(HaveOccurred())
+		if ref == missing {
+			g.Expect(vms[i]).To(BeNil())
+			continue
+		}
+		g.Expect(vms[i]).ToNot(BeNil())
+		g.Expect(vms[i].Reference()).To(Equal(ref))
+		g.Expect(vms[i].Config).ToNot(BeNil())
+		g.Expect(vms[i].Config.Uuid).ToNot(BeEmpty())
+		g.Expect(vms[i].HostName).ToNot(BeEmpty())
+	}
+	// The VMs are retrieved with a single request, which fails because of the
+	// missing VM, and then again without the missing VM.
+	g.Expect(retrievePropertiesCount(g, simr.ServerURL().Host) - requests).To(BeNumerically("==", 2))
+
+	// The VM is found by BIOS UUID, and then retrieved by its cached reference.
+	vm, err := s.FindVirtualMachineByBIOSUUID(ctx, vms[0].Config.Uuid)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(vm).ToNot(BeNil())
+	g.Expect(vm.Reference()).To(Equal(refs[0]))
+	_, ok := s.properties.refs.Load(vms[0].Config.Uuid)
+	g.Expect(ok).To(BeTrue())
+
+	vm, err = s.FindVirtualMachineByBIOSUUID(ctx, "00000000-0000-0000-0000-000000000000")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(vm).To(BeNil())
+}
+
+func retrievePropertiesCount(g *WithT, vcenter string) float64 {
+	var m dto.Metric
+	g.Expect(metrics.VCenterRequestsTotal.WithLabelValues(vcenter, "RetrieveProperties").Write(&m)).To(Succeed())
+	return m.GetCounter().GetValue()
+}
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"net/netip"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -43,6 +44,8 @@ import (
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/constants"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/metrics"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/tracing"
 )
 
 var (
@@ -52,6 +55,10 @@ var (
 	// mutex to control access to the GetOrCreate function to avoid duplicate
 	// session creations on startup.
 	sessionMU sync.Mutex
+
+	// sessionKeys are the keys of the sessions created since startup, to
+	// count the sessions created to replace an invalid one.
+	sessionKeys sync.Map
 )
 
 // Session is a vSphere session with a configured Finder.
@@ -60,6 +67,9 @@ type Session struct {
 	Finder     *find.Finder
 	datacenter *object.Datacenter
 	TagManager *tags.Manager
+
+	// properties batches the property retrievals of virtual machines.
+	properties *propertyBatcher
 }
 
 // Feature is a set of Features of the session.
@@ -201,6 +211,7 @@ func GetOrCreate(ctx context.Context, params *Params) (*Session, error) {
 		return nil, errors.Wrap(err, "unable to create tags manager")
 	}
 	session.TagManager = manager
+	session.properties = newPropertyBatcher(client.Client, soapURL.Host)
 
 	// Assign the datacenter if one was specified.
 	if params.datacenter != "" {
@@ -212,7 +223,11 @@ func GetOrCreate(ctx context.Context, params *Params) (*Session, error) {
 		session.Finder.SetDatacenter(dc)
 	}
 	// Cache the session.
-	sessionCache.Store(sessionKey, &session)
+	storeSession(sessionKey, &session)
+	metrics.SessionLoginsTotal.WithLabelValues(params.server).Inc()
+	if _, relogin := sessionKeys.LoadOrStore(sessionKey, true); relogin {
+		metrics.SessionReloginsTotal.WithLabelValues(params.server).Inc()
+	}
 
 	logger.V(2).Info("cached vSphere client session", "server", params.server, "datacenter", params.datacenter)
 
@@ -231,6 +246,8 @@ func newClient(ctx context.Context, logger logr.Logger, sessionKey string, url *
 		return nil, err
 	}
 	vimClient.UserAgent = "k8s-capv-useragent"
+	vimClient.RoundTripper = metrics.NewRoundTripper(url.Host, vimClient.RoundTripper)
+	vimClient.RoundTripper = tracing.NewRoundTripper(url.Host, vimClient.RoundTripper)
 
 	c := &govmomi.Client{
 		Client:         vimClient,
@@ -238,12 +255,15 @@ func newClient(ctx context.Context, logger logr.Logger, sessionKey string, url *
 	}
 
 	if feature.EnableKeepAlive {
+		// The keep-alives are not part of the operation which created the session.
+		keepAliveCtx := tracing.Detach(ctx)
 		vimClient.RoundTripper = session.KeepAliveHandler(vimClient.RoundTripper, feature.KeepAliveDuration, func(tripper soap.RoundTripper) error {
-			_, err := methods.GetCurrentTime(ctx, tripper)
+			_, err := methods.GetCurrentTime(keepAliveCtx, tripper)
 			if err != nil {
 				logger.Error(err, "failed to keep alive govmomi client")
 				logger.Info("clearing the session")
-				sessionCache.Delete(sessionKey)
+				metrics.KeepAliveFailuresTotal.WithLabelValues(sessionServer(sessionKey), "soap").Inc()
+				deleteSession(sessionKey)
 			}
 			return err
 		})
@@ -256,13 +276,36 @@ func newClient(ctx context.Context, logger logr.Logger, sessionKey string, url *
 	return c, nil
 }
 
+// storeSession caches the session, replacing the session with the same key.
+func storeSession(sessionKey string, s *Session) {
+	if _, loaded := sessionCache.Swap(sessionKey, s); !loaded {
+		metrics.SessionsActive.WithLabelValues(sessionServer(sessionKey)).Inc()
+	}
+}
+
+// deleteSession removes the session with the given key from the cache.
+func deleteSession(sessionKey string) {
+	if _, loaded := sessionCache.LoadAndDelete(sessionKey); loaded {
+		metrics.SessionsActive.WithLabelValues(sessionServer(sessionKey)).Dec()
+	}
+}
+
+// sessionServer returns the server of a session key.
+func sessionServer(sessionKey string) string {
+	server, _, _ := strings.Cut(sessionKey, "#")
+	return server
+}
+
 // newManager creates a Manager that encompasses the REST Client for the VSphere tagging API.
 func newManager(ctx context.Context, logger logr.Logger, sessionKey string, client *vim25.Client, user *url.Userinfo, feature Feature) (*tags.Manager, error) {
 	rc := rest.NewClient(client)
+	rc.Transport = tracing.NewTransport(client.URL().Host, rc.Transport)
 	if feature.EnableKeepAlive {
+		keepAliveCtx := tracing.Detach(ctx)
 		rc.Transport = keepalive.NewHandlerREST(rc, feature.KeepAliveDuration, func() error {
-			s, err := rc.Session(ctx)
+			s, err := rc.Session(keepAliveCtx)
 			if err != nil {
+				metrics.KeepAliveFailuresTotal.WithLabelValues(sessionServer(sessionKey), "rest").Inc()
 				return err
 			}
 			if s != nil {
@@ -270,7 +313,8 @@ func newManager(ctx context.Context, logger logr.Logger, sessionKey string, clie
 			}
 
 			logger.Info("rest client session expired, clearing session")
-			sessionCache.Delete(sessionKey)
+			metrics.KeepAliveFailuresTotal.WithLabelValues(sessionServer(sessionKey), "rest").Inc()
+			deleteSession(sessionKey)
 			return errors.New("rest client session expired")
 		})
 	}
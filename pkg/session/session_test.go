@@ -26,11 +26,13 @@ import (
 
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/vmware/govmomi/simulator"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/metrics"
 	"sigs.k8s.io/cluster-api-provider-vsphere/test/helpers/vcsim"
 )
 
@@ -172,3 +174,27 @@ func TestGetSessionWithKeepAlive(t *testing.T) {
 	g.Expect(sessionInfo.Key).ToNot(BeEquivalentTo(firstSession))
 	assertSessionCountEqualTo(g, simr, 1)
 }
+
+func TestSessionCacheMetrics(t *testing.T) {
+	g := NewWithT(t)
+
+	server := "vcenter-metrics.test"
+	active := func() float64 {
+		var m dto.Metric
+		g.Expect(metrics.SessionsActive.WithLabelValues(server).Write(&m)).To(Succeed())
+		return m.GetGauge().GetValue()
+	}
+
+	key := server + "#dc0#user#0123"
+	storeSession(key, &Session{})
+	g.Expect(active()).To(BeEquivalentTo(1))
+	// Replacing the session does not change the number of sessions.
+	storeSession(key, &Session{})
+	g.Expect(active()).To(BeEquivalentTo(1))
+
+	deleteSession(key)
+	g.Expect(active()).To(BeEquivalentTo(0))
+	// Deleting a session twice, e.g. by both keepalives, only counts once.
+	deleteSession(key)
+	g.Expect(active()).To(BeEquivalentTo(0))
+}
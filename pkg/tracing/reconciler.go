@@ -0,0 +1,56 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var (
+	controllerKey = attribute.Key("capv.controller")
+	namespaceKey  = attribute.Key("k8s.namespace.name")
+	nameKey       = attribute.Key("capv.object.name")
+	requeueKey    = attribute.Key("capv.requeue")
+)
+
+// Reconciler returns a reconciler which starts a span for each reconcile of
+// the given controller. The spans of the requests sent to vCenter during the
+// reconcile are children of this span.
+func Reconciler(controllerName string, r reconcile.Reconciler) reconcile.Reconciler {
+	return &reconciler{controllerName: controllerName, r: r}
+}
+
+type reconciler struct {
+	controllerName string
+	r              reconcile.Reconciler
+}
+
+func (r *reconciler) Reconcile(ctx context.Context, req reconcile.Request) (_ reconcile.Result, reterr error) {
+	ctx, span := Start(ctx, r.controllerName+"/Reconcile",
+		controllerKey.String(r.controllerName),
+		namespaceKey.String(req.Namespace),
+		nameKey.String(req.Name),
+	)
+	defer func() { End(span, reterr) }()
+
+	res, err := r.r.Reconcile(ctx, req)
+	span.SetAttributes(requeueKey.Bool(res.Requeue || res.RequeueAfter > 0))
+	return res, err
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"sigs.k8s.io/cluster-api-provider-vsphere/pkg/metrics"
+)
+
+var (
+	serverKey        = attribute.Key("vsphere.server")
+	methodKey        = attribute.Key("vsphere.method")
+	managedObjectKey = attribute.Key("vsphere.managed_object")
+	httpMethodKey    = attribute.Key("http.method")
+	httpPathKey      = attribute.Key("url.path")
+	httpActionKey    = attribute.Key("vsphere.rest.action")
+	httpStatusKey    = attribute.Key("http.status_code")
+)
+
+// Detach returns a context without the span of ctx, for the requests sent in
+// the background after the operation of the span, e.g. keep-alives.
+func Detach(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(ctx, trace.SpanContext{})
+}
+
+// NewRoundTripper returns a SOAP RoundTripper which starts a span for each
+// request to the given vCenter, tagged with the method and the managed object
+// of the request. Requests sent without a span in their context, e.g. by the
+// keep-alive handler, are not traced.
+func NewRoundTripper(vcenter string, rt soap.RoundTripper) soap.RoundTripper {
+	return &roundTripper{vcenter: vcenter, rt: rt}
+}
+
+type roundTripper struct {
+	vcenter string
+	rt      soap.RoundTripper
+}
+
+func (r *roundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) (reterr error) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return r.rt.RoundTrip(ctx, req, res)
+	}
+
+	method := metrics.Method(req)
+	attrs := []attribute.KeyValue{serverKey.String(r.vcenter), methodKey.String(method)}
+	if ref, ok := managedObject(req); ok {
+		attrs = append(attrs, managedObjectKey.String(ref.String()))
+	}
+	ctx, span := Start(ctx, method, attrs...)
+	defer func() { End(span, reterr) }()
+
+	// Faults are returned as errors by the soap client.
+	return r.rt.RoundTrip(ctx, req, res)
+}
+
+// managedObject returns the managed object on which the method of a SOAP
+// request body is invoked, e.g. the virtual machine of a
+// *methods.PowerOnVM_TaskBody.
+func managedObject(req soap.HasFault) (types.ManagedObjectReference, bool) {
+	v := reflect.ValueOf(req)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return types.ManagedObjectReference{}, false
+	}
+	body := v.FieldByName("Req")
+	if body.Kind() != reflect.Pointer || body.IsNil() {
+		return types.ManagedObjectReference{}, false
+	}
+	this := body.Elem().FieldByName("This")
+	if !this.IsValid() {
+		return types.ManagedObjectReference{}, false
+	}
+	ref, ok := this.Interface().(types.ManagedObjectReference)
+	return ref, ok
+}
+
+// NewTransport returns an HTTP RoundTripper for the REST client of the given
+// vCenter which starts a span for each request. As for SOAP requests,
+// requests sent without a span in their context are not traced.
+func NewTransport(vcenter string, rt http.RoundTripper) http.RoundTripper {
+	return &transport{vcenter: vcenter, rt: rt}
+}
+
+type transport struct {
+	vcenter string
+	rt      http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (_ *http.Response, reterr error) {
+	ctx := req.Context()
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return t.rt.RoundTrip(req)
+	}
+
+	attrs := []attribute.KeyValue{
+		serverKey.String(t.vcenter),
+		httpMethodKey.String(req.Method),
+		httpPathKey.String(req.URL.Path),
+	}
+	if action := req.URL.Query().Get("~action"); action != "" {
+		attrs = append(attrs, httpActionKey.String(action))
+	}
+	_, span := Start(ctx, "HTTP "+req.Method, attrs...)
+	defer func() { End(span, reterr) }()
+
+	res, err := t.rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(httpStatusKey.Int(res.StatusCode))
+	if res.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, res.Status)
+	}
+	return res, nil
+}
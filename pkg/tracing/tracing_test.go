@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type fakeRoundTripper struct {
+	err error
+}
+
+func (f fakeRoundTripper) RoundTrip(context.Context, soap.HasFault, soap.HasFault) error {
+	return f.err
+}
+
+func TestReconcilerSpans(t *testing.T) {
+	g := NewWithT(t)
+
+	// The recorder stands in for the OTLP collector.
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+
+	vm := types.ManagedObjectReference{Type: "VirtualMachine", Value: "vm-42"}
+	rt := NewRoundTripper("vcenter.example.com", fakeRoundTripper{})
+	failingRT := NewRoundTripper("vcenter.example.com", fakeRoundTripper{err: errors.New("connection refused")})
+
+	r := Reconciler("vspherevm-controller", reconcile.Func(func(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+		g.Expect(rt.RoundTrip(ctx, &methods.PowerOnVM_TaskBody{Req: &types.PowerOnVM_Task{This: vm}}, &methods.PowerOnVM_TaskBody{})).To(Succeed())
+		g.Expect(failingRT.RoundTrip(ctx, &methods.CurrentTimeBody{Req: &types.CurrentTime{}}, &methods.CurrentTimeBody{})).ToNot(Succeed())
+		return reconcile.Result{Requeue: true}, nil
+	}))
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: apitypes.NamespacedName{Namespace: "default", Name: "vm"}})
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Requests without a span in their context are not traced.
+	g.Expect(rt.RoundTrip(context.Background(), &methods.CurrentTimeBody{Req: &types.CurrentTime{}}, &methods.CurrentTimeBody{})).To(Succeed())
+
+	spans := recorder.Ended()
+	g.Expect(spans).To(HaveLen(3))
+	powerOn, currentTime, reconcileSpan := spans[0], spans[1], spans[2]
+
+	g.Expect(reconcileSpan.Name()).To(Equal("vspherevm-controller/Reconcile"))
+	g.Expect(reconcileSpan.Attributes()).To(ContainElements(
+		namespaceKey.String("default"),
+		nameKey.String("vm"),
+		requeueKey.Bool(true),
+	))
+	g.Expect(reconcileSpan.Status().Code).To(Equal(codes.Unset))
+
+	g.Expect(powerOn.Name()).To(Equal("PowerOnVM_Task"))
+	g.Expect(powerOn.Parent().SpanID()).To(Equal(reconcileSpan.SpanContext().SpanID()))
+	g.Expect(powerOn.Attributes()).To(ContainElements(
+		serverKey.String("vcenter.example.com"),
+		methodKey.String("PowerOnVM_Task"),
+		managedObjectKey.String("VirtualMachine:vm-42"),
+	))
+
+	g.Expect(currentTime.Name()).To(Equal("CurrentTime"))
+	g.Expect(currentTime.Parent().SpanID()).To(Equal(reconcileSpan.SpanContext().SpanID()))
+	g.Expect(currentTime.Status().Code).To(Equal(codes.Error))
+}
+
+func TestSetup(t *testing.T) {
+	g := NewWithT(t)
+
+	// Tracing is disabled without an endpoint.
+	shutdown, err := Setup(context.Background(), Options{})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(shutdown(context.Background())).To(Succeed())
+
+	_, err = Setup(context.Background(), Options{Endpoint: "localhost:4317", SamplingRatio: 2})
+	g.Expect(err).To(HaveOccurred())
+}
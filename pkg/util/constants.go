@@ -139,6 +139,32 @@ network:
         {{- end }}
       {{- end }}
     {{- end }}
+  {{- if .Bonds }}
+  bonds:
+    {{- range .Bonds }}
+    {{ .Name }}:
+      interfaces:
+      {{- range .Interfaces }}
+      - "{{ . }}"
+      {{- end }}
+      parameters:
+        {{- if .Mode }}
+        mode: "{{ .Mode }}"
+        {{- else }}
+        mode: "active-backup"
+        {{- end }}
+      {{- template "interface" .NetworkInterfaceSpec }}
+    {{- end }}
+  {{- end }}
+  {{- if .VLANs }}
+  vlans:
+    {{- range .VLANs }}
+    {{ .Name }}:
+      id: {{ .ID }}
+      link: "{{ .Link }}"
+      {{- template "interface" .NetworkInterfaceSpec }}
+    {{- end }}
+  {{- end }}
   {{- if .Routes }}
   routes:
   {{- range .Routes }}
@@ -148,3 +174,50 @@ network:
   {{- end }}
   {{- end }}
 `
+
+// interfaceFormat is the template of the addresses of a bond or VLAN
+// interface. It is associated with the metadata templates, so that custom
+// templates can use it too.
+const interfaceFormat = `
+      {{- if or .DHCP4 .DHCP6 }}
+      dhcp4: {{ .DHCP4 }}
+      dhcp6: {{ .DHCP6 }}
+      {{- end }}
+      {{- if .IPAddrs }}
+      addresses:
+      {{- range .IPAddrs }}
+      - "{{ . }}"
+      {{- end }}
+      {{- end }}
+      {{- if .Gateway4 }}
+      gateway4: "{{ .Gateway4 }}"
+      {{- end }}
+      {{- if .Gateway6 }}
+      gateway6: "{{ .Gateway6 }}"
+      {{- end }}
+      {{- if .MTU }}
+      mtu: {{ .MTU }}
+      {{- end }}
+      {{- if .Routes }}
+      routes:
+      {{- range .Routes }}
+      - to: "{{ .To }}"
+        via: "{{ .Via }}"
+        metric: {{ .Metric }}
+      {{- end }}
+      {{- end }}
+      {{- if or .Nameservers .SearchDomains }}
+      nameservers:
+        {{- if .Nameservers }}
+        addresses:
+        {{- range .Nameservers }}
+        - "{{ . }}"
+        {{- end }}
+        {{- end }}
+        {{- if .SearchDomains }}
+        search:
+        {{- range .SearchDomains }}
+        - "{{ . }}"
+        {{- end }}
+        {{- end }}
+      {{- end }}`
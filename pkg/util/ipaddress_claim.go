@@ -23,3 +23,15 @@ import "fmt"
 func IPAddressClaimName(vmName string, deviceIndex, poolIndex int) string {
 	return fmt.Sprintf("%s-%d-%d", vmName, deviceIndex, poolIndex)
 }
+
+// InterfaceIPAddressClaimName returns a name given a VSphereVM name, the name
+// of a bond or VLAN interface, and poolIndex.
+func InterfaceIPAddressClaimName(vmName, interfaceName string, poolIndex int) string {
+	return fmt.Sprintf("%s-%s-%d", vmName, interfaceName, poolIndex)
+}
+
+// ControlPlaneEndpointIPAddressClaimName returns the name of the IPAddressClaim
+// of the control plane endpoint given a VSphereCluster name.
+func ControlPlaneEndpointIPAddressClaimName(clusterName string) string {
+	return fmt.Sprintf("%s-control-plane-endpoint", clusterName)
+}
@@ -31,6 +31,7 @@ import (
 	"k8s.io/utils/integer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
 	vmwarev1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/vmware/v1beta1"
@@ -115,6 +116,12 @@ func IsControlPlaneMachine(machine metav1.Object) bool {
 // string for a given VSphereMachine.
 // IPAM state includes IP and Gateways that should be added to each device.
 func GetMachineMetadata(hostname string, vsphereVM infrav1.VSphereVM, ipamState map[string]infrav1.NetworkDeviceSpec, networkStatuses ...infrav1.NetworkStatus) ([]byte, error) {
+	return RenderMachineMetadata(metadataFormat, hostname, vsphereVM, ipamState, networkStatuses...)
+}
+
+// RenderMachineMetadata renders the given cloud-init metadata template with
+// the same data as GetMachineMetadata and validates the result.
+func RenderMachineMetadata(metadataTemplate, hostname string, vsphereVM infrav1.VSphereVM, ipamState map[string]infrav1.NetworkDeviceSpec, networkStatuses ...infrav1.NetworkStatus) ([]byte, error) {
 	// Create a copy of the devices and add their MAC addresses from a network status.
 	devices := make([]infrav1.NetworkDeviceSpec, integer.IntMax(len(vsphereVM.Spec.Network.Devices), len(networkStatuses)))
 
@@ -165,22 +172,65 @@ func GetMachineMetadata(hostname string, vsphereVM infrav1.VSphereVM, ipamState
 		devices[i].MACAddr = status.MACAddr
 	}
 
+	// Create a copy of the bonds and VLANs and add the addresses from the IPAM
+	// state, which holds them by interface name.
+	network := vsphereVM.Spec.Network.DeepCopy()
+	for _, iface := range NetworkInterfaces(network) {
+		if state, ok := ipamState[iface.Name]; ok {
+			iface.IPAddrs = append(iface.IPAddrs, state.IPAddrs...)
+			iface.Gateway4 = state.Gateway4
+			iface.Gateway6 = state.Gateway6
+		}
+		if iface.DHCP4 {
+			waitForIPv4 = true
+		}
+		if iface.DHCP6 {
+			waitForIPv6 = true
+		}
+		for _, ipStr := range iface.IPAddrs {
+			ip, _, err := net.ParseCIDR(ipStr)
+			if err != nil {
+				ip = net.ParseIP(ipStr)
+			}
+			if ip != nil {
+				if ip.To4() == nil {
+					waitForIPv6 = true
+				} else {
+					waitForIPv4 = true
+				}
+			}
+		}
+	}
+
 	buf := &bytes.Buffer{}
-	tpl := template.Must(template.New("t").Funcs(
+	tpl, err := template.New("t").Funcs(
 		template.FuncMap{
 			"nameservers": func(spec infrav1.NetworkDeviceSpec) bool {
 				return len(spec.Nameservers) > 0 || len(spec.SearchDomains) > 0
 			},
-		}).Parse(metadataFormat))
+		}).Parse(metadataTemplate)
+	if err == nil {
+		_, err = tpl.New("interface").Parse(interfaceFormat)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"error parsing cloud init metadata template for vsphereVM %s/%s",
+			vsphereVM.Namespace, vsphereVM.Name)
+	}
 	if err := tpl.Execute(buf, struct {
 		Hostname    string
 		Devices     []infrav1.NetworkDeviceSpec
+		Bonds       []infrav1.NetworkBondSpec
+		VLANs       []infrav1.NetworkVLANSpec
 		Routes      []infrav1.NetworkRouteSpec
 		WaitForIPv4 bool
 		WaitForIPv6 bool
 	}{
 		Hostname:    hostname, // note that hostname determines the Kubernetes node name
 		Devices:     devices,
+		Bonds:       network.Bonds,
+		VLANs:       network.VLANs,
 		Routes:      vsphereVM.Spec.Network.Routes,
 		WaitForIPv4: waitForIPv4,
 		WaitForIPv6: waitForIPv6,
@@ -190,9 +240,30 @@ func GetMachineMetadata(hostname string, vsphereVM infrav1.VSphereVM, ipamState
 			"error getting cloud init metadata for vsphereVM %s/%s",
 			vsphereVM.Namespace, vsphereVM.Name)
 	}
+	if err := validateMachineMetadata(buf.Bytes()); err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"invalid cloud init metadata for vsphereVM %s/%s",
+			vsphereVM.Namespace, vsphereVM.Name)
+	}
 	return buf.Bytes(), nil
 }
 
+// validateMachineMetadata returns an error if the metadata is not a YAML
+// mapping defining the instance ID and the hostname of the machine.
+func validateMachineMetadata(metadata []byte) error {
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal(metadata, &fields); err != nil {
+		return err
+	}
+	for _, key := range []string{"instance-id", "local-hostname"} {
+		if value, ok := fields[key].(string); !ok || value == "" {
+			return errors.Errorf("%s must be set", key)
+		}
+	}
+	return nil
+}
+
 // GetOwnerVSphereMachine returns the VSphereMachine owner for the passed object.
 func GetOwnerVSphereMachine(ctx context.Context, c client.Client, obj metav1.ObjectMeta) (*infrav1.VSphereMachine, error) {
 	for _, ref := range obj.OwnerReferences {
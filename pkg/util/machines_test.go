@@ -834,6 +834,111 @@ network:
       addresses:
       - "fe80::3/64"
       gateway6: "fe80::1"
+`,
+		},
+		{
+			name: "bond+vlans",
+			machine: &infrav1.VSphereVM{
+				Spec: infrav1.VSphereVMSpec{
+					VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+						Network: infrav1.NetworkSpec{
+							Devices: []infrav1.NetworkDeviceSpec{
+								{
+									NetworkName: "trunk1",
+									MACAddr:     "00:00:00:00:00",
+								},
+								{
+									NetworkName: "trunk2",
+									MACAddr:     "00:00:00:00:01",
+								},
+							},
+							Bonds: []infrav1.NetworkBondSpec{
+								{
+									Name:       "bond0",
+									Interfaces: []string{"eth0", "eth1"},
+									Mode:       infrav1.NetworkBondMode8023AD,
+									NetworkInterfaceSpec: infrav1.NetworkInterfaceSpec{
+										MTU: mtu(9000),
+									},
+								},
+							},
+							VLANs: []infrav1.NetworkVLANSpec{
+								{
+									Name: "vlan100",
+									ID:   100,
+									Link: "bond0",
+									NetworkInterfaceSpec: infrav1.NetworkInterfaceSpec{
+										DHCP4: true,
+									},
+								},
+								{
+									Name: "vlan200",
+									ID:   200,
+									Link: "bond0",
+									NetworkInterfaceSpec: infrav1.NetworkInterfaceSpec{
+										Nameservers: []string{"10.20.0.53"},
+										Routes:      []infrav1.NetworkRouteSpec{{To: "10.30.0.0/16", Via: "10.20.0.254", Metric: 10}},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			ipamState: map[string]infrav1.NetworkDeviceSpec{
+				"vlan200": {
+					IPAddrs: []string{
+						"fd00::20/64",
+					},
+					Gateway6: "fd00::1",
+				},
+			},
+			expected: `
+instance-id: "test-vm"
+local-hostname: "test-vm"
+wait-on-network:
+  ipv4: true
+  ipv6: true
+network:
+  version: 2
+  ethernets:
+    id0:
+      match:
+        macaddress: "00:00:00:00:00"
+      set-name: "eth0"
+      wakeonlan: true
+    id1:
+      match:
+        macaddress: "00:00:00:00:01"
+      set-name: "eth1"
+      wakeonlan: true
+  bonds:
+    bond0:
+      interfaces:
+      - "eth0"
+      - "eth1"
+      parameters:
+        mode: "802.3ad"
+      mtu: 9000
+  vlans:
+    vlan100:
+      id: 100
+      link: "bond0"
+      dhcp4: true
+      dhcp6: false
+    vlan200:
+      id: 200
+      link: "bond0"
+      addresses:
+      - "fd00::20/64"
+      gateway6: "fd00::1"
+      routes:
+      - to: "10.30.0.0/16"
+        via: "10.20.0.254"
+        metric: 10
+      nameservers:
+        addresses:
+        - "10.20.0.53"
 `,
 		},
 		{
@@ -912,6 +1017,107 @@ network:
 	}
 }
 
+func Test_RenderMachineMetadata(t *testing.T) {
+	machine := infrav1.VSphereVM{
+		Spec: infrav1.VSphereVMSpec{
+			VirtualMachineCloneSpec: infrav1.VirtualMachineCloneSpec{
+				Network: infrav1.NetworkSpec{
+					Devices: []infrav1.NetworkDeviceSpec{
+						{NetworkName: "network1", DHCP4: true},
+						{NetworkName: "network2", DHCP4: true},
+					},
+				},
+			},
+		},
+	}
+	networkStatuses := []infrav1.NetworkStatus{
+		{MACAddr: "00:00:00:00:ab"},
+		{MACAddr: "00:00:00:00:cd"},
+	}
+
+	testCases := []struct {
+		name          string
+		template      string
+		expected      string
+		expectedError bool
+	}{
+		{
+			name: "bond of all devices",
+			template: `instance-id: "{{ .Hostname }}"
+local-hostname: "{{ .Hostname }}"
+network:
+  version: 2
+  ethernets:
+    {{- range $i, $net := .Devices }}
+    eth{{ $i }}:
+      match:
+        macaddress: "{{ $net.MACAddr }}"
+    {{- end }}
+  bonds:
+    bond0:
+      interfaces: [{{ range $i, $net := .Devices }}{{ if $i }}, {{ end }}eth{{ $i }}{{ end }}]
+      dhcp4: true
+`,
+			expected: `instance-id: "test-vm"
+local-hostname: "test-vm"
+network:
+  version: 2
+  ethernets:
+    eth0:
+      match:
+        macaddress: "00:00:00:00:ab"
+    eth1:
+      match:
+        macaddress: "00:00:00:00:cd"
+  bonds:
+    bond0:
+      interfaces: [eth0, eth1]
+      dhcp4: true
+`,
+		},
+		{
+			name:          "invalid template",
+			template:      `instance-id: "{{ .Hostname }"`,
+			expectedError: true,
+		},
+		{
+			name:          "unknown field",
+			template:      `instance-id: "{{ .Unknown }}"`,
+			expectedError: true,
+		},
+		{
+			name:          "invalid YAML",
+			template:      "instance-id: \"{{ .Hostname }}\"\n  local-hostname: [",
+			expectedError: true,
+		},
+		{
+			name:          "missing hostname",
+			template:      `instance-id: "{{ .Hostname }}"`,
+			expectedError: true,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			actVal, err := util.RenderMachineMetadata(tc.template, "test-vm", machine, nil, networkStatuses...)
+			if tc.expectedError {
+				if err == nil {
+					t.Errorf("expected an error, got metadata %s", actVal)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(actVal) != tc.expected {
+				t.Logf("actual metadata value: %s", actVal)
+				t.Logf("expected metadata value: %s", tc.expected)
+				t.Error("unexpected metadata value")
+			}
+		})
+	}
+}
+
 func TestConvertProviderIDToUUID(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+// NetworkInterface is a bond or VLAN interface of a network spec.
+type NetworkInterface struct {
+	// Name is the name of the interface in the guest operating system.
+	Name string
+
+	// Links are the names of the interfaces the interface is created on, the
+	// members of a bond or the link of a VLAN.
+	Links []string
+
+	*infrav1.NetworkInterfaceSpec
+}
+
+// NetworkInterfaces returns the bonds and the VLANs of a network spec, in
+// this order. The specs of the interfaces point into the network spec.
+func NetworkInterfaces(network *infrav1.NetworkSpec) []NetworkInterface {
+	interfaces := make([]NetworkInterface, 0, len(network.Bonds)+len(network.VLANs))
+	for i := range network.Bonds {
+		bond := &network.Bonds[i]
+		interfaces = append(interfaces, NetworkInterface{
+			Name:                 bond.Name,
+			Links:                bond.Interfaces,
+			NetworkInterfaceSpec: &bond.NetworkInterfaceSpec,
+		})
+	}
+	for i := range network.VLANs {
+		vlan := &network.VLANs[i]
+		interfaces = append(interfaces, NetworkInterface{
+			Name:                 vlan.Name,
+			Links:                []string{vlan.Link},
+			NetworkInterfaceSpec: &vlan.NetworkInterfaceSpec,
+		})
+	}
+	return interfaces
+}
+
+// NetworkDeviceName returns the name of the network device with the given
+// index in the guest operating system, as set by the cloud-init metadata.
+func NetworkDeviceName(device infrav1.NetworkDeviceSpec, index int) string {
+	if device.DeviceName != "" {
+		return device.DeviceName
+	}
+	return fmt.Sprintf("eth%d", index)
+}
+
+// NetworkInterfaceDeviceIndex returns the index of the network device a bond
+// or VLAN interface is created on, i.e. the first member of a bond, or -1 if
+// the interface does not exist. Bonds and VLANs use the MAC address of this
+// device.
+func NetworkInterfaceDeviceIndex(network *infrav1.NetworkSpec, name string) int {
+	interfaces := NetworkInterfaces(network)
+	// Each iteration follows a link to another interface, the bound of the
+	// loop protects against cycles in unvalidated specs.
+	for n := 0; n <= len(interfaces); n++ {
+		for i := range network.Devices {
+			if NetworkDeviceName(network.Devices[i], i) == name {
+				return i
+			}
+		}
+		var links []string
+		for _, iface := range interfaces {
+			if iface.Name == name {
+				links = iface.Links
+				break
+			}
+		}
+		if len(links) == 0 {
+			return -1
+		}
+		name = links[0]
+	}
+	return -1
+}
+
+// IsNetworkInterfaceLink returns true if a bond or VLAN interface is created
+// on the network device or bond with the given name.
+func IsNetworkInterfaceLink(network *infrav1.NetworkSpec, name string) bool {
+	for _, iface := range NetworkInterfaces(network) {
+		for _, link := range iface.Links {
+			if link == name {
+				return true
+			}
+		}
+	}
+	return false
+}
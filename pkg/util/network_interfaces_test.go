@@ -0,0 +1,77 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-vsphere/apis/v1beta1"
+)
+
+func TestNetworkInterfaceDeviceIndex(t *testing.T) {
+	network := &infrav1.NetworkSpec{
+		Devices: []infrav1.NetworkDeviceSpec{
+			{NetworkName: "management"},
+			{NetworkName: "trunk1", DeviceName: "ens224"},
+			{NetworkName: "trunk2", DeviceName: "ens256"},
+		},
+		Bonds: []infrav1.NetworkBondSpec{
+			{Name: "bond0", Interfaces: []string{"ens224", "ens256"}},
+			// A cycle which is rejected by the webhooks.
+			{Name: "bond1", Interfaces: []string{"vlan300"}},
+		},
+		VLANs: []infrav1.NetworkVLANSpec{
+			{Name: "vlan100", ID: 100, Link: "eth0"},
+			{Name: "vlan200", ID: 200, Link: "bond0"},
+			{Name: "vlan300", ID: 300, Link: "bond1"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		expected int
+	}{
+		{name: "eth0", expected: 0},
+		{name: "ens256", expected: 2},
+		{name: "bond0", expected: 1},
+		{name: "vlan100", expected: 0},
+		{name: "vlan200", expected: 1},
+		{name: "vlan300", expected: -1},
+		{name: "vlan400", expected: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			g.Expect(NetworkInterfaceDeviceIndex(network, tt.name)).To(gomega.Equal(tt.expected))
+		})
+	}
+}
+
+func TestIsNetworkInterfaceLink(t *testing.T) {
+	g := gomega.NewWithT(t)
+	network := &infrav1.NetworkSpec{
+		Devices: []infrav1.NetworkDeviceSpec{{NetworkName: "trunk1"}, {NetworkName: "trunk2"}, {NetworkName: "management"}},
+		Bonds:   []infrav1.NetworkBondSpec{{Name: "bond0", Interfaces: []string{"eth0", "eth1"}}},
+		VLANs:   []infrav1.NetworkVLANSpec{{Name: "vlan100", ID: 100, Link: "bond0"}},
+	}
+	g.Expect(IsNetworkInterfaceLink(network, "eth1")).To(gomega.BeTrue())
+	g.Expect(IsNetworkInterfaceLink(network, "bond0")).To(gomega.BeTrue())
+	g.Expect(IsNetworkInterfaceLink(network, "eth2")).To(gomega.BeFalse())
+	g.Expect(IsNetworkInterfaceLink(network, "vlan100")).To(gomega.BeFalse())
+}